@@ -5,6 +5,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/spf13/viper"
 	"log"
+	"path/filepath"
 	"strings"
 )
 
@@ -21,28 +22,207 @@ mysql:
   multi_statements: true
   allow_native_passwords: true
   parse_time: true
+phone_number:
+  default_region: VN
+  countries:
+    - code: VN
+      calling_code: "84"
+      trunk_prefix: "0"
+      lengths: [9]
+      mobile_prefixes: ["3", "5", "7", "8", "9"]
+    - code: SG
+      calling_code: "65"
+      lengths: [8]
+      mobile_prefixes: ["8", "9"]
+    - code: TH
+      calling_code: "66"
+      trunk_prefix: "0"
+      lengths: [9]
+      mobile_prefixes: ["6", "8", "9"]
+    - code: US
+      calling_code: "1"
+      lengths: [10]
 phone_number_rate_limit:
   limit: 3
   burst: 3
+voice_rate_limit:
+  limit: 0.02
+  burst: 2
+rate_limiter:
+  # mysql shares the limits between every instance of the API, memory keeps them in process.
+  backend: mysql
+  purge_interval: 300
+  max_keys: 100000
+  evict_interval: 60
+rate_limit_policies:
+  # Requests from trusted proxies are limited by the client address they add to X-Forwarded-For.
+  trusted_proxies: ["127.0.0.1/32", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"]
+  device_header: X-Device-ID
+  phone_prefix_length: 7
+  # limit is in requests per second, a global budget of 300 SMS per minute is a limit of 5 with a burst of 300.
+  routes:
+    - path: /api/generate_otp
+      limits:
+        - {dimension: ip, limit: 0.05, burst: 10}
+        - {dimension: device, limit: 0.05, burst: 10}
+        - {dimension: phone_prefix, limit: 0.5, burst: 30}
+        - {dimension: global, limit: 5, burst: 300}
+    - path: /api/resend_otp
+      limits:
+        - {dimension: ip, limit: 0.05, burst: 10}
+        - {dimension: device, limit: 0.05, burst: 10}
+        - {dimension: phone_prefix, limit: 0.5, burst: 30}
+        - {dimension: global, limit: 5, burst: 300}
+    - path: /api/login
+      limits:
+        - {dimension: ip, limit: 0.2, burst: 20}
+        - {dimension: device, limit: 0.2, burst: 20}
+    - path: /api/otp/status
+      limits:
+        - {dimension: ip, limit: 0.5, burst: 30}
+        - {dimension: device, limit: 0.5, burst: 30}
+otp_challenge:
+  # OTP requests are challenged when risk is elevated, a challenge is solved by a proof of work or a CAPTCHA.
+  enabled: true
+  # secret_key signs challenges, it must be set outside Local where the secret key of tokens is used when it is empty.
+  secret_key: ""
+  expired_time: 300
+  difficulty: 18
+  # Risk triggers, limits are in requests per second: requests of an IP, OTPs to numbers without a user across
+  # all clients, and countries no OTP was sent to in new_country_days days.
+  ip_rate_limit: {limit: 0.005, burst: 5}
+  first_time_rate_limit: {limit: 1, burst: 60}
+  new_country_days: 7
+  captcha:
+    # siteverify checks tokens with a reCAPTCHA, hCaptcha or Turnstile compatible endpoint, fake accepts fake_token
+    # and is only allowed in Local and Staging. CAPTCHA is disabled when type is empty.
+    type: ""
+    url: ""
+    site_key: ""
+    secret: ""
+    fake_token: ""
+    timeout: 5
 otp:
   expired_time: 60
   resend_waiting_time: 30
+  voice_resend_waiting_time: 90
   size: 6
+  alphabet: numeric
+  # pepper keys the OTP hashes, it must be set through OTP__PEPPER outside Local.
+  pepper: ""
+  max_attempts: 5
+  max_invalidations: 3
+  lock_time: 900
+  country_policy:
+    # OTPs to countries without a limit of their own are capped per day, so SMS pumping cannot run up the bill.
+    allowed_countries: []
+    blocked_countries: []
+    blocked_prefixes: []
+    default_daily_limit: 500
+    daily_limits:
+      VN: 0
+  message:
+    app_name: TBOX
+    default_locale: vi
+    template_dir: ""
+    android_app_hash: ""
+    origin_domain: ""
+    # Vietnamese templates are unaccented, accented text is sent as UCS-2 and halves the length of an SMS.
+    # voice_login is read out by the voice channel instead, with {code} spelled digit by digit. Emails are accented too,
+    # the magic_link templates take the {link} placeholder.
+    templates:
+      vi:
+        login: "{code} la ma dang nhap {app_name} cua ban, co hieu luc trong {expiry_minutes} phut. Khong chia se ma nay voi bat ky ai."
+        phone_change: "{code} la ma xac nhan doi so dien thoai {app_name} cua ban, co hieu luc trong {expiry_minutes} phut. Khong chia se ma nay voi bat ky ai."
+        voice_login: "Mã đăng nhập {app_name} của bạn là: {code}."
+        email_login_subject: "Mã đăng nhập {app_name}"
+        email_login: "{code} là mã đăng nhập {app_name} của bạn, có hiệu lực trong {expiry_minutes} phút. Nếu bạn không đăng nhập, hãy bỏ qua email này."
+        magic_link_subject: "Đăng nhập {app_name}"
+        magic_link: "Mở liên kết sau để đăng nhập {app_name}: {link}\n\nLiên kết có hiệu lực trong {expiry_minutes} phút và chỉ dùng được một lần. Nếu bạn không đăng nhập, hãy bỏ qua email này."
+      en:
+        login: "{code} is your {app_name} login code. It expires in {expiry_minutes} minutes. Do not share it with anyone."
+        phone_change: "{code} is your {app_name} code to change your phone number. It expires in {expiry_minutes} minutes. Do not share it with anyone."
+        voice_login: "Your {app_name} login code is: {code}."
+        email_login_subject: "Your {app_name} login code"
+        email_login: "{code} is your {app_name} login code. It expires in {expiry_minutes} minutes. If you did not try to log in, ignore this email."
+        magic_link_subject: "Log in to {app_name}"
+        magic_link: "Open this link to log in to {app_name}: {link}\n\nThe link expires in {expiry_minutes} minutes and can only be used once. If you did not try to log in, ignore this email."
 sms_service:
-  url: https://5db83e44177b350014ac77c6.mockapi.io/v1/sms
+  timeout: 5
+  dev_inbox_size: 500
+  circuit_breaker:
+    failure_threshold: 5
+    open_time: 30
+    half_open_requests: 1
+  providers:
+    # The dev provider keeps messages in the inbox served at /dev/sms, it is skipped outside Local and Staging.
+    - name: dev
+      type: dev
+      priority: 0
+    - name: mockapi
+      type: http_json
+      url: https://5db83e44177b350014ac77c6.mockapi.io/v1/sms
+      priority: 1
+      weight: 1
+voice_service:
+  name: mockapi_voice
+  url: https://5db83e44177b350014ac77c6.mockapi.io/v1/voice
+  auth_token: ""
+  language: vi-VN
+  rate: 0.8
+  timeout: 5
+email_service:
+  smtp_address: mail:1025
+  username: ""
+  password: ""
+  from: TBOX <no-reply@tbox.vn>
+  timeout: 10
+  magic_link_url: http://localhost:3000/login/magic_link
+sms_outbox:
+  workers: 4
+  batch_size: 20
+  poll_interval: 1
+  lease_time: 60
+  max_attempts: 5
+  base_backoff: 2
+  max_backoff: 300
 swagger:
   url: http://localhost:8080/swagger/doc.json
 token:
-  secret_key: 5OQ3ldRoOlkFg5PavqYXlWTZ88gc1DPE
+  # secret_key signs tokens with HS256 when no keys are configured, one of them must be set outside Local.
+  secret_key: ""
+  issuer: tbox_backend
+  audience: tbox_app
+  client_id: tbox_app
+  scope: user
+  expired_time: 900
+  refresh_expired_time: 2592000
+  magic_link_expired_time: 900
+  # device_registration_time is how many seconds after an OTP login its access token may register a trusted device.
+  device_registration_time: 300
+  purge_interval: 3600
+  active_key_id: ""
+  keys: []
+oauth:
+  clients: []
 `)
 
 type Config struct {
 	Base
 	MySQL                MySQL                `yaml:"mysql" mapstructure:"mysql"`
+	PhoneNumber          PhoneNumber          `yaml:"phone_number" mapstructure:"phone_number"`
 	PhoneNumberRateLimit PhoneNumberRateLimit `yaml:"phone_number_rate_limit" mapstructure:"phone_number_rate_limit"`
+	VoiceRateLimit       PhoneNumberRateLimit `yaml:"voice_rate_limit" mapstructure:"voice_rate_limit"`
+	RateLimiter          RateLimiter          `yaml:"rate_limiter" mapstructure:"rate_limiter"`
+	RateLimitPolicies    RateLimitPolicies    `yaml:"rate_limit_policies" mapstructure:"rate_limit_policies"`
+	OtpChallenge         OtpChallenge         `yaml:"otp_challenge" mapstructure:"otp_challenge"`
 	Otp                  Otp                  `yaml:"otp" mapstructure:"otp"`
 	SmsService           SmsService           `yaml:"sms_service" mapstructure:"sms_service"`
+	VoiceService         VoiceService         `yaml:"voice_service" mapstructure:"voice_service"`
+	EmailService         EmailService         `yaml:"email_service" mapstructure:"email_service"`
+	SmsOutbox            SmsOutbox            `yaml:"sms_outbox" mapstructure:"sms_outbox"`
 	Token                Token                `yaml:"token" mapstructure:"token"`
+	OAuth                OAuth                `yaml:"oauth" mapstructure:"oauth"`
 	Swagger              Swagger              `yaml:"swagger" mapstructure:"swagger"`
 }
 
@@ -57,19 +237,211 @@ type MySQL struct {
 	ParseTime            bool   `yaml:"parse_time" mapstructure:"parse_time"`
 }
 
+// PhoneNumber configures the countries phone numbers are accepted from. Numbers are normalized to E.164,
+// numbers dialled without a country calling code belong to DefaultRegion.
+type PhoneNumber struct {
+	DefaultRegion string         `yaml:"default_region" mapstructure:"default_region"`
+	Countries     []PhoneCountry `yaml:"countries" mapstructure:"countries"`
+}
+
+// PhoneCountry holds the rules of a country. Code is its ISO 3166-1 alpha-2 code, TrunkPrefix is dialled before
+// national numbers and Lengths are the lengths of its national significant numbers. Only numbers starting with
+// one of MobilePrefixes are accepted, any number is when it is empty.
+type PhoneCountry struct {
+	Code           string   `yaml:"code" mapstructure:"code"`
+	CallingCode    string   `yaml:"calling_code" mapstructure:"calling_code"`
+	TrunkPrefix    string   `yaml:"trunk_prefix" mapstructure:"trunk_prefix"`
+	Lengths        []int    `yaml:"lengths" mapstructure:"lengths"`
+	MobilePrefixes []string `yaml:"mobile_prefixes" mapstructure:"mobile_prefixes"`
+}
+
 type PhoneNumberRateLimit struct {
 	Limit float64 `yaml:"limit" mapstructure:"limit"`
 	Burst int     `yaml:"burst" mapstructure:"burst"`
 }
 
+// Backends of the rate limiters.
+const (
+	MemoryRateLimiterBackend = "memory"
+	MySQLRateLimiterBackend  = "mysql"
+)
+
+// RateLimiter picks where the token buckets of the rate limits are kept. Buckets of the mysql backend which are
+// full again are deleted every PurgeInterval seconds. The memory backend keeps at most MaxKeys buckets per limit,
+// dropping the least recently used one first, and drops idle buckets which are full again every EvictInterval seconds.
+// Buckets which never refill, of limits of 0, are dropped by both backends after 24 hours, so such a limit allows
+// its burst again a day later rather than never.
+type RateLimiter struct {
+	Backend       string `yaml:"backend" mapstructure:"backend"`
+	PurgeInterval int    `yaml:"purge_interval" mapstructure:"purge_interval"`
+	MaxKeys       int    `yaml:"max_keys" mapstructure:"max_keys"`
+	EvictInterval int    `yaml:"evict_interval" mapstructure:"evict_interval"`
+}
+
+// Dimensions a rate limit policy keys its buckets by.
+const (
+	IPRateLimitDimension          = "ip"
+	DeviceRateLimitDimension      = "device"
+	PhonePrefixRateLimitDimension = "phone_prefix"
+	GlobalRateLimitDimension      = "global"
+)
+
+// RateLimitPolicies declares the limits of routes on top of the limit per phone number. Requests are limited by
+// the client address, which is taken from X-Forwarded-For when the request comes from one of the TrustedProxies
+// CIDRs, by the DeviceHeader, by the first PhonePrefixLength digits of the E.164 phone number of the body, or all
+// together. Requests without a device header or a phone number skip the limits of those dimensions.
+type RateLimitPolicies struct {
+	TrustedProxies    []string         `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+	DeviceHeader      string           `yaml:"device_header" mapstructure:"device_header"`
+	PhonePrefixLength int              `yaml:"phone_prefix_length" mapstructure:"phone_prefix_length"`
+	Routes            []RouteRateLimit `yaml:"routes" mapstructure:"routes"`
+}
+
+// RouteRateLimit holds the limits of the route at Path, every one of them must allow a request.
+type RouteRateLimit struct {
+	Path   string            `yaml:"path" mapstructure:"path"`
+	Limits []RateLimitPolicy `yaml:"limits" mapstructure:"limits"`
+}
+
+// RateLimitPolicy is a token bucket per key of the dimension, Limit is in requests per second.
+type RateLimitPolicy struct {
+	Dimension string  `yaml:"dimension" mapstructure:"dimension"`
+	Limit     float64 `yaml:"limit" mapstructure:"limit"`
+	Burst     int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// OtpChallenge gates OTP requests with a challenge when one of the risk triggers fires. Challenges are signed with
+// SecretKey, expire after ExpiredTime seconds and are solved with a proof of work of Difficulty leading zero bits,
+// or a CAPTCHA when Captcha is configured. A challenge is required once IPRateLimit of the client IP or
+// FirstTimeRateLimit of numbers without a user is exhausted, or for countries no OTP was sent to in the last
+// NewCountryDays days.
+type OtpChallenge struct {
+	Enabled            bool                 `yaml:"enabled" mapstructure:"enabled"`
+	SecretKey          string               `yaml:"secret_key" mapstructure:"secret_key"`
+	ExpiredTime        int                  `yaml:"expired_time" mapstructure:"expired_time"`
+	Difficulty         int                  `yaml:"difficulty" mapstructure:"difficulty"`
+	IPRateLimit        PhoneNumberRateLimit `yaml:"ip_rate_limit" mapstructure:"ip_rate_limit"`
+	FirstTimeRateLimit PhoneNumberRateLimit `yaml:"first_time_rate_limit" mapstructure:"first_time_rate_limit"`
+	NewCountryDays     int                  `yaml:"new_country_days" mapstructure:"new_country_days"`
+	Captcha            Captcha              `yaml:"captcha" mapstructure:"captcha"`
+}
+
+// Captcha configures the verifier of CAPTCHA tokens. SiteKey is handed to clients to render the widget, Timeout
+// is in seconds.
+type Captcha struct {
+	Type      string `yaml:"type" mapstructure:"type"`
+	Url       string `yaml:"url" mapstructure:"url"`
+	SiteKey   string `yaml:"site_key" mapstructure:"site_key"`
+	Secret    string `yaml:"secret" mapstructure:"secret"`
+	FakeToken string `yaml:"fake_token" mapstructure:"fake_token"`
+	Timeout   int    `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// Otp times are in seconds. VoiceResendWaitingTime applies instead of ResendWaitingTime to OTPs sent by a voice call.
 type Otp struct {
-	ExpiredTime       int `yaml:"expired_time" mapstructure:"expired_time"`
-	ResendWaitingTime int `yaml:"resend_waiting_time" mapstructure:"resend_waiting_time"`
-	Size              int `yaml:"size" mapstructure:"size"`
+	ExpiredTime            int              `yaml:"expired_time" mapstructure:"expired_time"`
+	ResendWaitingTime      int              `yaml:"resend_waiting_time" mapstructure:"resend_waiting_time"`
+	VoiceResendWaitingTime int              `yaml:"voice_resend_waiting_time" mapstructure:"voice_resend_waiting_time"`
+	Size                   int              `yaml:"size" mapstructure:"size"`
+	Alphabet               string           `yaml:"alphabet" mapstructure:"alphabet"`
+	Pepper                 string           `yaml:"pepper" mapstructure:"pepper"`
+	MaxAttempts            int              `yaml:"max_attempts" mapstructure:"max_attempts"`
+	MaxInvalidations       int              `yaml:"max_invalidations" mapstructure:"max_invalidations"`
+	LockTime               int              `yaml:"lock_time" mapstructure:"lock_time"`
+	CountryPolicy          OtpCountryPolicy `yaml:"country_policy" mapstructure:"country_policy"`
+	Message                OtpMessage       `yaml:"message" mapstructure:"message"`
 }
 
+// OtpCountryPolicy protects OTP sending from SMS pumping. When AllowedCountries is set OTPs are only sent to those
+// countries, never to BlockedCountries nor to numbers starting with one of the E.164 BlockedPrefixes, e.g. premium
+// rate ranges. DailyLimits caps the OTPs sent to a country per UTC day, countries without a limit of their own are
+// capped by DefaultDailyLimit. A limit of 0 means no cap.
+type OtpCountryPolicy struct {
+	AllowedCountries  []string       `yaml:"allowed_countries" mapstructure:"allowed_countries"`
+	BlockedCountries  []string       `yaml:"blocked_countries" mapstructure:"blocked_countries"`
+	BlockedPrefixes   []string       `yaml:"blocked_prefixes" mapstructure:"blocked_prefixes"`
+	DefaultDailyLimit int            `yaml:"default_daily_limit" mapstructure:"default_daily_limit"`
+	DailyLimits       map[string]int `yaml:"daily_limits" mapstructure:"daily_limits"`
+}
+
+// OtpMessage configures the content of the OTP SMS. Templates are keyed by locale, then by purpose, and may use
+// the {code}, {expiry_minutes} and {app_name} placeholders. Every <locale>.yaml file in TemplateDir adds or
+// overrides the templates of that locale.
+// AndroidAppHash enables the Android SMS Retriever API, OriginDomain the iOS and web one-time-code autofill.
+type OtpMessage struct {
+	AppName        string                       `yaml:"app_name" mapstructure:"app_name"`
+	DefaultLocale  string                       `yaml:"default_locale" mapstructure:"default_locale"`
+	TemplateDir    string                       `yaml:"template_dir" mapstructure:"template_dir"`
+	AndroidAppHash string                       `yaml:"android_app_hash" mapstructure:"android_app_hash"`
+	OriginDomain   string                       `yaml:"origin_domain" mapstructure:"origin_domain"`
+	Templates      map[string]map[string]string `yaml:"templates" mapstructure:"templates"`
+}
+
+// SmsService configures the SMS providers. Timeout is the time in seconds a provider has to accept a message,
+// a provider may override it. DevInboxSize is the number of recent messages kept by the dev provider.
 type SmsService struct {
-	Url string `yaml:"url" mapstructure:"url"`
+	Timeout        int            `yaml:"timeout" mapstructure:"timeout"`
+	DevInboxSize   int            `yaml:"dev_inbox_size" mapstructure:"dev_inbox_size"`
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker" mapstructure:"circuit_breaker"`
+	Providers      []SmsProvider  `yaml:"providers" mapstructure:"providers"`
+}
+
+// CircuitBreaker stops sending to a provider after FailureThreshold failures in a row. After OpenTime seconds
+// HalfOpenRequests trial messages are let through, the provider is closed again once all of them succeed.
+type CircuitBreaker struct {
+	FailureThreshold int `yaml:"failure_threshold" mapstructure:"failure_threshold"`
+	OpenTime         int `yaml:"open_time" mapstructure:"open_time"`
+	HalfOpenRequests int `yaml:"half_open_requests" mapstructure:"half_open_requests"`
+}
+
+// SmsProvider configures one SMS gateway. Providers with a lower priority are tried first,
+// providers sharing a priority are picked by weight.
+type SmsProvider struct {
+	Name      string `yaml:"name" mapstructure:"name"`
+	Type      string `yaml:"type" mapstructure:"type"`
+	Url       string `yaml:"url" mapstructure:"url"`
+	Priority  int    `yaml:"priority" mapstructure:"priority"`
+	Weight    int    `yaml:"weight" mapstructure:"weight"`
+	AccountID string `yaml:"account_id" mapstructure:"account_id"`
+	AuthToken string `yaml:"auth_token" mapstructure:"auth_token"`
+	From      string `yaml:"from" mapstructure:"from"`
+	Timeout   int    `yaml:"timeout" mapstructure:"timeout"`
+	// WebhookUrl is the public url of /api/webhooks/sms/{name}, WebhookSecret verifies its delivery reports.
+	WebhookUrl    string `yaml:"webhook_url" mapstructure:"webhook_url"`
+	WebhookSecret string `yaml:"webhook_secret" mapstructure:"webhook_secret"`
+}
+
+// VoiceService configures the text-to-speech provider of the voice OTP channel. Rate is the speech rate,
+// below 1 to read the digits slowly.
+type VoiceService struct {
+	Name      string  `yaml:"name" mapstructure:"name"`
+	Url       string  `yaml:"url" mapstructure:"url"`
+	AuthToken string  `yaml:"auth_token" mapstructure:"auth_token"`
+	Language  string  `yaml:"language" mapstructure:"language"`
+	Rate      float64 `yaml:"rate" mapstructure:"rate"`
+	Timeout   int     `yaml:"timeout" mapstructure:"timeout"`
+}
+
+// EmailService configures the SMTP server emails are sent through. STARTTLS is used when the server offers it.
+// MagicLinkUrl is the page of the web app which logs in with the token of a magic link.
+type EmailService struct {
+	SmtpAddress  string `yaml:"smtp_address" mapstructure:"smtp_address"`
+	Username     string `yaml:"username" mapstructure:"username"`
+	Password     string `yaml:"password" mapstructure:"password"`
+	From         string `yaml:"from" mapstructure:"from"`
+	Timeout      int    `yaml:"timeout" mapstructure:"timeout"`
+	MagicLinkUrl string `yaml:"magic_link_url" mapstructure:"magic_link_url"`
+}
+
+// SmsOutbox configures the dispatcher of queued SMS. Times are in seconds.
+type SmsOutbox struct {
+	Workers      int `yaml:"workers" mapstructure:"workers"`
+	BatchSize    int `yaml:"batch_size" mapstructure:"batch_size"`
+	PollInterval int `yaml:"poll_interval" mapstructure:"poll_interval"`
+	LeaseTime    int `yaml:"lease_time" mapstructure:"lease_time"`
+	MaxAttempts  int `yaml:"max_attempts" mapstructure:"max_attempts"`
+	BaseBackoff  int `yaml:"base_backoff" mapstructure:"base_backoff"`
+	MaxBackoff   int `yaml:"max_backoff" mapstructure:"max_backoff"`
 }
 
 type Swagger struct {
@@ -77,7 +449,34 @@ type Swagger struct {
 }
 
 type Token struct {
-	SecretKey string `yaml:"secret_key" mapstructure:"secret_key"`
+	SecretKey              string     `yaml:"secret_key" mapstructure:"secret_key"`
+	Issuer                 string     `yaml:"issuer" mapstructure:"issuer"`
+	Audience               string     `yaml:"audience" mapstructure:"audience"`
+	ClientID               string     `yaml:"client_id" mapstructure:"client_id"`
+	Scope                  string     `yaml:"scope" mapstructure:"scope"`
+	ExpiredTime            int        `yaml:"expired_time" mapstructure:"expired_time"`
+	RefreshExpiredTime     int        `yaml:"refresh_expired_time" mapstructure:"refresh_expired_time"`
+	MagicLinkExpiredTime   int        `yaml:"magic_link_expired_time" mapstructure:"magic_link_expired_time"`
+	DeviceRegistrationTime int        `yaml:"device_registration_time" mapstructure:"device_registration_time"`
+	PurgeInterval          int        `yaml:"purge_interval" mapstructure:"purge_interval"`
+	ActiveKeyID            string     `yaml:"active_key_id" mapstructure:"active_key_id"`
+	Keys                   []TokenKey `yaml:"keys" mapstructure:"keys"`
+}
+
+type TokenKey struct {
+	ID             string `yaml:"id" mapstructure:"id"`
+	Algorithm      string `yaml:"algorithm" mapstructure:"algorithm"`
+	PrivateKeyFile string `yaml:"private_key_file" mapstructure:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file" mapstructure:"public_key_file"`
+}
+
+type OAuth struct {
+	Clients []OAuthClient `yaml:"clients" mapstructure:"clients"`
+}
+
+type OAuthClient struct {
+	ClientID     string `yaml:"client_id" mapstructure:"client_id"`
+	ClientSecret string `yaml:"client_secret" mapstructure:"client_secret"`
 }
 
 // FormatDSN returns MySQL DSN from settings.
@@ -104,11 +503,34 @@ type Tracing struct {
 	Enabled bool
 }
 
+const (
+	LocalEnvironment      = "Local"
+	StagingEnvironment    = "Staging"
+	ProductionEnvironment = "Production"
+)
+
 type Base struct {
 	Environment string `yaml:"environment"`
 	Port        int    `yaml:"port"`
 }
 
+// IsDevelopment reports whether developer tools such as the dev SMS inbox may be enabled.
+func (b Base) IsDevelopment() bool {
+	return b.Environment == LocalEnvironment || b.Environment == StagingEnvironment
+}
+
+// DailyLimit returns the daily OTP cap of the country. Country codes are compared ignoring case, viper lowercases
+// the keys of DailyLimits.
+func (p OtpCountryPolicy) DailyLimit(countryCode string) int {
+	for code, limit := range p.DailyLimits {
+		if strings.EqualFold(code, countryCode) {
+			return limit
+		}
+	}
+
+	return p.DefaultDailyLimit
+}
+
 func Load() Config {
 	var cfg = Config{}
 	viper.SetConfigType("yaml")
@@ -125,6 +547,87 @@ func Load() Config {
 		log.Fatalf("Failed to unmarshal config %v", err)
 	}
 
-	log.Println("Config loaded: ", cfg)
+	err = cfg.Otp.Message.loadTemplates()
+	if err != nil {
+		log.Fatalf("Failed to load OTP message templates %v", err)
+	}
+
+	log.Println("Config loaded: ", cfg.Redacted())
 	return cfg
 }
+
+// redacted replaces secrets in the config logged at startup.
+const redacted = "[redacted]"
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// Redacted returns a copy of the config without its secrets, to be logged.
+func (cfg Config) Redacted() Config {
+	cfg.MySQL.Password = redact(cfg.MySQL.Password)
+	cfg.OtpChallenge.SecretKey = redact(cfg.OtpChallenge.SecretKey)
+	cfg.OtpChallenge.Captcha.Secret = redact(cfg.OtpChallenge.Captcha.Secret)
+	cfg.OtpChallenge.Captcha.FakeToken = redact(cfg.OtpChallenge.Captcha.FakeToken)
+	cfg.Otp.Pepper = redact(cfg.Otp.Pepper)
+	cfg.VoiceService.AuthToken = redact(cfg.VoiceService.AuthToken)
+	cfg.EmailService.Password = redact(cfg.EmailService.Password)
+	cfg.Token.SecretKey = redact(cfg.Token.SecretKey)
+
+	providers := make([]SmsProvider, len(cfg.SmsService.Providers))
+	for i, provider := range cfg.SmsService.Providers {
+		provider.AuthToken = redact(provider.AuthToken)
+		provider.WebhookSecret = redact(provider.WebhookSecret)
+		providers[i] = provider
+	}
+
+	cfg.SmsService.Providers = providers
+
+	clients := make([]OAuthClient, len(cfg.OAuth.Clients))
+	for i, client := range cfg.OAuth.Clients {
+		client.ClientSecret = redact(client.ClientSecret)
+		clients[i] = client
+	}
+
+	cfg.OAuth.Clients = clients
+	return cfg
+}
+
+func (m *OtpMessage) loadTemplates() error {
+	if m.TemplateDir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.TemplateDir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+
+	if m.Templates == nil {
+		m.Templates = map[string]map[string]string{}
+	}
+
+	for _, file := range files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		err := v.ReadInConfig()
+		if err != nil {
+			return err
+		}
+
+		locale := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		if m.Templates[locale] == nil {
+			m.Templates[locale] = map[string]string{}
+		}
+
+		for purpose := range v.AllSettings() {
+			m.Templates[locale][purpose] = v.GetString(purpose)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `revoked_tokens`;
\ No newline at end of file
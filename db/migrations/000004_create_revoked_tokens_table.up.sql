@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `revoked_tokens` (
+  `revoked_token_id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `token_id` varchar(64) NOT NULL DEFAULT '',
+  `user_id` int(11) unsigned NOT NULL,
+  `expired_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`revoked_token_id`),
+  UNIQUE KEY `token_id` (`token_id`),
+  KEY `expired_at` (`expired_at`),
+  CONSTRAINT `revoked_tokens_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE NO ACTION ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
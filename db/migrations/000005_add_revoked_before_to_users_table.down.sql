@@ -0,0 +1 @@
+ALTER TABLE `users` DROP COLUMN `revoked_before`;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE `user_otp`
+  DROP COLUMN `otp_salt`,
+  CHANGE COLUMN `otp_hash` `otp` varchar(255) NOT NULL DEFAULT '';
+UPDATE `user_otp` SET `otp` = '';
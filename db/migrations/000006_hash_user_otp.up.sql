@@ -0,0 +1,6 @@
+-- Plaintext OTPs can not be hashed here because the pepper only lives in the application config.
+-- They are short lived, so pending OTPs are invalidated and users request a new one.
+UPDATE `user_otp` SET `otp` = '';
+ALTER TABLE `user_otp`
+  CHANGE COLUMN `otp` `otp_hash` char(64) NOT NULL DEFAULT '',
+  ADD COLUMN `otp_salt` char(32) NOT NULL DEFAULT '' AFTER `otp_hash`;
@@ -0,0 +1,4 @@
+ALTER TABLE `user_otp`
+  DROP COLUMN `locked_until`,
+  DROP COLUMN `invalidated_count`,
+  DROP COLUMN `failed_attempts`;
@@ -0,0 +1,4 @@
+ALTER TABLE `user_otp`
+  ADD COLUMN `failed_attempts` int NOT NULL DEFAULT 0 AFTER `otp_salt`,
+  ADD COLUMN `invalidated_count` int NOT NULL DEFAULT 0 AFTER `failed_attempts`,
+  ADD COLUMN `locked_until` datetime NULL DEFAULT NULL AFTER `invalidated_count`;
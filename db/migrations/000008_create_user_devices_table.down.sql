@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `user_devices`;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `user_devices` (
+  `user_device_id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `device_id` varchar(64) NOT NULL DEFAULT '',
+  `user_id` int(11) unsigned NOT NULL,
+  `name` varchar(255) NOT NULL DEFAULT '',
+  `secret_hash` char(64) NOT NULL DEFAULT '',
+  `revoked` tinyint(1) NOT NULL DEFAULT 0,
+  `last_used_at` datetime NULL DEFAULT NULL,
+  `last_used_ip` varchar(45) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`user_device_id`),
+  UNIQUE KEY `device_id` (`device_id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `user_devices_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE NO ACTION ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
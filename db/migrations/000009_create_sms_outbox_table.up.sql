@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `sms_outbox` (
+  `sms_outbox_id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `phone_number` varchar(20) NOT NULL DEFAULT '',
+  `content` varchar(1024) NOT NULL DEFAULT '',
+  `status` tinyint(1) NOT NULL DEFAULT 1,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime NOT NULL,
+  `last_error` varchar(1024) NOT NULL DEFAULT '',
+  `claim_token` varchar(64) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`sms_outbox_id`),
+  KEY `status_next_attempt_at` (`status`, `next_attempt_at`),
+  KEY `claim_token` (`claim_token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
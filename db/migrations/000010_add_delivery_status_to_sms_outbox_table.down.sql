@@ -0,0 +1,6 @@
+ALTER TABLE `sms_outbox`
+  DROP KEY `phone_number`,
+  DROP KEY `provider_message_id`,
+  DROP COLUMN `delivery_status`,
+  DROP COLUMN `provider_message_id`,
+  DROP COLUMN `provider`;
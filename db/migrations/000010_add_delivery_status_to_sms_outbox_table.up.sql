@@ -0,0 +1,6 @@
+ALTER TABLE `sms_outbox`
+  ADD COLUMN `provider` varchar(64) NOT NULL DEFAULT '' AFTER `last_error`,
+  ADD COLUMN `provider_message_id` varchar(128) NOT NULL DEFAULT '' AFTER `provider`,
+  ADD COLUMN `delivery_status` varchar(16) NOT NULL DEFAULT 'queued' AFTER `provider_message_id`,
+  ADD KEY `provider_message_id` (`provider`, `provider_message_id`),
+  ADD KEY `phone_number` (`phone_number`);
@@ -0,0 +1,2 @@
+ALTER TABLE `sms_outbox`
+  DROP COLUMN `channel`;
@@ -0,0 +1,2 @@
+ALTER TABLE `sms_outbox`
+  ADD COLUMN `channel` varchar(8) NOT NULL DEFAULT 'sms' AFTER `phone_number`;
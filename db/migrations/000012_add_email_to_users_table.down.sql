@@ -0,0 +1,12 @@
+-- Users without a phone number, who log in by email, cannot be kept once phone_number is required again. They are
+-- deleted along with the rows which reference them, instead of all collapsing onto the same empty number.
+DELETE o FROM `user_otp` o JOIN `users` u ON u.`user_id` = o.`user_id` WHERE u.`phone_number` IS NULL;
+DELETE t FROM `refresh_tokens` t JOIN `users` u ON u.`user_id` = t.`user_id` WHERE u.`phone_number` IS NULL;
+DELETE t FROM `revoked_tokens` t JOIN `users` u ON u.`user_id` = t.`user_id` WHERE u.`phone_number` IS NULL;
+DELETE d FROM `user_devices` d JOIN `users` u ON u.`user_id` = d.`user_id` WHERE u.`phone_number` IS NULL;
+DELETE FROM `users` WHERE `phone_number` IS NULL;
+
+ALTER TABLE `users`
+  DROP KEY `email`,
+  DROP COLUMN `email`,
+  MODIFY `phone_number` varchar(10) NOT NULL DEFAULT '';
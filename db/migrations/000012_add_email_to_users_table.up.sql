@@ -0,0 +1,4 @@
+ALTER TABLE `users`
+  MODIFY `phone_number` varchar(10) NULL DEFAULT NULL,
+  ADD COLUMN `email` varchar(255) NULL DEFAULT NULL AFTER `phone_number`,
+  ADD UNIQUE KEY `email` (`email`);
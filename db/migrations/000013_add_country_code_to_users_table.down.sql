@@ -0,0 +1,20 @@
+UPDATE `sms_outbox` SET `phone_number` = CONCAT('0', SUBSTRING(`phone_number`, 4))
+WHERE `phone_number` LIKE '+84%';
+
+UPDATE `users` SET `phone_number` = CONCAT('0', SUBSTRING(`phone_number`, 4))
+WHERE `phone_number` LIKE '+84%';
+
+-- Numbers outside Vietnam do not fit the local format. Users who have an email keep it without their phone number,
+-- the others are deleted along with the rows which reference them.
+UPDATE `users` SET `phone_number` = NULL
+WHERE `phone_number` LIKE '+%' AND `email` IS NOT NULL;
+
+DELETE o FROM `user_otp` o JOIN `users` u ON u.`user_id` = o.`user_id` WHERE u.`phone_number` LIKE '+%';
+DELETE t FROM `refresh_tokens` t JOIN `users` u ON u.`user_id` = t.`user_id` WHERE u.`phone_number` LIKE '+%';
+DELETE t FROM `revoked_tokens` t JOIN `users` u ON u.`user_id` = t.`user_id` WHERE u.`phone_number` LIKE '+%';
+DELETE d FROM `user_devices` d JOIN `users` u ON u.`user_id` = d.`user_id` WHERE u.`phone_number` LIKE '+%';
+DELETE FROM `users` WHERE `phone_number` LIKE '+%';
+
+ALTER TABLE `users`
+  DROP COLUMN `country_code`,
+  MODIFY `phone_number` varchar(10) NULL DEFAULT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE `users`
+  MODIFY `phone_number` varchar(16) NULL DEFAULT NULL,
+  ADD COLUMN `country_code` char(2) NULL DEFAULT NULL AFTER `phone_number`;
+
+UPDATE `users` SET `phone_number` = CONCAT('+84', SUBSTRING(`phone_number`, 2)), `country_code` = 'VN'
+WHERE `phone_number` LIKE '0%';
+
+UPDATE `sms_outbox` SET `phone_number` = CONCAT('+84', SUBSTRING(`phone_number`, 2))
+WHERE `phone_number` LIKE '0%';
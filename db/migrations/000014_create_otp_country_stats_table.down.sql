@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `otp_country_stats`;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `otp_country_stats` (
+  `country_code` char(2) NOT NULL DEFAULT '',
+  `day` date NOT NULL,
+  `sent_count` int(11) NOT NULL DEFAULT 0,
+  `blocked_count` int(11) NOT NULL DEFAULT 0,
+  `capped_count` int(11) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`country_code`, `day`),
+  KEY `day` (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `rate_limits` (
+  `limiter_key` varchar(191) NOT NULL,
+  `tokens` double NOT NULL DEFAULT 0,
+  `updated_at` datetime(6) NOT NULL,
+  `expired_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`limiter_key`),
+  KEY `expired_at` (`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,3 @@
+ALTER TABLE `users` ADD COLUMN `revoked_before` datetime NULL DEFAULT NULL AFTER `status`;
+UPDATE `users` SET `revoked_before` = UTC_TIMESTAMP() WHERE `token_generation` > 0;
+ALTER TABLE `users` DROP COLUMN `token_generation`;
@@ -0,0 +1,3 @@
+ALTER TABLE `users` ADD COLUMN `token_generation` int NOT NULL DEFAULT 0 AFTER `status`;
+UPDATE `users` SET `token_generation` = 1 WHERE `revoked_before` IS NOT NULL;
+ALTER TABLE `users` DROP COLUMN `revoked_before`;
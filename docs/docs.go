@@ -1,6 +1,6 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2019-12-29 23:01:11.657014 +0700 +07 m=+0.082239454
+// 2026-10-18 06:36:01.343123945 +0000 UTC m=+0.076471003
 
 package docs
 
@@ -26,9 +26,234 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/otp/countries": {
+            "get": {
+                "description": "OTPs sent to every country on a UTC day and the ones refused by the country policy. Clients authenticate with HTTP Basic.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "OTP country stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Day as YYYY-MM-DD, today when empty",
+                        "name": "day",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OtpCountryStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OtpCountryStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OAuthErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sms/providers": {
+            "get": {
+                "description": "Circuit breaker state (closed, open or half_open) and counters of every SMS provider, in priority order. Clients authenticate with HTTP Basic.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "SMS providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SmsProvidersResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OAuthErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dev/sms/inbox": {
+            "get": {
+                "description": "Recent messages kept by the dev SMS provider, newest first. Only served in Local and Staging.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Dev SMS inbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number, every number when empty",
+                        "name": "phone_number",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DevSmsInboxResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/login": {
+            "post": {
+                "description": "Verify the otp sent to the email and return access_token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Login with email",
+                "parameters": [
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.EmailLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/magic_link": {
+            "post": {
+                "description": "Send a signed link to the email which logs in once. The user is created on first use.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Send magic link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the email",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.EmailOtpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GenerateOtpResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/magic_link/login": {
+            "post": {
+                "description": "Exchange the token of a magic link for an access_token. The web page of the link posts the token, a GET link would be used up by email scanners opening it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Login with magic link",
+                "parameters": [
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.MagicLinkLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/email/otp": {
+            "post": {
+                "description": "Generate otp and send otp to the email. The user is created on first use. The email is written in the locale of the request, or of the Accept-Language header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generate email otp",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the email",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.EmailOtpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GenerateOtpResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/generate_otp": {
             "post": {
-                "description": "Generate otp and send otp to phone number. OTP will be printed in console log.",
+                "description": "Generate otp and send otp to phone number by SMS or, with the voice channel, by a call. The message is written in the locale of the request, or of the Accept-Language header. When the risk of the request is elevated, status 207 returns a challenge: send the request again with the challenge and either a proof of work nonce or a CAPTCHA token. Once sent, status_token gives access to the delivery status of the OTP.",
                 "consumes": [
                     "application/json"
                 ],
@@ -37,6 +262,12 @@ var doc = `{
                 ],
                 "summary": "Generate otp",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the SMS",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
                     {
                         "description": "Body",
                         "name": "Body",
@@ -52,7 +283,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.GenerateOtpResponse"
+                            "$ref": "#/definitions/dto.OtpChallengeResponse"
                         }
                     }
                 }
@@ -60,7 +291,7 @@ var doc = `{
         },
         "/login": {
             "post": {
-                "description": "Verify otp and return access_token. OTP is not necessary after the fist successful login",
+                "description": "Verify otp and return access_token. OTP is required on every login unless a trusted device_id and device_secret are given instead.",
                 "consumes": [
                     "application/json"
                 ],
@@ -90,25 +321,31 @@ var doc = `{
                 }
             }
         },
-        "/resend_otp": {
+        "/logout": {
             "post": {
-                "description": "Generate new otp and send otp to phone number. OTP will be printed in console log.",
+                "description": "Revoke the access_token and, when given, the refresh_token of the current session.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Resend otp",
+                "summary": "Logout",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "description": "Body",
                         "name": "Body",
                         "in": "body",
-                        "required": true,
                         "schema": {
                             "type": "object",
-                            "$ref": "#/definitions/dto.GenerateOtpRequest"
+                            "$ref": "#/definitions/dto.LogoutRequest"
                         }
                     }
                 ],
@@ -116,55 +353,818 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.GenerateOtpResponse"
+                            "$ref": "#/definitions/dto.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.GenerateOtpRequest": {
-            "type": "object",
-            "properties": {
-                "phone_number": {
-                    "type": "string"
-                }
-            }
         },
-        "dto.GenerateOtpResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "integer"
+        "/logout_all": {
+            "post": {
+                "description": "Revoke every access_token and refresh_token issued to the current user.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Logout from all sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Response"
+                        }
+                    }
                 }
             }
         },
-        "dto.LoginRequest": {
-            "type": "object",
-            "properties": {
-                "otp": {
-                    "type": "string"
-                },
-                "phone_number": {
-                    "type": "string"
+        "/me": {
+            "get": {
+                "description": "Return the user the access_token was issued to.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Current user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserResponse"
+                        }
+                    }
                 }
             }
         },
-        "dto.LoginResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "integer"
-                },
-                "token": {
-                    "type": "string"
+        "/me/devices": {
+            "get": {
+                "description": "List the trusted devices of the current user.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DevicesResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register the current device as trusted, with the access token of an OTP login of the last few minutes. The device_secret is only returned once and lets the device log in without an OTP.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Register device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.RegisterDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeviceResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/devices/{device_id}": {
+            "delete": {
+                "description": "Revoke a trusted device of the current user.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revoke device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer access_token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/introspect": {
+            "post": {
+                "description": "RFC 7662 token introspection. Clients authenticate with HTTP Basic or client_id/client_secret form fields.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Token introspection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token type hint",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.IntrospectionResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OAuthErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/status": {
+            "get": {
+                "description": "Return the delivery status (queued, sent, delivered or failed) of the pending OTP of the phone number. status_token is returned by generate_otp and resend_otp, without it no status is returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "OTP delivery status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number",
+                        "name": "phone_number",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status token of the OTP",
+                        "name": "status_token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OtpStatusResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/resend_otp": {
+            "post": {
+                "description": "Generate new otp and send otp to phone number by SMS or, with the voice channel, by a call. The message is written in the locale of the request, or of the Accept-Language header. Like generate_otp, status 207 returns a challenge when the risk of the request is elevated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Resend otp",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preferred locales of the SMS",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.GenerateOtpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OtpSentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access_token. The refresh token is rotated and can only be used once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Refresh token",
+                "parameters": [
+                    {
+                        "description": "Body",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/dto.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/sms/{provider}": {
+            "post": {
+                "description": "Webhook for delivery reports of the named SMS provider. The request must carry the signature of the provider.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "SMS delivery report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "SMS provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.DevSmsInboxResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DevSmsMessage"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DevSmsMessage": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "phone_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DeviceCredential": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "device_secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DeviceResponse": {
+            "type": "object",
+            "properties": {
+                "device": {
+                    "type": "object",
+                    "$ref": "#/definitions/dto.DeviceCredential"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DevicesResponse": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UserDevice"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.EmailLoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "otp": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.EmailOtpRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GenerateOtpRequest": {
+            "type": "object",
+            "properties": {
+                "captcha_token": {
+                    "type": "string"
+                },
+                "challenge": {
+                    "type": "string"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "string"
+                },
+                "phone_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GenerateOtpResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.IntrospectionResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "iat": {
+                    "type": "integer"
+                },
+                "jti": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "sub": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "device_secret": {
+                    "type": "string"
+                },
+                "otp": {
+                    "type": "string"
+                },
+                "phone_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MagicLinkLoginRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OAuthErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OtpChallenge": {
+            "type": "object",
+            "properties": {
+                "captcha_site_key": {
+                    "type": "string"
+                },
+                "challenge": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OtpChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "type": "object",
+                    "$ref": "#/definitions/dto.OtpChallenge"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.OtpCountryStat": {
+            "type": "object",
+            "properties": {
+                "blocked_count": {
+                    "type": "integer"
+                },
+                "capped_count": {
+                    "type": "integer"
+                },
+                "country_code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "sent_count": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OtpCountryStatsResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "stats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.OtpCountryStat"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.OtpSentResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "status_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OtpStatus": {
+            "type": "object",
+            "properties": {
+                "delivery_status": {
+                    "type": "string"
+                },
+                "resend_available_at": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OtpStatusResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "otp_status": {
+                    "type": "object",
+                    "$ref": "#/definitions/dto.OtpStatus"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.RefreshTokenRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RegisterDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Response": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.SmsProviderState": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "trips": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.SmsProvidersResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "providers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.SmsProviderState"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.User": {
+            "type": "object",
+            "properties": {
+                "country_code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "phone_number": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UserDevice": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "last_used_ip": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UserResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "user": {
+                    "type": "object",
+                    "$ref": "#/definitions/dto.User"
                 }
             }
         }
@@ -0,0 +1,108 @@
+package external
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"tbox_backend/config"
+	"time"
+)
+
+const (
+	SiteVerifyCaptchaType = "siteverify"
+	FakeCaptchaType       = "fake"
+)
+
+const defaultCaptchaTimeout = 5
+
+// ICaptchaVerifier checks the token a client got by solving a CAPTCHA.
+type ICaptchaVerifier interface {
+	Verify(ctx context.Context, token string, remoteIP string) (bool, error)
+}
+
+// NewCaptchaVerifier returns nil when no CAPTCHA is configured.
+func NewCaptchaVerifier(cfg config.Captcha) (ICaptchaVerifier, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case SiteVerifyCaptchaType:
+		if cfg.Url == "" {
+			return nil, fmt.Errorf("No CAPTCHA verify url is configured ")
+		}
+
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultCaptchaTimeout
+		}
+
+		return NewSiteVerifyCaptchaVerifier(cfg, &http.Client{Timeout: time.Duration(timeout) * time.Second}), nil
+	case FakeCaptchaType:
+		return NewFakeCaptchaVerifier(cfg.FakeToken), nil
+	default:
+		return nil, fmt.Errorf("CAPTCHA type %s is not supported ", cfg.Type)
+	}
+}
+
+type siteVerifyResponse struct {
+	Success bool `json:"success"`
+}
+
+// SiteVerifyCaptchaVerifier posts the token to a siteverify endpoint, the API shared by reCAPTCHA, hCaptcha
+// and Turnstile.
+type SiteVerifyCaptchaVerifier struct {
+	cfg    config.Captcha
+	client *http.Client
+}
+
+func NewSiteVerifyCaptchaVerifier(cfg config.Captcha, client *http.Client) *SiteVerifyCaptchaVerifier {
+	return &SiteVerifyCaptchaVerifier{cfg: cfg, client: client}
+}
+
+func (v SiteVerifyCaptchaVerifier) Verify(ctx context.Context, token string, remoteIP string) (bool, error) {
+	form := url.Values{}
+	form.Set("secret", v.cfg.Secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", v.cfg.Url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := do(v.client, "captcha", req)
+	if err != nil {
+		return false, err
+	}
+
+	var response siteVerifyResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Success, nil
+}
+
+// FakeCaptchaVerifier accepts a fixed token, so CAPTCHA flows can be run locally and by QA automation.
+type FakeCaptchaVerifier struct {
+	token string
+}
+
+func NewFakeCaptchaVerifier(token string) *FakeCaptchaVerifier {
+	return &FakeCaptchaVerifier{token: token}
+}
+
+func (v FakeCaptchaVerifier) Verify(ctx context.Context, token string, remoteIP string) (bool, error) {
+	if v.token == "" {
+		return false, nil
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(v.token)) == 1, nil
+}
@@ -0,0 +1,59 @@
+package external_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"testing"
+)
+
+func TestSiteVerifyCaptchaVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("secret") != "secret" || r.PostForm.Get("remoteip") != "203.0.113.7" || r.PostForm.Get("response") != "solved" {
+			_, _ = w.Write([]byte(`{"success":false}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	verifier := external.NewSiteVerifyCaptchaVerifier(config.Captcha{Url: server.URL, Secret: "secret"}, server.Client())
+	if valid, err := verifier.Verify(context.Background(), "solved", "203.0.113.7"); err != nil || !valid {
+		t.Fatalf("expected true, got %v %v", valid, err)
+	}
+
+	if valid, err := verifier.Verify(context.Background(), "unsolved", "203.0.113.7"); err != nil || valid {
+		t.Fatalf("expected false, got %v %v", valid, err)
+	}
+}
+
+func TestSiteVerifyCaptchaVerifier_Non2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	verifier := external.NewSiteVerifyCaptchaVerifier(config.Captcha{Url: server.URL}, server.Client())
+	if _, err := verifier.Verify(context.Background(), "solved", ""); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFakeCaptchaVerifier_Verify(t *testing.T) {
+	verifier := external.NewFakeCaptchaVerifier("pass")
+	if valid, _ := verifier.Verify(context.Background(), "pass", ""); !valid {
+		t.Fatalf("expected true")
+	}
+
+	if valid, _ := verifier.Verify(context.Background(), "fail", ""); valid {
+		t.Fatalf("expected false")
+	}
+
+	if valid, _ := external.NewFakeCaptchaVerifier("").Verify(context.Background(), "", ""); valid {
+		t.Fatalf("expected an empty fake token to accept nothing")
+	}
+}
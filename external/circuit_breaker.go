@@ -0,0 +1,152 @@
+package external
+
+import (
+	"fmt"
+	"sync"
+	"tbox_backend/config"
+	"time"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTime         = 30
+	defaultHalfOpenRequests = 1
+)
+
+// CircuitBreaker tracks the health of a provider. A closed breaker lets every request through and opens after
+// FailureThreshold failures in a row. An open breaker rejects requests until OpenTime has passed, then it is
+// half-open and lets HalfOpenRequests trial requests through: the breaker closes once all of them succeed
+// and opens again on the first failure.
+type CircuitBreaker struct {
+	cfg    config.CircuitBreaker
+	mutex  sync.Mutex
+	now    func() time.Time
+	state  string
+	stats  CircuitBreakerStats
+	trials int
+	passed int
+}
+
+// CircuitBreakerStats is a snapshot of a breaker, the counters are totals since the process started.
+type CircuitBreakerStats struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at"`
+	Requests            int64     `json:"requests"`
+	Failures            int64     `json:"failures"`
+	Rejected            int64     `json:"rejected"`
+	Trips               int64     `json:"trips"`
+}
+
+func NewCircuitBreaker(cfg config.CircuitBreaker) *CircuitBreaker {
+	return NewCircuitBreakerWithClock(cfg, time.Now)
+}
+
+func NewCircuitBreakerWithClock(cfg config.CircuitBreaker, now func() time.Time) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+
+	if cfg.OpenTime <= 0 {
+		cfg.OpenTime = defaultOpenTime
+	}
+
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &CircuitBreaker{cfg: cfg, now: now, state: CircuitClosed}
+}
+
+// Allow reports whether a request may be sent. Every allowed request must be followed by a call to Done
+// or Cancel.
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.stats.OpenedAt) >= time.Duration(b.cfg.OpenTime)*time.Second {
+		b.state = CircuitHalfOpen
+		b.trials, b.passed = 0, 0
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		b.stats.Rejected++
+		return false
+	case CircuitHalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			b.stats.Rejected++
+			return false
+		}
+
+		b.trials++
+	}
+
+	b.stats.Requests++
+	return true
+}
+
+// Done records the outcome of an allowed request.
+func (b *CircuitBreaker) Done(success bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if success {
+		b.stats.ConsecutiveFailures = 0
+		if b.state == CircuitHalfOpen {
+			b.passed++
+			if b.passed >= b.cfg.HalfOpenRequests {
+				b.state = CircuitClosed
+			}
+		}
+
+		return
+	}
+
+	b.stats.Failures++
+	b.stats.ConsecutiveFailures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.stats.ConsecutiveFailures >= b.cfg.FailureThreshold) {
+		b.state = CircuitOpen
+		b.stats.OpenedAt = b.now().UTC()
+		b.stats.Trips++
+	}
+}
+
+// Cancel gives back an allowed request whose outcome says nothing about the provider, so it does not
+// use up a trial of a half-open breaker.
+func (b *CircuitBreaker) Cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitHalfOpen && b.trials > b.passed {
+		b.trials--
+	}
+}
+
+func (b *CircuitBreaker) Stats() CircuitBreakerStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats := b.stats
+	stats.State = b.state
+	if b.state == CircuitOpen && b.now().Sub(b.stats.OpenedAt) >= time.Duration(b.cfg.OpenTime)*time.Second {
+		// The next request will be a trial, report the breaker as it will behave.
+		stats.State = CircuitHalfOpen
+	}
+
+	return stats
+}
+
+type CircuitOpenError struct {
+	Provider string
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("Circuit breaker of %s is open ", e.Provider)
+}
@@ -0,0 +1,67 @@
+package external_test
+
+import (
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := external.NewCircuitBreakerWithClock(
+		config.CircuitBreaker{FailureThreshold: 2, OpenTime: 30, HalfOpenRequests: 1},
+		func() time.Time { return now },
+	)
+
+	for i := 0; i < 2; i++ {
+		if !breaker.Allow() {
+			t.Fatalf("expected a closed breaker to allow requests")
+		}
+
+		breaker.Done(false)
+	}
+
+	if breaker.Allow() || breaker.Stats().State != external.CircuitOpen {
+		t.Fatalf("expected the breaker to open after 2 failures")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !breaker.Allow() || breaker.Allow() {
+		t.Fatalf("expected a half-open breaker to allow a single trial")
+	}
+
+	breaker.Done(false)
+	if breaker.Allow() {
+		t.Fatalf("expected a failed trial to open the breaker again")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !breaker.Allow() {
+		t.Fatalf("expected a trial")
+	}
+
+	breaker.Done(true)
+	stats := breaker.Stats()
+	if stats.State != external.CircuitClosed || stats.Trips != 2 || stats.Failures != 3 || stats.Rejected != 3 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+}
+
+func TestCircuitBreaker_Cancel(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := external.NewCircuitBreakerWithClock(
+		config.CircuitBreaker{FailureThreshold: 1, OpenTime: 30, HalfOpenRequests: 1},
+		func() time.Time { return now },
+	)
+
+	breaker.Allow()
+	breaker.Done(false)
+	now = now.Add(time.Minute)
+
+	breaker.Allow()
+	breaker.Cancel()
+	if !breaker.Allow() {
+		t.Fatalf("expected a cancelled trial to be given back")
+	}
+}
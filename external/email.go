@@ -0,0 +1,160 @@
+package external
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"tbox_backend/config"
+	"time"
+)
+
+const defaultEmailTimeout = 10
+
+// IEmailService sends plain text emails, the email channel of OTP and magic link delivery.
+type IEmailService interface {
+	Send(to string, subject string, body string) error
+}
+
+// SmtpEmailService sends emails through an SMTP server, upgrading the connection with STARTTLS when
+// the server offers it.
+type SmtpEmailService struct {
+	cfg  config.EmailService
+	from *mail.Address
+}
+
+func NewSmtpEmailService(cfg config.EmailService) (*SmtpEmailService, error) {
+	if cfg.SmtpAddress == "" {
+		return nil, fmt.Errorf("No SMTP server is configured ")
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("Sender address %s is invalid: %v ", cfg.From, err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultEmailTimeout
+	}
+
+	return &SmtpEmailService{cfg: cfg, from: from}, nil
+}
+
+func (s SmtpEmailService) Send(to string, subject string, body string) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return err
+	}
+
+	message, err := s.message(recipient, subject, body)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(s.cfg.SmtpAddress)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(s.cfg.Timeout) * time.Second
+	conn, err := net.DialTimeout("tcp", s.cfg.SmtpAddress, timeout)
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(s.from.Address)
+	if err != nil {
+		return err
+	}
+
+	err = client.Rcpt(recipient.Address)
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(message)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message builds an UTF-8 plain text message. Header values are encoded, so they can not inject headers.
+func (s SmtpEmailService) message(to *mail.Address, subject string, body string) ([]byte, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+	headers := []string{
+		"From: " + s.from.String(),
+		"To: " + to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + hex.EncodeToString(buf) + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+	}
+
+	message := new(bytes.Buffer)
+	message.WriteString(strings.Join(headers, "\r\n"))
+	message.WriteString("\r\n\r\n")
+	writer := quotedprintable.NewWriter(message)
+	_, err := writer.Write([]byte(strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\n", "\r\n", -1)))
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	message.WriteString("\r\n")
+	return message.Bytes(), nil
+}
@@ -0,0 +1,157 @@
+package external_test
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"testing"
+)
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpSink is a local SMTP server which accepts every message without TLS or authentication.
+type smtpSink struct {
+	listener net.Listener
+	messages chan smtpMessage
+}
+
+func newSmtpSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &smtpSink{listener: listener, messages: make(chan smtpMessage, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go sink.serve(conn)
+		}
+	}()
+
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer func() {
+		_ = text.Close()
+	}()
+
+	message := smtpMessage{}
+	_ = text.PrintfLine("220 localhost ESMTP sink")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			_ = text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			message.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			_ = text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			message.to = append(message.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			_ = text.PrintfLine("250 OK")
+		case command == "DATA":
+			_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+
+			message.data = strings.Join(lines, "\r\n")
+			s.messages <- message
+			_ = text.PrintfLine("250 OK")
+		case command == "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpSink) Close() {
+	_ = s.listener.Close()
+}
+
+func TestSmtpEmailService_Send(t *testing.T) {
+	sink := newSmtpSink(t)
+	defer sink.Close()
+
+	emailService, err := external.NewSmtpEmailService(config.EmailService{
+		SmtpAddress: sink.listener.Addr().String(),
+		From:        "TBOX <no-reply@tbox.vn>",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = emailService.Send("user@tbox.vn", "Mã đăng nhập TBOX", "123456 là mã đăng nhập của bạn.\nĐừng chia sẻ mã này.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := <-sink.messages
+	if received.from != "no-reply@tbox.vn" || len(received.to) != 1 || received.to[0] != "user@tbox.vn" {
+		t.Fatalf("unexpected envelope %v", received)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(received.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || subject != "Mã đăng nhập TBOX" {
+		t.Fatalf("unexpected subject %q, %v", subject, err)
+	}
+
+	body, err := ioutil.ReadAll(quotedprintable.NewReader(message.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(string(body)) != "123456 là mã đăng nhập của bạn.\r\nĐừng chia sẻ mã này." {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestSmtpEmailService_Send_InvalidRecipient(t *testing.T) {
+	emailService, _ := external.NewSmtpEmailService(config.EmailService{SmtpAddress: "127.0.0.1:1", From: "no-reply@tbox.vn"})
+	err := emailService.Send("user@tbox.vn\r\nBcc: victim@tbox.vn", "hello", "hello")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestNewSmtpEmailService(t *testing.T) {
+	_, err := external.NewSmtpEmailService(config.EmailService{})
+	if err == nil {
+		t.Fatalf("expected error without SMTP server")
+	}
+
+	_, err = external.NewSmtpEmailService(config.EmailService{SmtpAddress: "localhost:1025", From: "not an address"})
+	if err == nil {
+		t.Fatalf("expected error with invalid sender")
+	}
+}
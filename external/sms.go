@@ -1,23 +1,131 @@
 package external
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"tbox_backend/config"
+	"time"
 )
 
+const defaultSmsTimeout = 5
+
 type ISmsService interface {
-	SendOtp(phoneNumber string, otp string) error
+	Send(ctx context.Context, phoneNumber string, content string) (SmsReceipt, error)
+	ParseDeliveryReport(provider string, req *http.Request, body []byte) (SmsDeliveryReport, error)
+	ProviderStates() []SmsProviderState
+}
+
+// SmsReceipt identifies a message accepted by a provider, delivery reports refer to it by MessageID.
+type SmsReceipt struct {
+	Provider  string
+	MessageID string
+}
+
+// SmsProviderState reports the circuit breaker of a provider.
+type SmsProviderState struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	CircuitBreakerStats
 }
 
+// SmsService routes messages to the configured providers and fails over to the next provider
+// when one of them returns an error, times out or has its circuit breaker open.
 type SmsService struct {
-	url string
+	providers []smsRoute
+	mutex     sync.Mutex
+	random    *rand.Rand
 }
 
-func NewSmsService(url string) *SmsService {
-	return &SmsService{url: url}
+type smsRoute struct {
+	provider ISmsProvider
+	priority int
+	weight   int
+	timeout  time.Duration
+	breaker  *CircuitBreaker
+}
+
+// NewSmsService creates the configured providers. Providers of the dev type keep their messages in devSmsInbox,
+// they are skipped when it is nil.
+func NewSmsService(cfg config.SmsService, devSmsInbox *DevSmsInbox) (*SmsService, error) {
+	// Every request carries the timeout of its provider in its context.
+	client := &http.Client{}
+	providerCfgs := make([]config.SmsProvider, 0, len(cfg.Providers))
+	providers := make([]ISmsProvider, 0, len(cfg.Providers))
+	for _, providerCfg := range cfg.Providers {
+		var provider ISmsProvider
+		if providerCfg.Type == DevSmsProviderType {
+			if devSmsInbox == nil {
+				log.Printf("SMS provider %s is skipped, the dev inbox is disabled", providerCfg.Name)
+				continue
+			}
+
+			provider = NewDevSmsProvider(providerCfg, devSmsInbox)
+		} else {
+			var err error
+			provider, err = NewSmsProvider(providerCfg, client)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		providerCfgs = append(providerCfgs, providerCfg)
+		providers = append(providers, provider)
+	}
+
+	cfg.Providers = providerCfgs
+	return NewSmsServiceWithProviders(cfg, providers...)
+}
+
+// NewSmsServiceWithProviders builds the routing table from already created providers,
+// cfg.Providers[i] holds the priority, weight and timeout of providers[i]. Every provider gets its own
+// circuit breaker.
+func NewSmsServiceWithProviders(cfg config.SmsService, providers ...ISmsProvider) (*SmsService, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("No SMS provider is configured ")
+	} else if len(cfg.Providers) != len(providers) {
+		return nil, fmt.Errorf("Expected %d SMS provider configs, got %d ", len(providers), len(cfg.Providers))
+	}
+
+	routes := make([]smsRoute, 0, len(providers))
+	for i, provider := range providers {
+		providerCfg := cfg.Providers[i]
+		weight := providerCfg.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		timeout := providerCfg.Timeout
+		if timeout <= 0 {
+			timeout = cfg.Timeout
+		}
+
+		if timeout <= 0 {
+			timeout = defaultSmsTimeout
+		}
+
+		routes = append(routes, smsRoute{
+			provider: provider,
+			priority: providerCfg.Priority,
+			weight:   weight,
+			timeout:  time.Duration(timeout) * time.Second,
+			breaker:  NewCircuitBreaker(cfg.CircuitBreaker),
+		})
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].priority < routes[j].priority
+	})
+
+	return &SmsService{
+		providers: routes,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
 }
 
 type SmsRequest struct {
@@ -25,33 +133,136 @@ type SmsRequest struct {
 	Content     string `json:"content"`
 }
 
-func (r SmsRequest) String() string {
-	text, _ := json.Marshal(r)
-	return string(text)
+// Send tries the providers in routing order until one of them accepts the message. Providers with an open
+// circuit breaker are skipped. It gives up when ctx is done.
+func (s *SmsService) Send(ctx context.Context, phoneNumber string, content string) (SmsReceipt, error) {
+	var failures []string
+	for _, route := range s.route() {
+		if ctx.Err() != nil {
+			return SmsReceipt{}, ctx.Err()
+		}
+
+		messageID, err := route.send(ctx, phoneNumber, content)
+		if err == nil {
+			return SmsReceipt{Provider: route.provider.Name(), MessageID: messageID}, nil
+		}
+
+		log.Println(fmt.Sprintf("SMS provider %s failed, trying the next one", route.provider.Name()), err)
+		failures = append(failures, fmt.Sprintf("%s: %v", route.provider.Name(), err))
+	}
+
+	return SmsReceipt{}, SmsDeliveryError{Failures: failures}
 }
 
-func (s SmsService) SendOtp(phoneNumber string, otp string) error {
-	request := &SmsRequest{
-		PhoneNumber: phoneNumber,
-		Content:     fmt.Sprintf("Your OTP is: %s", otp),
+// send gives the provider its own timeout and reports the outcome to its circuit breaker.
+func (r smsRoute) send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	if !r.breaker.Allow() {
+		return "", CircuitOpenError{Provider: r.provider.Name()}
 	}
 
-	log.Println("----- SMS message -----")
-	log.Println(request)
-	log.Println("-----------------------")
+	requestCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
 
-	buf := new(bytes.Buffer)
-	_ = json.NewEncoder(buf).Encode(request)
-	req, _ := http.NewRequest("POST", s.url, buf)
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return err
+	messageID, err := r.provider.Send(requestCtx, phoneNumber, content)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, it says nothing about the provider.
+		r.breaker.Cancel()
+	} else {
+		r.breaker.Done(!isProviderFailure(err))
 	}
 
-	defer func() {
-		_ = res.Body.Close()
-	}()
+	return messageID, err
+}
+
+// isProviderFailure tells errors of an unhealthy provider from messages it rejected. A 4xx response other
+// than a timeout or throttling means the provider is up.
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if providerErr, ok := err.(SmsProviderError); ok && providerErr.StatusCode >= 400 && providerErr.StatusCode < 500 {
+		return providerErr.StatusCode == http.StatusRequestTimeout || providerErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// ProviderStates returns the circuit breaker of every provider in priority order.
+func (s *SmsService) ProviderStates() []SmsProviderState {
+	states := make([]SmsProviderState, 0, len(s.providers))
+	for _, route := range s.providers {
+		states = append(states, SmsProviderState{
+			Name:                route.provider.Name(),
+			Priority:            route.priority,
+			CircuitBreakerStats: route.breaker.Stats(),
+		})
+	}
+
+	return states
+}
+
+// ParseDeliveryReport verifies the signature of a delivery report sent to the webhook of the named provider.
+func (s *SmsService) ParseDeliveryReport(provider string, req *http.Request, body []byte) (SmsDeliveryReport, error) {
+	for _, route := range s.providers {
+		if route.provider.Name() == provider {
+			return route.provider.ParseDeliveryReport(req, body)
+		}
+	}
+
+	return SmsDeliveryReport{}, UnknownSmsProviderError{Provider: provider}
+}
+
+// route orders the providers by priority. Providers with the same priority are shuffled by weight,
+// so traffic is spread between them and each one is still tried once.
+func (s *SmsService) route() []smsRoute {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	routes := make([]smsRoute, 0, len(s.providers))
+	for start := 0; start < len(s.providers); {
+		end := start
+		for end < len(s.providers) && s.providers[end].priority == s.providers[start].priority {
+			end++
+		}
+
+		group := append([]smsRoute{}, s.providers[start:end]...)
+		for len(group) > 0 {
+			total := 0
+			for _, route := range group {
+				total += route.weight
+			}
+
+			pick := s.random.Intn(total)
+			for i, route := range group {
+				if pick < route.weight {
+					routes = append(routes, route)
+					group = append(group[:i], group[i+1:]...)
+					break
+				}
+
+				pick -= route.weight
+			}
+		}
+
+		start = end
+	}
+
+	return routes
+}
+
+type SmsDeliveryError struct {
+	Failures []string
+}
+
+func (e SmsDeliveryError) Error() string {
+	return fmt.Sprintf("All SMS providers failed: %s ", strings.Join(e.Failures, "; "))
+}
+
+type UnknownSmsProviderError struct {
+	Provider string
+}
 
-	return nil
+func (e UnknownSmsProviderError) Error() string {
+	return fmt.Sprintf("SMS provider %s is not configured ", e.Provider)
 }
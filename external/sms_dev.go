@@ -0,0 +1,104 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"tbox_backend/config"
+	"time"
+)
+
+const defaultDevSmsInboxSize = 500
+
+// DevSmsMessage is a message kept by the dev provider instead of being sent.
+type DevSmsMessage struct {
+	ID          string
+	PhoneNumber string
+	Content     string
+	CreatedAt   time.Time
+}
+
+// DevSmsInbox keeps the most recent messages sent through the dev provider in memory, so developers and QA
+// automation can read OTPs without a real SMS gateway. Each instance of the API has its own inbox.
+type DevSmsInbox struct {
+	mutex    sync.Mutex
+	messages []DevSmsMessage
+	next     int
+	count    int
+	lastID   int
+}
+
+func NewDevSmsInbox(size int) *DevSmsInbox {
+	if size <= 0 {
+		size = defaultDevSmsInboxSize
+	}
+
+	return &DevSmsInbox{messages: make([]DevSmsMessage, size)}
+}
+
+// Add keeps the message, dropping the oldest one when the inbox is full.
+func (i *DevSmsInbox) Add(phoneNumber string, content string) DevSmsMessage {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.lastID++
+	message := DevSmsMessage{
+		ID:          strconv.Itoa(i.lastID),
+		PhoneNumber: phoneNumber,
+		Content:     content,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	i.messages[i.next] = message
+	i.next = (i.next + 1) % len(i.messages)
+	if i.count < len(i.messages) {
+		i.count++
+	}
+
+	return message
+}
+
+// Messages returns the kept messages sent to the phone number, newest first. An empty phone number
+// returns the messages of every number.
+func (i *DevSmsInbox) Messages(phoneNumber string) []DevSmsMessage {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	messages := make([]DevSmsMessage, 0)
+	for n := 1; n <= i.count; n++ {
+		message := i.messages[(i.next-n+len(i.messages))%len(i.messages)]
+		if phoneNumber == "" || message.PhoneNumber == phoneNumber {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// DevSmsProvider accepts every message and keeps it in the DevSmsInbox. It has no delivery reports.
+type DevSmsProvider struct {
+	cfg   config.SmsProvider
+	inbox *DevSmsInbox
+}
+
+func NewDevSmsProvider(cfg config.SmsProvider, inbox *DevSmsInbox) *DevSmsProvider {
+	return &DevSmsProvider{cfg: cfg, inbox: inbox}
+}
+
+func (p DevSmsProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p DevSmsProvider) Send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	return p.inbox.Add(phoneNumber, content).ID, nil
+}
+
+func (p DevSmsProvider) ParseDeliveryReport(req *http.Request, body []byte) (SmsDeliveryReport, error) {
+	return SmsDeliveryReport{}, fmt.Errorf("SMS provider %s has no delivery reports ", p.cfg.Name)
+}
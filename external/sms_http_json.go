@@ -0,0 +1,103 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"tbox_backend/config"
+)
+
+// HttpJsonSmsProvider posts {"phone_number", "content"} as JSON to the configured url and reads the message id
+// from the "id" field of the response. Delivery reports are JSON {"message_id", "status"} signed with
+// an X-Signature header holding the hex HMAC-SHA256 of the body keyed with WebhookSecret.
+type HttpJsonSmsProvider struct {
+	cfg    config.SmsProvider
+	client *http.Client
+}
+
+type httpJsonSmsResponse struct {
+	ID json.RawMessage `json:"id"`
+}
+
+type httpJsonDeliveryReport struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+}
+
+var httpJsonStatuses = map[string]string{
+	SmsStatusQueued:    SmsStatusQueued,
+	SmsStatusSent:      SmsStatusSent,
+	SmsStatusDelivered: SmsStatusDelivered,
+	SmsStatusFailed:    SmsStatusFailed,
+}
+
+func NewHttpJsonSmsProvider(cfg config.SmsProvider, client *http.Client) *HttpJsonSmsProvider {
+	return &HttpJsonSmsProvider{cfg: cfg, client: client}
+}
+
+func (p HttpJsonSmsProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p HttpJsonSmsProvider) Send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(SmsRequest{PhoneNumber: phoneNumber, Content: content})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.Url, buf)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	body, err := do(p.client, p.cfg.Name, req)
+	if err != nil {
+		return "", err
+	}
+
+	// The id is optional, a gateway without delivery reports does not need to return one.
+	var response httpJsonSmsResponse
+	if json.Unmarshal(body, &response) != nil || len(response.ID) == 0 {
+		return "", nil
+	}
+
+	var messageID string
+	if json.Unmarshal(response.ID, &messageID) != nil {
+		messageID = string(response.ID)
+	}
+
+	return messageID, nil
+}
+
+func (p HttpJsonSmsProvider) ParseDeliveryReport(req *http.Request, body []byte) (SmsDeliveryReport, error) {
+	signature, err := hex.DecodeString(req.Header.Get("X-Signature"))
+	if err != nil || p.cfg.WebhookSecret == "" {
+		return SmsDeliveryReport{}, InvalidSmsSignatureError{Provider: p.cfg.Name}
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.cfg.WebhookSecret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return SmsDeliveryReport{}, InvalidSmsSignatureError{Provider: p.cfg.Name}
+	}
+
+	var report httpJsonDeliveryReport
+	err = json.Unmarshal(body, &report)
+	if err != nil {
+		return SmsDeliveryReport{}, err
+	}
+
+	status, exists := httpJsonStatuses[report.Status]
+	if !exists || report.MessageID == "" {
+		return SmsDeliveryReport{}, fmt.Errorf("Delivery report of SMS provider %s is invalid ", p.cfg.Name)
+	}
+
+	return SmsDeliveryReport{Provider: p.cfg.Name, MessageID: report.MessageID, Status: status}, nil
+}
@@ -0,0 +1,133 @@
+package external
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"tbox_backend/config"
+)
+
+// NexmoSmsProvider sends through the Nexmo (Vonage) SMS API. Url is the API base, e.g. https://rest.nexmo.com,
+// AccountID is the api key and AuthToken the api secret. Delivery receipts are signed with WebhookSecret,
+// the signature secret of the account.
+type NexmoSmsProvider struct {
+	cfg    config.SmsProvider
+	client *http.Client
+}
+
+type nexmoResponse struct {
+	Messages []struct {
+		MessageID string `json:"message-id"`
+		Status    string `json:"status"`
+		ErrorText string `json:"error-text"`
+	} `json:"messages"`
+}
+
+var nexmoStatuses = map[string]string{
+	"accepted":  SmsStatusQueued,
+	"buffered":  SmsStatusQueued,
+	"delivered": SmsStatusDelivered,
+	"expired":   SmsStatusFailed,
+	"failed":    SmsStatusFailed,
+	"rejected":  SmsStatusFailed,
+}
+
+func NewNexmoSmsProvider(cfg config.SmsProvider, client *http.Client) *NexmoSmsProvider {
+	return &NexmoSmsProvider{cfg: cfg, client: client}
+}
+
+func (p NexmoSmsProvider) Name() string {
+	return p.cfg.Name
+}
+
+// Send checks the per message status as well, Nexmo reports most failures with a 200 response.
+func (p NexmoSmsProvider) Send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	form := url.Values{}
+	form.Set("api_key", p.cfg.AccountID)
+	form.Set("api_secret", p.cfg.AuthToken)
+	form.Set("to", phoneNumber)
+	form.Set("from", p.cfg.From)
+	form.Set("text", content)
+	if p.cfg.WebhookUrl != "" {
+		form.Set("callback", p.cfg.WebhookUrl)
+	}
+
+	endpoint := fmt.Sprintf("%s/sms/json", strings.TrimRight(p.cfg.Url, "/"))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := do(p.client, p.cfg.Name, req)
+	if err != nil {
+		return "", err
+	}
+
+	var response nexmoResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", err
+	}
+
+	for _, message := range response.Messages {
+		if message.Status != "0" {
+			return "", fmt.Errorf("SMS provider %s rejected the message: %s ", p.cfg.Name, message.ErrorText)
+		}
+	}
+
+	if len(response.Messages) == 0 {
+		return "", fmt.Errorf("SMS provider %s did not accept the message ", p.cfg.Name)
+	}
+
+	return response.Messages[0].MessageID, nil
+}
+
+// ParseDeliveryReport checks the sig parameter, the HMAC-SHA256 of the sorted "&key=value" parameters.
+// Receipts may come as query parameters or as a form body.
+func (p NexmoSmsProvider) ParseDeliveryReport(req *http.Request, body []byte) (SmsDeliveryReport, error) {
+	params := req.URL.Query()
+	if len(body) > 0 {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return SmsDeliveryReport{}, err
+		}
+
+		params = form
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "sig" {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	replacer := strings.NewReplacer("&", "_", "=", "_")
+	payload := ""
+	for _, key := range keys {
+		payload += "&" + key + "=" + replacer.Replace(params.Get(key))
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.cfg.WebhookSecret))
+	mac.Write([]byte(payload))
+	signature, err := hex.DecodeString(params.Get("sig"))
+	if err != nil || p.cfg.WebhookSecret == "" || !hmac.Equal(signature, mac.Sum(nil)) {
+		return SmsDeliveryReport{}, InvalidSmsSignatureError{Provider: p.cfg.Name}
+	}
+
+	status, exists := nexmoStatuses[params.Get("status")]
+	if !exists || params.Get("messageId") == "" {
+		return SmsDeliveryReport{}, fmt.Errorf("Delivery report of SMS provider %s is invalid ", p.cfg.Name)
+	}
+
+	return SmsDeliveryReport{Provider: p.cfg.Name, MessageID: params.Get("messageId"), Status: status}, nil
+}
@@ -0,0 +1,91 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"tbox_backend/config"
+)
+
+const (
+	HttpJsonSmsProviderType = "http_json"
+	TwilioSmsProviderType   = "twilio"
+	NexmoSmsProviderType    = "nexmo"
+	DevSmsProviderType      = "dev"
+)
+
+// Delivery statuses reported by providers are normalized to these values.
+const (
+	SmsStatusQueued    = "queued"
+	SmsStatusSent      = "sent"
+	SmsStatusDelivered = "delivered"
+	SmsStatusFailed    = "failed"
+)
+
+type ISmsProvider interface {
+	Name() string
+	// Send returns the id the provider assigned to the message. It gives up when ctx is done.
+	Send(ctx context.Context, phoneNumber string, content string) (string, error)
+	ParseDeliveryReport(req *http.Request, body []byte) (SmsDeliveryReport, error)
+}
+
+type SmsDeliveryReport struct {
+	Provider  string
+	MessageID string
+	Status    string
+}
+
+func NewSmsProvider(cfg config.SmsProvider, client *http.Client) (ISmsProvider, error) {
+	switch cfg.Type {
+	case HttpJsonSmsProviderType:
+		return NewHttpJsonSmsProvider(cfg, client), nil
+	case TwilioSmsProviderType:
+		return NewTwilioSmsProvider(cfg, client), nil
+	case NexmoSmsProviderType:
+		return NewNexmoSmsProvider(cfg, client), nil
+	default:
+		return nil, fmt.Errorf("SMS provider type %s is not supported ", cfg.Type)
+	}
+}
+
+type SmsProviderError struct {
+	Provider   string
+	StatusCode int
+}
+
+func (e SmsProviderError) Error() string {
+	return fmt.Sprintf("SMS provider %s responded with status %d ", e.Provider, e.StatusCode)
+}
+
+type InvalidSmsSignatureError struct {
+	Provider string
+}
+
+func (e InvalidSmsSignatureError) Error() string {
+	return fmt.Sprintf("Delivery report signature of SMS provider %s is invalid ", e.Provider)
+}
+
+// do sends the request and treats every non-2xx response as a failure.
+func do(client *http.Client, provider string, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, SmsProviderError{Provider: provider, StatusCode: res.StatusCode}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,472 @@
+package external_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"testing"
+	"time"
+)
+
+type fakeSmsProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *fakeSmsProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeSmsProvider) Send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	p.calls++
+	if p.err != nil {
+		return "", p.err
+	}
+
+	return p.name + "-message", nil
+}
+
+func (p *fakeSmsProvider) ParseDeliveryReport(req *http.Request, body []byte) (external.SmsDeliveryReport, error) {
+	return external.SmsDeliveryReport{Provider: p.name, MessageID: string(body), Status: external.SmsStatusDelivered}, nil
+}
+
+func TestHttpJsonSmsProvider_Send(t *testing.T) {
+	var request external.SmsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":42}`))
+	}))
+	defer server.Close()
+
+	provider := external.NewHttpJsonSmsProvider(config.SmsProvider{Name: "json", Url: server.URL}, server.Client())
+	messageID, err := provider.Send(context.Background(), "0961234567", "Your OTP is: 123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.PhoneNumber != "0961234567" || request.Content != "Your OTP is: 123456" {
+		t.Fatalf("unexpected request %v", request)
+	}
+
+	if messageID != "42" {
+		t.Fatalf("expected message id 42, got %s", messageID)
+	}
+}
+
+func TestHttpJsonSmsProvider_ParseDeliveryReport(t *testing.T) {
+	provider := external.NewHttpJsonSmsProvider(config.SmsProvider{Name: "json", WebhookSecret: "secret"}, http.DefaultClient)
+	body := []byte(`{"message_id":"42","status":"delivered"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/api/webhooks/sms/json", bytes.NewReader(body))
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	report, err := provider.ParseDeliveryReport(req, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (external.SmsDeliveryReport{Provider: "json", MessageID: "42", Status: external.SmsStatusDelivered}) {
+		t.Fatalf("unexpected report %v", report)
+	}
+
+	req.Header.Set("X-Signature", hex.EncodeToString([]byte("forged")))
+	_, err = provider.ParseDeliveryReport(req, body)
+	if _, ok := err.(external.InvalidSmsSignatureError); !ok {
+		t.Fatalf("expected InvalidSmsSignatureError, got %v", err)
+	}
+}
+
+func TestHttpJsonSmsProvider_Send_Non2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider := external.NewHttpJsonSmsProvider(config.SmsProvider{Name: "json", Url: server.URL}, server.Client())
+	_, err := provider.Send(context.Background(), "0961234567", "Your OTP is: 123456")
+	if providerErr, ok := err.(external.SmsProviderError); !ok || providerErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected SmsProviderError, got %v", err)
+	}
+}
+
+func TestTwilioSmsProvider_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" || !ok || username != "AC123" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("To") != "0961234567" || r.FormValue("From") != "TBOX" || r.FormValue("Body") != "hello" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM123"}`))
+	}))
+	defer server.Close()
+
+	provider := external.NewTwilioSmsProvider(config.SmsProvider{
+		Name:      "twilio",
+		Url:       server.URL,
+		AccountID: "AC123",
+		AuthToken: "token",
+		From:      "TBOX",
+	}, server.Client())
+
+	messageID, err := provider.Send(context.Background(), "0961234567", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID != "SM123" {
+		t.Fatalf("expected message id SM123, got %s", messageID)
+	}
+}
+
+func TestTwilioSmsProvider_ParseDeliveryReport(t *testing.T) {
+	provider := external.NewTwilioSmsProvider(config.SmsProvider{
+		Name:       "twilio",
+		AuthToken:  "token",
+		WebhookUrl: "https://tbox.example/api/webhooks/sms/twilio",
+	}, http.DefaultClient)
+
+	form := url.Values{}
+	form.Set("MessageSid", "SM123")
+	form.Set("MessageStatus", "undelivered")
+	mac := hmac.New(sha1.New, []byte("token"))
+	mac.Write([]byte("https://tbox.example/api/webhooks/sms/twilioMessageSidSM123MessageStatusundelivered"))
+
+	body := []byte(form.Encode())
+	req := httptest.NewRequest("POST", "/api/webhooks/sms/twilio", bytes.NewReader(body))
+	req.Header.Set("X-Twilio-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	report, err := provider.ParseDeliveryReport(req, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (external.SmsDeliveryReport{Provider: "twilio", MessageID: "SM123", Status: external.SmsStatusFailed}) {
+		t.Fatalf("unexpected report %v", report)
+	}
+
+	form.Set("MessageStatus", "delivered")
+	_, err = provider.ParseDeliveryReport(req, []byte(form.Encode()))
+	if _, ok := err.(external.InvalidSmsSignatureError); !ok {
+		t.Fatalf("expected InvalidSmsSignatureError, got %v", err)
+	}
+}
+
+func TestNexmoSmsProvider_Send(t *testing.T) {
+	status := "0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sms/json" || r.FormValue("api_key") != "key" || r.FormValue("api_secret") != "secret" || r.FormValue("text") != "hello" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"message-count":"1","messages":[{"message-id":"0A01","status":"` + status + `","error-text":"Throttled"}]}`))
+	}))
+	defer server.Close()
+
+	provider := external.NewNexmoSmsProvider(config.SmsProvider{
+		Name:      "nexmo",
+		Url:       server.URL,
+		AccountID: "key",
+		AuthToken: "secret",
+		From:      "TBOX",
+	}, server.Client())
+
+	messageID, err := provider.Send(context.Background(), "0961234567", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID != "0A01" {
+		t.Fatalf("expected message id 0A01, got %s", messageID)
+	}
+
+	status = "1"
+	_, err = provider.Send(context.Background(), "0961234567", "hello")
+	if err == nil {
+		t.Fatalf("expected rejected message to fail")
+	}
+}
+
+func TestNexmoSmsProvider_ParseDeliveryReport(t *testing.T) {
+	provider := external.NewNexmoSmsProvider(config.SmsProvider{Name: "nexmo", WebhookSecret: "secret"}, http.DefaultClient)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("&messageId=0A01&status=delivered&to=84961234567"))
+
+	query := url.Values{}
+	query.Set("messageId", "0A01")
+	query.Set("status", "delivered")
+	query.Set("to", "84961234567")
+	query.Set("sig", hex.EncodeToString(mac.Sum(nil)))
+	req := httptest.NewRequest("GET", "/api/webhooks/sms/nexmo?"+query.Encode(), nil)
+	report, err := provider.ParseDeliveryReport(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (external.SmsDeliveryReport{Provider: "nexmo", MessageID: "0A01", Status: external.SmsStatusDelivered}) {
+		t.Fatalf("unexpected report %v", report)
+	}
+
+	query.Set("status", "failed")
+	req = httptest.NewRequest("GET", "/api/webhooks/sms/nexmo?"+query.Encode(), nil)
+	_, err = provider.ParseDeliveryReport(req, nil)
+	if _, ok := err.(external.InvalidSmsSignatureError); !ok {
+		t.Fatalf("expected InvalidSmsSignatureError, got %v", err)
+	}
+}
+
+func TestNewSmsProvider_UnknownType(t *testing.T) {
+	_, err := external.NewSmsProvider(config.SmsProvider{Name: "unknown", Type: "carrier_pigeon"}, http.DefaultClient)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSmsService_Send_Failover(t *testing.T) {
+	primary := &fakeSmsProvider{name: "primary", err: errors.New("down")}
+	secondary := &fakeSmsProvider{name: "secondary"}
+	smsService, err := external.NewSmsServiceWithProviders(
+		config.SmsService{Providers: []config.SmsProvider{{Priority: 2}, {Priority: 1}}},
+		secondary,
+		primary,
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := smsService.Send(context.Background(), "0961234567", "Your OTP is: 123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt != (external.SmsReceipt{Provider: "secondary", MessageID: "secondary-message"}) {
+		t.Fatalf("unexpected receipt %v", receipt)
+	}
+
+	if primary.calls != 1 || secondary.calls != 1 {
+		t.Fatalf("expected failover from primary to secondary, got %d and %d calls", primary.calls, secondary.calls)
+	}
+}
+
+func TestSmsService_Send_AllFailed(t *testing.T) {
+	first := &fakeSmsProvider{name: "first", err: errors.New("down")}
+	second := &fakeSmsProvider{name: "second", err: errors.New("down")}
+	smsService, _ := external.NewSmsServiceWithProviders(config.SmsService{Providers: []config.SmsProvider{{Priority: 1}, {Priority: 2}}}, first, second)
+
+	_, err := smsService.Send(context.Background(), "0961234567", "hello")
+	if _, ok := err.(external.SmsDeliveryError); !ok {
+		t.Fatalf("expected SmsDeliveryError, got %v", err)
+	}
+}
+
+func TestSmsService_Send_Weight(t *testing.T) {
+	heavy := &fakeSmsProvider{name: "heavy"}
+	light := &fakeSmsProvider{name: "light"}
+	smsService, _ := external.NewSmsServiceWithProviders(
+		config.SmsService{Providers: []config.SmsProvider{{Priority: 1, Weight: 9}, {Priority: 1, Weight: 1}}},
+		heavy,
+		light,
+	)
+
+	for i := 0; i < 1000; i++ {
+		_, _ = smsService.Send(context.Background(), "0961234567", "hello")
+	}
+
+	if heavy.calls+light.calls != 1000 || heavy.calls < 800 || light.calls < 50 {
+		t.Fatalf("expected traffic split by weight, got %d and %d", heavy.calls, light.calls)
+	}
+}
+
+func TestSmsService_Send_TimeoutFailover(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	delivered := false
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer fast.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	cfgs := []config.SmsProvider{
+		{Name: "slow", Url: slow.URL, Priority: 1},
+		{Name: "fast", Url: fast.URL, Priority: 2},
+	}
+
+	smsService, _ := external.NewSmsServiceWithProviders(
+		config.SmsService{Providers: cfgs},
+		external.NewHttpJsonSmsProvider(cfgs[0], client),
+		external.NewHttpJsonSmsProvider(cfgs[1], client),
+	)
+
+	_, err := smsService.Send(context.Background(), "0961234567", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !delivered {
+		t.Fatalf("expected failover after timeout")
+	}
+}
+
+func TestSmsService_ParseDeliveryReport(t *testing.T) {
+	provider := &fakeSmsProvider{name: "json"}
+	smsService, _ := external.NewSmsServiceWithProviders(config.SmsService{Providers: []config.SmsProvider{{Priority: 1}}}, provider)
+
+	report, err := smsService.ParseDeliveryReport("json", httptest.NewRequest("POST", "/", nil), []byte("42"))
+	if err != nil || report.MessageID != "42" {
+		t.Fatalf("unexpected report %v, %v", report, err)
+	}
+
+	_, err = smsService.ParseDeliveryReport("unknown", httptest.NewRequest("POST", "/", nil), nil)
+	if _, ok := err.(external.UnknownSmsProviderError); !ok {
+		t.Fatalf("expected UnknownSmsProviderError, got %v", err)
+	}
+}
+
+func TestNewSmsService(t *testing.T) {
+	_, err := external.NewSmsService(config.SmsService{}, nil)
+	if err == nil {
+		t.Fatalf("expected error without providers")
+	}
+
+	_, err = external.NewSmsService(config.SmsService{Providers: []config.SmsProvider{{Name: "json", Type: external.HttpJsonSmsProviderType}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSmsService_Send_CircuitOpen(t *testing.T) {
+	primary := &fakeSmsProvider{name: "primary", err: errors.New("down")}
+	secondary := &fakeSmsProvider{name: "secondary"}
+	smsService, _ := external.NewSmsServiceWithProviders(
+		config.SmsService{
+			CircuitBreaker: config.CircuitBreaker{FailureThreshold: 2, OpenTime: 60},
+			Providers:      []config.SmsProvider{{Priority: 1}, {Priority: 2}},
+		},
+		primary,
+		secondary,
+	)
+
+	for i := 0; i < 5; i++ {
+		_, err := smsService.Send(context.Background(), "0961234567", "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if primary.calls != 2 || secondary.calls != 5 {
+		t.Fatalf("expected the open primary to be skipped, got %d and %d calls", primary.calls, secondary.calls)
+	}
+
+	states := smsService.ProviderStates()
+	if len(states) != 2 || states[0].Name != "primary" || states[0].State != external.CircuitOpen ||
+		states[0].Rejected != 3 || states[1].State != external.CircuitClosed {
+		t.Fatalf("unexpected provider states %v", states)
+	}
+}
+
+func TestSmsService_Send_ClientErrorKeepsCircuitClosed(t *testing.T) {
+	provider := &fakeSmsProvider{name: "json", err: external.SmsProviderError{Provider: "json", StatusCode: http.StatusBadRequest}}
+	smsService, _ := external.NewSmsServiceWithProviders(
+		config.SmsService{
+			CircuitBreaker: config.CircuitBreaker{FailureThreshold: 1},
+			Providers:      []config.SmsProvider{{Priority: 1}},
+		},
+		provider,
+	)
+
+	_, _ = smsService.Send(context.Background(), "0961234567", "hello")
+	_, _ = smsService.Send(context.Background(), "0961234567", "hello")
+	if provider.calls != 2 || smsService.ProviderStates()[0].State != external.CircuitClosed {
+		t.Fatalf("expected a rejected message not to open the breaker")
+	}
+}
+
+func TestSmsService_Send_Cancelled(t *testing.T) {
+	var received bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	cfg := config.SmsService{Providers: []config.SmsProvider{{Name: "json", Url: server.URL, Priority: 1}}}
+	smsService, _ := external.NewSmsServiceWithProviders(cfg, external.NewHttpJsonSmsProvider(cfg.Providers[0], &http.Client{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := smsService.Send(ctx, "0961234567", "hello")
+	if err != context.Canceled || received {
+		t.Fatalf("expected a cancelled send not to reach the provider, got %v", err)
+	}
+
+	if smsService.ProviderStates()[0].Failures != 0 {
+		t.Fatalf("expected a cancelled send not to count as a failure")
+	}
+}
+
+func TestDevSmsInbox(t *testing.T) {
+	inbox := external.NewDevSmsInbox(3)
+	provider := external.NewDevSmsProvider(config.SmsProvider{Name: "dev"}, inbox)
+	for _, phoneNumber := range []string{"0961234567", "0967654321", "0961234567", "0961234567"} {
+		_, err := provider.Send(context.Background(), phoneNumber, "Your OTP is: "+phoneNumber)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages := inbox.Messages("0961234567")
+	if len(messages) != 2 || messages[0].ID != "4" || messages[1].ID != "3" {
+		t.Fatalf("expected the 2 kept messages of the number newest first, got %v", messages)
+	}
+
+	if len(inbox.Messages("")) != 3 {
+		t.Fatalf("expected the inbox to keep 3 messages")
+	}
+}
+
+func TestNewSmsService_DevProvider(t *testing.T) {
+	cfg := config.SmsService{Providers: []config.SmsProvider{
+		{Name: "dev", Type: external.DevSmsProviderType, Priority: 0},
+		{Name: "json", Type: external.HttpJsonSmsProviderType, Priority: 1},
+	}}
+
+	smsService, err := external.NewSmsService(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if states := smsService.ProviderStates(); len(states) != 1 || states[0].Name != "json" {
+		t.Fatalf("expected the dev provider to be skipped without an inbox, got %v", states)
+	}
+
+	inbox := external.NewDevSmsInbox(10)
+	smsService, _ = external.NewSmsService(cfg, inbox)
+	receipt, err := smsService.Send(context.Background(), "0961234567", "Your OTP is: 123456")
+	if err != nil || receipt.Provider != "dev" || len(inbox.Messages("0961234567")) != 1 {
+		t.Fatalf("expected the message to be kept by the dev provider, got %v, %v", receipt, err)
+	}
+}
@@ -0,0 +1,106 @@
+package external
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"tbox_backend/config"
+)
+
+// TwilioSmsProvider sends through the Twilio Messages API. Url is the API base, e.g. https://api.twilio.com,
+// AccountID is the account SID and AuthToken its auth token. Status callbacks are signed with the auth token
+// over WebhookUrl, the public url Twilio posts to.
+type TwilioSmsProvider struct {
+	cfg    config.SmsProvider
+	client *http.Client
+}
+
+type twilioResponse struct {
+	Sid string `json:"sid"`
+}
+
+var twilioStatuses = map[string]string{
+	"accepted":    SmsStatusQueued,
+	"queued":      SmsStatusQueued,
+	"sending":     SmsStatusQueued,
+	"sent":        SmsStatusSent,
+	"delivered":   SmsStatusDelivered,
+	"undelivered": SmsStatusFailed,
+	"failed":      SmsStatusFailed,
+}
+
+func NewTwilioSmsProvider(cfg config.SmsProvider, client *http.Client) *TwilioSmsProvider {
+	return &TwilioSmsProvider{cfg: cfg, client: client}
+}
+
+func (p TwilioSmsProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p TwilioSmsProvider) Send(ctx context.Context, phoneNumber string, content string) (string, error) {
+	form := url.Values{}
+	form.Set("To", phoneNumber)
+	form.Set("From", p.cfg.From)
+	form.Set("Body", content)
+	if p.cfg.WebhookUrl != "" {
+		form.Set("StatusCallback", p.cfg.WebhookUrl)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(p.cfg.Url, "/"), url.PathEscape(p.cfg.AccountID))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.cfg.AccountID, p.cfg.AuthToken)
+	body, err := do(p.client, p.cfg.Name, req)
+	if err != nil {
+		return "", err
+	}
+
+	var response twilioResponse
+	_ = json.Unmarshal(body, &response)
+	return response.Sid, nil
+}
+
+// ParseDeliveryReport checks X-Twilio-Signature, the base64 HMAC-SHA1 of the callback url followed by
+// the sorted form parameters.
+func (p TwilioSmsProvider) ParseDeliveryReport(req *http.Request, body []byte) (SmsDeliveryReport, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SmsDeliveryReport{}, err
+	}
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	payload := p.cfg.WebhookUrl
+	for _, key := range keys {
+		payload += key + form.Get(key)
+	}
+
+	mac := hmac.New(sha1.New, []byte(p.cfg.AuthToken))
+	mac.Write([]byte(payload))
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get("X-Twilio-Signature"))
+	if err != nil || p.cfg.AuthToken == "" || !hmac.Equal(signature, mac.Sum(nil)) {
+		return SmsDeliveryReport{}, InvalidSmsSignatureError{Provider: p.cfg.Name}
+	}
+
+	status, exists := twilioStatuses[form.Get("MessageStatus")]
+	if !exists || form.Get("MessageSid") == "" {
+		return SmsDeliveryReport{}, fmt.Errorf("Delivery report of SMS provider %s is invalid ", p.cfg.Name)
+	}
+
+	return SmsDeliveryReport{Provider: p.cfg.Name, MessageID: form.Get("MessageSid"), Status: status}, nil
+}
@@ -0,0 +1,100 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"tbox_backend/config"
+	"time"
+)
+
+const defaultVoiceTimeout = 5
+
+// IVoiceService places text-to-speech calls, the voice channel of OTP delivery.
+type IVoiceService interface {
+	Call(ctx context.Context, phoneNumber string, speech string) (VoiceReceipt, error)
+}
+
+// VoiceReceipt identifies a call accepted by the provider.
+type VoiceReceipt struct {
+	Provider string
+	CallID   string
+}
+
+// VoiceRequest is posted as JSON to the configured url. The speech is read out in Language at Rate,
+// 1 being the normal speed of the provider.
+type VoiceRequest struct {
+	PhoneNumber string  `json:"phone_number"`
+	Speech      string  `json:"speech"`
+	Language    string  `json:"language"`
+	Rate        float64 `json:"rate"`
+}
+
+type voiceResponse struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// HttpJsonVoiceService submits calls to a provider taking a VoiceRequest, and reads the call id from
+// the "id" field of the response.
+type HttpJsonVoiceService struct {
+	cfg    config.VoiceService
+	client *http.Client
+}
+
+func NewHttpJsonVoiceService(cfg config.VoiceService, client *http.Client) *HttpJsonVoiceService {
+	return &HttpJsonVoiceService{cfg: cfg, client: client}
+}
+
+func NewVoiceService(cfg config.VoiceService) (*HttpJsonVoiceService, error) {
+	if cfg.Url == "" {
+		return nil, fmt.Errorf("No voice provider is configured ")
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultVoiceTimeout
+	}
+
+	return NewHttpJsonVoiceService(cfg, &http.Client{Timeout: time.Duration(timeout) * time.Second}), nil
+}
+
+func (s HttpJsonVoiceService) Call(ctx context.Context, phoneNumber string, speech string) (VoiceReceipt, error) {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(VoiceRequest{
+		PhoneNumber: phoneNumber,
+		Speech:      speech,
+		Language:    s.cfg.Language,
+		Rate:        s.cfg.Rate,
+	})
+
+	if err != nil {
+		return VoiceReceipt{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.Url, buf)
+	if err != nil {
+		return VoiceReceipt{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.AuthToken)
+	}
+
+	body, err := do(s.client, s.cfg.Name, req)
+	if err != nil {
+		return VoiceReceipt{}, err
+	}
+
+	receipt := VoiceReceipt{Provider: s.cfg.Name}
+	var response voiceResponse
+	if json.Unmarshal(body, &response) == nil && len(response.ID) > 0 {
+		if json.Unmarshal(response.ID, &receipt.CallID) != nil {
+			receipt.CallID = string(response.ID)
+		}
+	}
+
+	return receipt, nil
+}
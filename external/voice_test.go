@@ -0,0 +1,66 @@
+package external_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"testing"
+)
+
+func TestHttpJsonVoiceService_Call(t *testing.T) {
+	var request external.VoiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_, _ = w.Write([]byte(`{"id":"CA123"}`))
+	}))
+	defer server.Close()
+
+	voiceService := external.NewHttpJsonVoiceService(config.VoiceService{
+		Name:      "voice",
+		Url:       server.URL,
+		AuthToken: "token",
+		Language:  "vi-VN",
+		Rate:      0.8,
+	}, server.Client())
+
+	receipt, err := voiceService.Call(context.Background(), "0961234567", "1, 2, 3. 1, 2, 3.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt != (external.VoiceReceipt{Provider: "voice", CallID: "CA123"}) {
+		t.Fatalf("unexpected receipt %v", receipt)
+	}
+
+	if request.PhoneNumber != "0961234567" || request.Speech != "1, 2, 3. 1, 2, 3." || request.Language != "vi-VN" || request.Rate != 0.8 {
+		t.Fatalf("unexpected request %v", request)
+	}
+}
+
+func TestHttpJsonVoiceService_Call_Non2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	voiceService := external.NewHttpJsonVoiceService(config.VoiceService{Name: "voice", Url: server.URL}, server.Client())
+	_, err := voiceService.Call(context.Background(), "0961234567", "1, 2, 3.")
+	if providerErr, ok := err.(external.SmsProviderError); !ok || providerErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected SmsProviderError, got %v", err)
+	}
+}
+
+func TestNewVoiceService(t *testing.T) {
+	_, err := external.NewVoiceService(config.VoiceService{})
+	if err == nil {
+		t.Fatalf("expected error without url")
+	}
+}
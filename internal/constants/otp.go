@@ -0,0 +1,52 @@
+package constants
+
+const (
+	OtpNumericAlphabet      = "numeric"
+	OtpAlphanumericAlphabet = "alphanumeric"
+)
+
+// The alphanumeric alphabet leaves out characters that are easily mistaken for each other (0/O, 1/I/L).
+var otpAlphabets = map[string]string{
+	OtpNumericAlphabet:      "0123456789",
+	OtpAlphanumericAlphabet: "23456789ABCDEFGHJKMNPQRSTUVWXYZ",
+}
+
+// OtpCharacters returns the characters of the named OTP alphabet. An empty name means numeric.
+func OtpCharacters(alphabet string) (string, bool) {
+	if alphabet == "" {
+		alphabet = OtpNumericAlphabet
+	}
+
+	characters, exists := otpAlphabets[alphabet]
+	return characters, exists
+}
+
+// Purposes of an OTP, they key the OTP message templates of a locale.
+const (
+	OtpLoginPurpose       = "login"
+	OtpPhoneChangePurpose = "phone_change"
+	OtpVoiceLoginPurpose  = "voice_login"
+	OtpEmailLoginPurpose  = "email_login"
+	MagicLinkPurpose      = "magic_link"
+)
+
+// Channels an OTP can be delivered through. An empty channel means sms.
+const (
+	OtpSmsChannel   = "sms"
+	OtpVoiceChannel = "voice"
+)
+
+// Reasons an OTP is not sent to a phone number by the country policy.
+const (
+	OtpCountryNotAllowedReason = "country_not_allowed"
+	OtpPrefixBlockedReason     = "prefix_blocked"
+	OtpDailyLimitReason        = "daily_limit"
+)
+
+// Reasons an OTP request has to solve a challenge before it is sent.
+const (
+	OtpChallengeIPReason         = "ip_volume"
+	OtpChallengeFirstTimeReason  = "first_time_burst"
+	OtpChallengeNewCountryReason = "new_country"
+	OtpChallengeInvalidReason    = "invalid_solution"
+)
@@ -4,3 +4,8 @@ const SuccessStatus = 100
 const InvalidRequestStatus = 200
 const TooManyRequestStatus = 201
 const SomethingWentWrongStatus = 202
+const UnauthorizedStatus = 203
+const OtpAttemptsExceededStatus = 204
+const PhoneNumberLockedStatus = 205
+const PhoneNumberBlockedStatus = 206
+const OtpChallengeRequiredStatus = 207
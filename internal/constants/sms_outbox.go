@@ -0,0 +1,9 @@
+package constants
+
+type SmsOutboxStatus int
+
+const (
+	SmsOutboxPendingStatus = iota + 1
+	SmsOutboxSentStatus
+	SmsOutboxDeadStatus
+)
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+)
+
+// OtpChallenge is solved by a proof of work: a nonce such that the SHA-256 hash of "challenge:nonce" starts with
+// Difficulty zero bits. When CaptchaSiteKey is set, a CAPTCHA token may be sent instead of the nonce.
+type OtpChallenge struct {
+	Challenge      string    `json:"challenge"`
+	Reason         string    `json:"reason"`
+	Difficulty     int       `json:"difficulty"`
+	CaptchaSiteKey string    `json:"captcha_site_key,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// OtpChallengeSolution is sent along with the OTP request which was challenged.
+type OtpChallengeSolution struct {
+	Challenge    string
+	Nonce        string
+	CaptchaToken string
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+)
+
+// OtpCountryStat counts the OTPs of a country on a UTC day. SentCount counts the OTPs sent to the country.
+// BlockedCount counts the OTPs refused by the allowed countries, blocked countries and blocked prefixes of the
+// country policy, CappedCount the ones refused by the daily limit.
+type OtpCountryStat struct {
+	CountryCode  string    `json:"country_code"`
+	Day          time.Time `json:"day"`
+	SentCount    int       `json:"sent_count"`
+	BlockedCount int       `json:"blocked_count"`
+	CappedCount  int       `json:"capped_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OtpCountryQuota counts an OTP against the daily limit of its country in the transaction storing it, so OTPs which
+// are not stored are not counted. A Limit of 0 counts the OTP without a cap, an empty CountryCode counts nothing.
+type OtpCountryQuota struct {
+	CountryCode string
+	Day         time.Time
+	Limit       int
+}
@@ -0,0 +1,15 @@
+package dto
+
+import (
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	Revoked   bool
+	ExpiredAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -1,10 +1,48 @@
 package dto
 
+// GenerateOtpRequest takes the locale of the OTP message, the Accept-Language header is used when it is empty.
+// Channel is sms, the default, or voice. A challenged request is sent again with the Challenge and either its
+// proof of work Nonce or a CaptchaToken.
 type GenerateOtpRequest struct {
-	PhoneNumber string `json:"phone_number"`
+	PhoneNumber  string `json:"phone_number"`
+	Locale       string `json:"locale"`
+	Channel      string `json:"channel"`
+	Challenge    string `json:"challenge"`
+	Nonce        string `json:"nonce"`
+	CaptchaToken string `json:"captcha_token"`
 }
 
+// LoginRequest carries either an otp or the credential of a trusted device.
 type LoginRequest struct {
-	PhoneNumber string `json:"phone_number"`
-	Otp         string `json:"otp"`
+	PhoneNumber  string `json:"phone_number"`
+	Otp          string `json:"otp"`
+	DeviceID     string `json:"device_id"`
+	DeviceSecret string `json:"device_secret"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RegisterDeviceRequest struct {
+	Name string `json:"name"`
+}
+
+// EmailOtpRequest asks for an OTP or a magic link sent to the email. Locale works as in GenerateOtpRequest.
+type EmailOtpRequest struct {
+	Email  string `json:"email"`
+	Locale string `json:"locale"`
+}
+
+type EmailLoginRequest struct {
+	Email string `json:"email"`
+	Otp   string `json:"otp"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token"`
 }
@@ -1,10 +1,21 @@
 package dto
 
+import (
+	"strconv"
+)
+
 type Response struct {
 	Status  int    `json:"status"`
 	Message string `json:"message"`
 }
 
+func NewResponse(status int, message string) *Response {
+	return &Response{
+		Status:  status,
+		Message: message,
+	}
+}
+
 type GenerateOtpResponse struct {
 	Response
 }
@@ -16,17 +27,190 @@ func NewGenerateOtpResponse(status int, message string) *GenerateOtpResponse {
 	}}
 }
 
+// OtpSentResponse carries the status token of the OTP, which GET /api/otp/status requires.
+type OtpSentResponse struct {
+	Response
+	StatusToken string `json:"status_token"`
+}
+
+func NewOtpSentResponse(status int, message string, statusToken string) *OtpSentResponse {
+	return &OtpSentResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		StatusToken: statusToken,
+	}
+}
+
+type UserResponse struct {
+	Response
+	User *User `json:"user"`
+}
+
+func NewUserResponse(status int, message string, user *User) *UserResponse {
+	return &UserResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		User: user,
+	}
+}
+
 type LoginResponse struct {
 	Response
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
 }
 
-func NewLoginResponse(status int, message string, token string) *LoginResponse {
+func NewLoginResponse(status int, message string, token Token) *LoginResponse {
 	return &LoginResponse{
 		Response: Response{
 			Status:  status,
 			Message: message,
 		},
-		Token: token,
+		Token:        token.AccessToken,
+		ExpiresIn:    token.ExpiresIn,
+		RefreshToken: token.RefreshToken,
 	}
 }
+
+type DeviceResponse struct {
+	Response
+	Device *DeviceCredential `json:"device"`
+}
+
+func NewDeviceResponse(status int, message string, device *DeviceCredential) *DeviceResponse {
+	return &DeviceResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Device: device,
+	}
+}
+
+type DevicesResponse struct {
+	Response
+	Devices []UserDevice `json:"devices"`
+}
+
+func NewDevicesResponse(status int, message string, devices []UserDevice) *DevicesResponse {
+	return &DevicesResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Devices: devices,
+	}
+}
+
+type OtpStatusResponse struct {
+	Response
+	OtpStatus *OtpStatus `json:"otp_status"`
+}
+
+func NewOtpStatusResponse(status int, message string, otpStatus *OtpStatus) *OtpStatusResponse {
+	return &OtpStatusResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		OtpStatus: otpStatus,
+	}
+}
+
+type SmsProvidersResponse struct {
+	Response
+	Providers []SmsProviderState `json:"providers"`
+}
+
+func NewSmsProvidersResponse(status int, message string, providers []SmsProviderState) *SmsProvidersResponse {
+	return &SmsProvidersResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Providers: providers,
+	}
+}
+
+type OtpCountryStatsResponse struct {
+	Response
+	Stats []OtpCountryStat `json:"stats"`
+}
+
+func NewOtpCountryStatsResponse(status int, message string, stats []OtpCountryStat) *OtpCountryStatsResponse {
+	return &OtpCountryStatsResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Stats: stats,
+	}
+}
+
+type OtpChallengeResponse struct {
+	Response
+	Challenge OtpChallenge `json:"challenge"`
+}
+
+func NewOtpChallengeResponse(status int, message string, challenge OtpChallenge) *OtpChallengeResponse {
+	return &OtpChallengeResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Challenge: challenge,
+	}
+}
+
+type DevSmsInboxResponse struct {
+	Response
+	Messages []DevSmsMessage `json:"messages"`
+}
+
+func NewDevSmsInboxResponse(status int, message string, messages []DevSmsMessage) *DevSmsInboxResponse {
+	return &DevSmsInboxResponse{
+		Response: Response{
+			Status:  status,
+			Message: message,
+		},
+		Messages: messages,
+	}
+}
+
+// IntrospectionResponse follows RFC 7662, so it is not wrapped in Response.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+func NewIntrospectionResponse(active bool, tokenInfo TokenInfo) *IntrospectionResponse {
+	if !active {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Sub:       strconv.Itoa(tokenInfo.UserID),
+		Exp:       tokenInfo.ExpiredAt.Unix(),
+		Iat:       tokenInfo.IssuedAt.Unix(),
+		Jti:       tokenInfo.ID,
+		Scope:     tokenInfo.Scope,
+		ClientID:  tokenInfo.ClientID,
+		TokenType: "Bearer",
+	}
+}
+
+type OAuthErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -3,6 +3,7 @@ package dto_test
 import (
 	"tbox_backend/internal/dto"
 	"testing"
+	"time"
 )
 
 func TestNewGenerateOtpResponse(t *testing.T) {
@@ -17,7 +18,11 @@ func TestNewGenerateOtpResponse(t *testing.T) {
 }
 
 func TestNewLoginResponse(t *testing.T) {
-	loginResponse := dto.NewLoginResponse(100, "test", "abc")
+	loginResponse := dto.NewLoginResponse(100, "test", dto.Token{
+		AccessToken:  "abc",
+		RefreshToken: "def",
+		ExpiresIn:    900,
+	})
 	if loginResponse.Status != 100 {
 		t.Fatalf("expected status: 100")
 	}
@@ -29,4 +34,38 @@ func TestNewLoginResponse(t *testing.T) {
 	if loginResponse.Token != "abc" {
 		t.Fatalf("expected token: abc")
 	}
+
+	if loginResponse.RefreshToken != "def" {
+		t.Fatalf("expected refresh token: def")
+	}
+
+	if loginResponse.ExpiresIn != 900 {
+		t.Fatalf("expected expires in: 900")
+	}
+}
+
+func TestNewIntrospectionResponse(t *testing.T) {
+	now := time.Unix(1577836800, 0)
+	tokenInfo := dto.TokenInfo{
+		ID:        "jti",
+		UserID:    1,
+		Scope:     "user",
+		ClientID:  "tbox_app",
+		IssuedAt:  now,
+		ExpiredAt: now.Add(time.Minute),
+	}
+
+	response := dto.NewIntrospectionResponse(true, tokenInfo)
+	if !response.Active || response.Sub != "1" || response.Iat != 1577836800 || response.Exp != 1577836860 {
+		t.Fatalf("wrong introspection response: %v", response)
+	}
+
+	if response.Scope != "user" || response.ClientID != "tbox_app" || response.Jti != "jti" {
+		t.Fatalf("wrong introspection response: %v", response)
+	}
+
+	response = dto.NewIntrospectionResponse(false, tokenInfo)
+	if response.Active || response.Sub != "" {
+		t.Fatalf("expected inactive response without claims")
+	}
 }
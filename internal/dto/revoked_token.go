@@ -0,0 +1,13 @@
+package dto
+
+import (
+	"time"
+)
+
+type RevokedToken struct {
+	ID        int
+	TokenID   string
+	UserID    int
+	ExpiredAt time.Time
+	CreatedAt time.Time
+}
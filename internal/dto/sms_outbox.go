@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+)
+
+type SmsOutbox struct {
+	ID                int
+	PhoneNumber       string
+	Channel           string
+	Content           string
+	Status            int
+	Attempts          int
+	NextAttemptAt     time.Time
+	LastError         string
+	Provider          string
+	ProviderMessageID string
+	DeliveryStatus    string
+	ClaimToken        string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type SmsDeliveryReport struct {
+	Provider  string
+	MessageID string
+	Status    string
+}
+
+// SmsProviderState is the circuit breaker of an SMS provider, the counters are totals since the process started.
+type SmsProviderState struct {
+	Name                string    `json:"name"`
+	Priority            int       `json:"priority"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at"`
+	Requests            int64     `json:"requests"`
+	Failures            int64     `json:"failures"`
+	Rejected            int64     `json:"rejected"`
+	Trips               int64     `json:"trips"`
+}
+
+type DevSmsMessage struct {
+	ID          string    `json:"id"`
+	PhoneNumber string    `json:"phone_number"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+)
+
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+type TokenInfo struct {
+	ID        string
+	UserID    int
+	Scope     string
+	ClientID  string
+	IssuedAt  time.Time
+	ExpiredAt time.Time
+	// TokenGeneration is the token generation of the user when the token was issued.
+	TokenGeneration int
+	// OtpVerified tells tokens issued by an OTP login from the ones issued by a device login or a refresh token.
+	OtpVerified bool
+}
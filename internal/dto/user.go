@@ -5,9 +5,13 @@ import (
 )
 
 type User struct {
-	ID          int
-	PhoneNumber string
-	Status      int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID          int    `json:"id"`
+	PhoneNumber string `json:"phone_number"`
+	CountryCode string `json:"country_code"`
+	Email       string `json:"email"`
+	Status      int    `json:"status"`
+	// TokenGeneration is raised by logging out everywhere, access tokens of an older generation are revoked.
+	TokenGeneration int       `json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+)
+
+type UserDevice struct {
+	ID         int       `json:"-"`
+	DeviceID   string    `json:"device_id"`
+	UserID     int       `json:"-"`
+	Name       string    `json:"name"`
+	SecretHash string    `json:"-"`
+	Revoked    bool      `json:"-"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	LastUsedIP string    `json:"last_used_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DeviceCredential is returned once when a device is registered. Only the hash of the secret is stored.
+type DeviceCredential struct {
+	DeviceID     string `json:"device_id"`
+	DeviceSecret string `json:"device_secret"`
+}
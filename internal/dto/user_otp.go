@@ -5,9 +5,20 @@ import (
 )
 
 type UserOtp struct {
-	ID        int
-	UserID    int
-	Otp       string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID               int
+	UserID           int
+	OtpHash          string
+	OtpSalt          string
+	FailedAttempts   int
+	InvalidatedCount int
+	LockedUntil      time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type OtpStatus struct {
+	DeliveryStatus    string    `json:"delivery_status"`
+	SentAt            time.Time `json:"sent_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	ResendAvailableAt time.Time `json:"resend_available_at"`
 }
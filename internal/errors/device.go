@@ -0,0 +1,27 @@
+package errors
+
+import (
+	"fmt"
+)
+
+type InvalidDeviceCredentialError struct {
+}
+
+func (e InvalidDeviceCredentialError) Error() string {
+	return "Device credential is invalid "
+}
+
+type NotExistsDeviceError struct {
+	DeviceID string
+}
+
+func (e NotExistsDeviceError) Error() string {
+	return fmt.Sprintf("Device %s does not exist ", e.DeviceID)
+}
+
+type OtpLoginRequiredError struct {
+}
+
+func (e OtpLoginRequiredError) Error() string {
+	return "Please log in with an OTP again to register a device "
+}
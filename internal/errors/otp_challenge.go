@@ -0,0 +1,20 @@
+package errors
+
+import "fmt"
+
+// OtpChallengeRequiredError is returned when an OTP request has to solve a challenge first, Reason is one of the
+// OtpChallenge*Reason constants.
+type OtpChallengeRequiredError struct {
+	Reason string
+}
+
+func (e OtpChallengeRequiredError) Error() string {
+	return fmt.Sprintf("Challenge is required: %s ", e.Reason)
+}
+
+type InvalidOtpChallengeError struct {
+}
+
+func (e InvalidOtpChallengeError) Error() string {
+	return "Challenge is invalid "
+}
@@ -0,0 +1,43 @@
+package errors
+
+type InvalidRefreshTokenError struct {
+}
+
+func (e InvalidRefreshTokenError) Error() string {
+	return "Refresh token is invalid "
+}
+
+type ExpiredRefreshTokenError struct {
+}
+
+func (e ExpiredRefreshTokenError) Error() string {
+	return "Refresh token is expired "
+}
+
+type InvalidTokenError struct {
+}
+
+func (e InvalidTokenError) Error() string {
+	return "Access token is invalid "
+}
+
+type ExpiredTokenError struct {
+}
+
+func (e ExpiredTokenError) Error() string {
+	return "Access token is expired "
+}
+
+type RevokedTokenError struct {
+}
+
+func (e RevokedTokenError) Error() string {
+	return "Access token is revoked "
+}
+
+type InvalidMagicLinkError struct {
+}
+
+func (e InvalidMagicLinkError) Error() string {
+	return "Magic link is invalid, expired or already used "
+}
@@ -2,16 +2,10 @@ package errors
 
 import (
 	"fmt"
+	"math"
+	"time"
 )
 
-type VerifiedPhoneNumberError struct {
-	PhoneNumber string
-}
-
-func (e VerifiedPhoneNumberError) Error() string {
-	return fmt.Sprintf("Phone number %s is already verified ", e.PhoneNumber)
-}
-
 type NotExistsPhoneNumberError struct {
 	PhoneNumber string
 }
@@ -28,11 +22,34 @@ func (e InvalidPhoneNumberError) Error() string {
 	return fmt.Sprintf("Phone number %s is invalid ", e.PhoneNumber)
 }
 
+// BlockedPhoneNumberError is returned when the OTP country policy does not let an OTP be sent to the phone number,
+// Reason is one of the Otp*Reason constants.
+type BlockedPhoneNumberError struct {
+	PhoneNumber string
+	Reason      string
+}
+
+func (e BlockedPhoneNumberError) Error() string {
+	return fmt.Sprintf("OTP cannot be sent to phone number %s: %s ", e.PhoneNumber, e.Reason)
+}
+
+// GeneratedOtpError is returned while the last OTP is in its cooldown, a new one can be sent after RetryAfter.
 type GeneratedOtpError struct {
+	RetryAfter time.Duration
 }
 
 func (e GeneratedOtpError) Error() string {
-	return "OTP has been generated "
+	return fmt.Sprintf("OTP has been generated, retry in %d seconds ", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, the unit of the Retry-After header.
+func (e GeneratedOtpError) RetryAfterSeconds() int {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
 }
 
 type InvalidOtpError struct {
@@ -58,3 +75,68 @@ type ExpiredOtpError struct {
 func (e ExpiredOtpError) Error() string {
 	return fmt.Sprintf("OTP %s is expired ", e.Otp)
 }
+
+type TooManyOtpAttemptsError struct {
+}
+
+func (e TooManyOtpAttemptsError) Error() string {
+	return "Too many incorrect OTP attempts, please request a new OTP "
+}
+
+type LockedPhoneNumberError struct {
+	PhoneNumber string
+	LockedUntil time.Time
+}
+
+func (e LockedPhoneNumberError) Error() string {
+	return fmt.Sprintf("Phone number %s is locked until %s ", e.PhoneNumber, e.LockedUntil.Format(time.RFC3339))
+}
+
+type InvalidOtpAlphabetError struct {
+	Alphabet string
+}
+
+func (e InvalidOtpAlphabetError) Error() string {
+	return fmt.Sprintf("OTP alphabet %s is not supported ", e.Alphabet)
+}
+
+type NotSentOtpError struct {
+	PhoneNumber string
+}
+
+func (e NotSentOtpError) Error() string {
+	return fmt.Sprintf("No OTP has been sent to %s ", e.PhoneNumber)
+}
+
+type InvalidOtpChannelError struct {
+	Channel string
+}
+
+func (e InvalidOtpChannelError) Error() string {
+	return fmt.Sprintf("OTP channel %s is not supported ", e.Channel)
+}
+
+type NotExistsEmailError struct {
+	Email string
+}
+
+func (e NotExistsEmailError) Error() string {
+	return fmt.Sprintf("Email %s is not found ", e.Email)
+}
+
+type InvalidEmailError struct {
+	Email string
+}
+
+func (e InvalidEmailError) Error() string {
+	return fmt.Sprintf("Email %s is invalid ", e.Email)
+}
+
+type LockedEmailError struct {
+	Email       string
+	LockedUntil time.Time
+}
+
+func (e LockedEmailError) Error() string {
+	return fmt.Sprintf("Email %s is locked until %s ", e.Email, e.LockedUntil.Format(time.RFC3339))
+}
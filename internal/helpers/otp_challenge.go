@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"github.com/dgrijalva/jwt-go"
+	"math/bits"
+	"strconv"
+	"tbox_backend/config"
+	e "tbox_backend/internal/errors"
+	"time"
+)
+
+const otpChallengeIDSize = 16
+
+// otpChallengeAudience keeps challenges apart from tokens signed with the same secret key.
+const otpChallengeAudience = "otp_challenge"
+
+// maxProofOfWorkDifficulty keeps a misconfigured difficulty solvable.
+const maxProofOfWorkDifficulty = 32
+
+// OtpChallengeClaims bind a challenge to the phone number it was issued for, the phone number is the subject.
+// Difficulty tells clients how to solve the challenge, solutions are checked against the configured difficulty.
+type OtpChallengeClaims struct {
+	jwt.StandardClaims
+	Difficulty int `json:"difficulty"`
+}
+
+type IOtpChallengeHelper interface {
+	NewChallenge(phoneNumber string, now time.Time) (string, OtpChallengeClaims, error)
+	ParseChallenge(challenge string, phoneNumber string) (OtpChallengeClaims, error)
+	Difficulty() int
+}
+
+// OtpChallengeHelper signs the challenges OTP requests solve when their risk is elevated.
+type OtpChallengeHelper struct {
+	cfg       config.OtpChallenge
+	secretKey []byte
+}
+
+func NewOtpChallengeHelper(cfg config.OtpChallenge, secretKey string) *OtpChallengeHelper {
+	if cfg.Difficulty > maxProofOfWorkDifficulty {
+		cfg.Difficulty = maxProofOfWorkDifficulty
+	}
+
+	return &OtpChallengeHelper{cfg: cfg, secretKey: []byte(secretKey)}
+}
+
+// NewChallenge signs a challenge for the phone number, the challenge is the signed token.
+func (h OtpChallengeHelper) NewChallenge(phoneNumber string, now time.Time) (string, OtpChallengeClaims, error) {
+	challengeID, err := randomString(otpChallengeIDSize)
+	if err != nil {
+		return "", OtpChallengeClaims{}, err
+	}
+
+	claims := OtpChallengeClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        challengeID,
+			Audience:  otpChallengeAudience,
+			Subject:   phoneNumber,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Duration(h.cfg.ExpiredTime) * time.Second).Unix(),
+		},
+		Difficulty: h.cfg.Difficulty,
+	}
+
+	challenge, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.secretKey)
+	if err != nil {
+		return "", OtpChallengeClaims{}, err
+	}
+
+	return challenge, claims, nil
+}
+
+// ParseChallenge returns the claims of a challenge which was signed by the helper for the phone number and
+// has not expired.
+func (h OtpChallengeHelper) ParseChallenge(challenge string, phoneNumber string) (OtpChallengeClaims, error) {
+	claims := OtpChallengeClaims{}
+	_, err := jwt.ParseWithClaims(challenge, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, e.InvalidOtpChallengeError{}
+		}
+
+		return h.secretKey, nil
+	})
+
+	if err != nil {
+		return OtpChallengeClaims{}, e.InvalidOtpChallengeError{}
+	}
+
+	if !claims.VerifyAudience(otpChallengeAudience, true) ||
+		claims.ExpiresAt == 0 ||
+		claims.Id == "" ||
+		claims.Subject != phoneNumber {
+		return OtpChallengeClaims{}, e.InvalidOtpChallengeError{}
+	}
+
+	return claims, nil
+}
+
+// Difficulty is the number of leading zero bits the proof of work of a challenge must have.
+func (h OtpChallengeHelper) Difficulty() int {
+	return h.cfg.Difficulty
+}
+
+// VerifyProofOfWork reports whether the SHA-256 hash of "challenge:nonce" starts with difficulty zero bits,
+// the hashcash proof of work of a challenge.
+func VerifyProofOfWork(challenge string, nonce string, difficulty int) bool {
+	if nonce == "" {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeroBits := 0
+	for _, b := range hash {
+		zeroBits += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return zeroBits >= difficulty
+}
+
+// SolveProofOfWork finds a nonce of the challenge the way clients do, trying decimal nonces from 0.
+func SolveProofOfWork(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		if VerifyProofOfWork(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package helpers_test
+
+import (
+	"tbox_backend/config"
+	e "tbox_backend/internal/errors"
+	"tbox_backend/internal/helpers"
+	"testing"
+	"time"
+)
+
+func TestOtpChallengeHelper_ParseChallenge(t *testing.T) {
+	otpChallengeHelper := helpers.NewOtpChallengeHelper(config.OtpChallenge{ExpiredTime: 300, Difficulty: 8}, "secret")
+	challenge, claims, err := otpChallengeHelper.NewChallenge("+84967288123", time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedClaims, err := otpChallengeHelper.ParseChallenge(challenge, "+84967288123")
+	if err != nil || parsedClaims.Id != claims.Id || parsedClaims.Difficulty != 8 {
+		t.Fatalf("expected the claims of the challenge, got %+v %v", parsedClaims, err)
+	}
+
+	// A challenge only holds for the number it was issued for.
+	if _, err := otpChallengeHelper.ParseChallenge(challenge, "+84967288124"); err == nil {
+		t.Fatalf("expected InvalidOtpChallengeError")
+	}
+
+	otherHelper := helpers.NewOtpChallengeHelper(config.OtpChallenge{ExpiredTime: 300, Difficulty: 8}, "other")
+	if _, err := otherHelper.ParseChallenge(challenge, "+84967288123"); err == nil {
+		t.Fatalf("expected InvalidOtpChallengeError")
+	}
+}
+
+func TestOtpChallengeHelper_ParseChallenge_Expired(t *testing.T) {
+	otpChallengeHelper := helpers.NewOtpChallengeHelper(config.OtpChallenge{ExpiredTime: 300}, "secret")
+	challenge, _, _ := otpChallengeHelper.NewChallenge("+84967288123", time.Now().UTC().Add(-time.Hour))
+	_, err := otpChallengeHelper.ParseChallenge(challenge, "+84967288123")
+	if _, ok := err.(e.InvalidOtpChallengeError); !ok {
+		t.Fatalf("expected InvalidOtpChallengeError, got %v", err)
+	}
+}
+
+func TestOtpChallengeHelper_ParseChallenge_AccessToken(t *testing.T) {
+	// Challenges signed with the secret key of tokens cannot be mistaken for access tokens, nor the other way round.
+	userHelper := helpers.NewUserHelper(config.Token{Issuer: "tbox_backend", Audience: "tbox_app", ExpiredTime: 900}, helpers.NewHmacTokenKeySet("secret"))
+	accessToken, err := userHelper.GenerateToken(1, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otpChallengeHelper := helpers.NewOtpChallengeHelper(config.OtpChallenge{ExpiredTime: 300}, "secret")
+	if _, err := otpChallengeHelper.ParseChallenge(accessToken, "1"); err == nil {
+		t.Fatalf("expected InvalidOtpChallengeError")
+	}
+
+	challenge, _, _ := otpChallengeHelper.NewChallenge("1", time.Now().UTC())
+	if _, err := userHelper.ParseToken(challenge); err == nil {
+		t.Fatalf("expected InvalidTokenError")
+	}
+}
+
+func TestVerifyProofOfWork(t *testing.T) {
+	nonce := helpers.SolveProofOfWork("challenge", 12)
+	if !helpers.VerifyProofOfWork("challenge", nonce, 12) {
+		t.Fatalf("expected the nonce to solve the challenge")
+	}
+
+	if helpers.VerifyProofOfWork("other challenge", nonce, 12) {
+		t.Fatalf("expected the nonce not to solve another challenge")
+	}
+
+	if helpers.VerifyProofOfWork("challenge", "", 0) {
+		t.Fatalf("expected an empty nonce to be refused")
+	}
+}
@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"tbox_backend/internal/constants"
+)
+
+// fallbackOtpTemplate is used when neither the requested nor the default locale has a template for the purpose
+// and fallbackTemplates has none either.
+const fallbackOtpTemplate = "Your OTP is: {code}"
+
+// subjectSuffix turns an email purpose into the purpose of its subject.
+const subjectSuffix = "_subject"
+
+var fallbackTemplates = map[string]string{
+	constants.OtpEmailLoginPurpose + subjectSuffix: "Your login code",
+	constants.MagicLinkPurpose + subjectSuffix:     "Your login link",
+	constants.MagicLinkPurpose:                     "Open this link to log in: {link}",
+}
+
+// otpSpeechRepeats is how many times the speech of a voice OTP is read out.
+const otpSpeechRepeats = 2
+
+type localePreference struct {
+	locale  string
+	quality float64
+}
+
+// OtpMessage renders the OTP SMS for the purpose in the best matching locale. locale may be a single tag like
+// "vi-VN" or a whole Accept-Language header.
+func (h UserOtpHelper) OtpMessage(locale string, purpose string, otp string) string {
+	content := h.render(locale, purpose, h.cfg.ExpiredTime, "{code}", otp)
+
+	// Origin-bound one-time codes are read from the "@domain #code" line, the SMS Retriever API only hands messages
+	// ending with the app hash to the app, so the hash comes last when both are configured.
+	if h.cfg.Message.OriginDomain != "" {
+		content += "\n\n@" + h.cfg.Message.OriginDomain + " #" + otp
+	}
+
+	if h.cfg.Message.AndroidAppHash != "" {
+		content += "\n" + h.cfg.Message.AndroidAppHash
+	}
+
+	return content
+}
+
+// OtpSpeech renders the text read out by a voice OTP call. The digits are separated by commas, so text-to-speech
+// pauses between them, and the whole text is repeated.
+func (h UserOtpHelper) OtpSpeech(locale string, purpose string, otp string) string {
+	speech := h.render(locale, purpose, h.cfg.ExpiredTime, "{code}", strings.Join(strings.Split(otp, ""), ", "))
+	speeches := make([]string, otpSpeechRepeats)
+	for i := range speeches {
+		speeches[i] = speech
+	}
+
+	return strings.Join(speeches, " ")
+}
+
+// OtpEmail renders the subject and the body of an email carrying the OTP.
+func (h UserOtpHelper) OtpEmail(locale string, purpose string, otp string) (string, string) {
+	subject := h.render(locale, purpose+subjectSuffix, h.cfg.ExpiredTime, "{code}", otp)
+	body := h.render(locale, purpose, h.cfg.ExpiredTime, "{code}", otp)
+	return subject, body
+}
+
+// MagicLinkEmail renders the subject and the body of an email carrying a magic link which expires
+// after expiredTime seconds.
+func (h UserOtpHelper) MagicLinkEmail(locale string, link string, expiredTime int) (string, string) {
+	subject := h.render(locale, constants.MagicLinkPurpose+subjectSuffix, expiredTime, "{link}", link)
+	body := h.render(locale, constants.MagicLinkPurpose, expiredTime, "{link}", link)
+	return subject, body
+}
+
+// render fills the template of the purpose with the app name, the expiry and the given placeholder value pairs.
+func (h UserOtpHelper) render(locale string, purpose string, expiredTime int, placeholders ...string) string {
+	template, exists := h.template(h.matchLocale(locale), purpose)
+	if !exists {
+		template, exists = h.template(h.cfg.Message.DefaultLocale, purpose)
+	}
+
+	if !exists {
+		template, exists = fallbackTemplates[purpose]
+	}
+
+	if !exists {
+		template = fallbackOtpTemplate
+	}
+
+	expiryMinutes := (expiredTime + 59) / 60
+	replacements := append([]string{
+		"{expiry_minutes}", strconv.Itoa(expiryMinutes),
+		"{app_name}", h.cfg.Message.AppName,
+	}, placeholders...)
+
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+func (h UserOtpHelper) template(locale string, purpose string) (string, bool) {
+	template, exists := h.cfg.Message.Templates[strings.ToLower(locale)][purpose]
+	return template, exists && template != ""
+}
+
+// matchLocale returns the configured locale preferred by an Accept-Language style list, trying the exact tag
+// before its base language. It returns "" when nothing matches.
+func (h UserOtpHelper) matchLocale(locale string) string {
+	preferences := make([]localePreference, 0)
+	for _, tag := range strings.Split(locale, ",") {
+		parts := strings.Split(tag, ";")
+		preference := localePreference{
+			locale:  strings.ToLower(strings.Replace(strings.TrimSpace(parts[0]), "_", "-", -1)),
+			quality: 1,
+		}
+
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				quality, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					preference.quality = quality
+				}
+			}
+		}
+
+		if preference.locale != "" && preference.quality > 0 {
+			preferences = append(preferences, preference)
+		}
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+
+	for _, preference := range preferences {
+		if _, exists := h.cfg.Message.Templates[preference.locale]; exists {
+			return preference.locale
+		}
+
+		base := strings.SplitN(preference.locale, "-", 2)[0]
+		if _, exists := h.cfg.Message.Templates[base]; exists {
+			return base
+		}
+	}
+
+	return ""
+}
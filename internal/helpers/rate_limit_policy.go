@@ -0,0 +1,199 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"tbox_backend/config"
+)
+
+const defaultPhonePrefixLength = 7
+
+// globalRateLimitKey is the key of the single bucket of the global dimension.
+const globalRateLimitKey = "global"
+
+// RateLimitKeys are the values of a request the dimensions of a policy are keyed by. Empty values skip
+// the limits of their dimension.
+type RateLimitKeys struct {
+	IP          string
+	DeviceID    string
+	PhoneNumber string
+}
+
+// RateLimitPolicies applies the limits declared for each route to its requests.
+type RateLimitPolicies struct {
+	cfg            config.RateLimitPolicies
+	trustedProxies []*net.IPNet
+	routes         map[string][]routeLimiter
+}
+
+type routeLimiter struct {
+	dimension string
+	limiter   IRateLimiter
+}
+
+// NewRateLimitPolicies creates a limiter per limit of each route with newLimiter, which is given a name unique
+// to the limit.
+func NewRateLimitPolicies(cfg config.RateLimitPolicies, newLimiter func(name string, r float64, b int) IRateLimiter) (*RateLimitPolicies, error) {
+	if cfg.PhonePrefixLength <= 0 {
+		cfg.PhonePrefixLength = defaultPhonePrefixLength
+	}
+
+	p := &RateLimitPolicies{cfg: cfg, routes: make(map[string][]routeLimiter)}
+	for _, cidr := range cfg.TrustedProxies {
+		_, trustedProxy, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("Trusted proxy %s is invalid: %v ", cidr, err)
+		}
+
+		p.trustedProxies = append(p.trustedProxies, trustedProxy)
+	}
+
+	for _, route := range cfg.Routes {
+		for i, limit := range route.Limits {
+			switch limit.Dimension {
+			case config.IPRateLimitDimension, config.DeviceRateLimitDimension, config.PhonePrefixRateLimitDimension, config.GlobalRateLimitDimension:
+			default:
+				return nil, fmt.Errorf("Rate limit dimension %s of %s is unknown ", limit.Dimension, route.Path)
+			}
+
+			name := fmt.Sprintf("%s:%d:%s", route.Path, i, limit.Dimension)
+			p.routes[route.Path] = append(p.routes[route.Path], routeLimiter{
+				dimension: limit.Dimension,
+				limiter:   newLimiter(name, limit.Limit, limit.Burst),
+			})
+		}
+	}
+
+	return p, nil
+}
+
+// HasLimits reports whether limits are declared for the route.
+func (p *RateLimitPolicies) HasLimits(path string) bool {
+	return len(p.routes[path]) > 0
+}
+
+// Allow takes a token from every limit of the route. A refused request returns the state of the first limit which
+// has no token left and its dimension, an allowed one the state of the limit with the fewest tokens left.
+// The tokens a refused request took from the limits before the one refusing it are given back, so requests
+// refused by one limit, e.g. of a phone prefix, do not spend the limits of their IP or device.
+func (p *RateLimitPolicies) Allow(path string, keys RateLimitKeys) (RateLimit, string, error) {
+	result := RateLimit{Allowed: true}
+	var taken []routeLimiter
+	var takenKeys []string
+	for _, route := range p.routes[path] {
+		key := p.key(route.dimension, keys)
+		if key == "" {
+			continue
+		}
+
+		rateLimit, err := route.limiter.Allow(key)
+		if err == nil && rateLimit.Allowed {
+			taken = append(taken, route)
+			takenKeys = append(takenKeys, key)
+		} else {
+			for i, takenRoute := range taken {
+				if refundErr := takenRoute.limiter.Refund(takenKeys[i]); refundErr != nil && err == nil {
+					err = refundErr
+				}
+			}
+
+			if err != nil {
+				return RateLimit{}, route.dimension, err
+			}
+
+			return rateLimit, route.dimension, nil
+		}
+
+		if result.Limit == 0 || rateLimit.Remaining < result.Remaining {
+			result = rateLimit
+		}
+	}
+
+	return result, "", nil
+}
+
+func (p *RateLimitPolicies) key(dimension string, keys RateLimitKeys) string {
+	switch dimension {
+	case config.IPRateLimitDimension:
+		return keys.IP
+	case config.DeviceRateLimitDimension:
+		if keys.DeviceID == "" {
+			return ""
+		}
+
+		// The device header is sent by the client, it is hashed to keep its keys the size of the limiter_key column.
+		hash := sha256.Sum256([]byte(keys.DeviceID))
+		return hex.EncodeToString(hash[:])
+	case config.PhonePrefixRateLimitDimension:
+		digits := strings.TrimPrefix(keys.PhoneNumber, "+")
+		if len(digits) > p.cfg.PhonePrefixLength {
+			digits = digits[:p.cfg.PhonePrefixLength]
+		}
+
+		return digits
+	default:
+		return globalRateLimitKey
+	}
+}
+
+// DeviceID returns the value of the device header of the request.
+func (p *RateLimitPolicies) DeviceID(req *http.Request) string {
+	if p.cfg.DeviceHeader == "" {
+		return ""
+	}
+
+	return strings.TrimSpace(req.Header.Get(p.cfg.DeviceHeader))
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only trusted when the request comes from
+// a trusted proxy, it is read from the right and the first address which is not a trusted proxy is the client.
+func (p *RateLimitPolicies) ClientIP(req *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		remoteIP = strings.TrimSpace(req.RemoteAddr)
+	}
+
+	if !p.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	var forwardedIPs []string
+	for _, header := range req.Header["X-Forwarded-For"] {
+		for _, forwardedIP := range strings.Split(header, ",") {
+			forwardedIPs = append(forwardedIPs, strings.TrimSpace(forwardedIP))
+		}
+	}
+
+	clientIP := remoteIP
+	for i := len(forwardedIPs) - 1; i >= 0; i-- {
+		if net.ParseIP(forwardedIPs[i]) == nil {
+			break
+		}
+
+		clientIP = forwardedIPs[i]
+		if !p.isTrustedProxy(clientIP) {
+			break
+		}
+	}
+
+	return clientIP
+}
+
+func (p *RateLimitPolicies) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, trustedProxy := range p.trustedProxies {
+		if trustedProxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
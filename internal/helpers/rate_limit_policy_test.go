@@ -0,0 +1,187 @@
+package helpers_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"tbox_backend/config"
+	"tbox_backend/internal/helpers"
+	"tbox_backend/internal/stores"
+	"testing"
+)
+
+func TestRateLimitPolicies_ClientIP(t *testing.T) {
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{TrustedProxies: []string{"10.0.0.0/8"}}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewPhoneNumberRateLimiters(r, b)
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		expectedIP   string
+	}{
+		{"203.0.113.7:4000", "", "203.0.113.7"},
+		// Only trusted proxies may set X-Forwarded-For.
+		{"203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"10.0.0.2:4000", "198.51.100.1", "198.51.100.1"},
+		// Addresses added by the client in front of the real one are ignored.
+		{"10.0.0.2:4000", "1.2.3.4, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"10.0.0.2:4000", "10.0.0.4, 10.0.0.3", "10.0.0.4"},
+		{"10.0.0.2:4000", "", "10.0.0.2"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/generate_otp", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+
+		if clientIP := rateLimitPolicies.ClientIP(req); clientIP != test.expectedIP {
+			t.Fatalf("expected %s for %s %s, got %s", test.expectedIP, test.remoteAddr, test.forwardedFor, clientIP)
+		}
+	}
+}
+
+func TestRateLimitPolicies_Allow(t *testing.T) {
+	rateLimitStore := stores.NewMemoryRateLimitStore()
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		PhonePrefixLength: 5,
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/generate_otp", Limits: []config.RateLimitPolicy{
+				{Dimension: config.IPRateLimitDimension, Limit: 0, Burst: 2},
+				{Dimension: config.DeviceRateLimitDimension, Limit: 0, Burst: 1},
+				{Dimension: config.PhonePrefixRateLimitDimension, Limit: 0, Burst: 3},
+				{Dimension: config.GlobalRateLimitDimension, Limit: 0, Burst: 4},
+			}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewStoreRateLimiter(rateLimitStore, name, r, b)
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	expected := []struct {
+		keys      helpers.RateLimitKeys
+		allowed   bool
+		dimension string
+	}{
+		{helpers.RateLimitKeys{IP: "1.1.1.1", PhoneNumber: "+84967477177"}, true, ""},
+		{helpers.RateLimitKeys{IP: "1.1.1.1", PhoneNumber: "+84967477178"}, true, ""},
+		{helpers.RateLimitKeys{IP: "1.1.1.1", PhoneNumber: "+84967477179"}, false, config.IPRateLimitDimension},
+		{helpers.RateLimitKeys{IP: "2.2.2.2", DeviceID: "device", PhoneNumber: "+84967000000"}, true, ""},
+		{helpers.RateLimitKeys{IP: "3.3.3.3", DeviceID: "device", PhoneNumber: "+84967000001"}, false, config.DeviceRateLimitDimension},
+		{helpers.RateLimitKeys{IP: "4.4.4.4", PhoneNumber: "+84967000002"}, false, config.PhonePrefixRateLimitDimension},
+		{helpers.RateLimitKeys{IP: "5.5.5.5", PhoneNumber: "+84988123123"}, true, ""},
+		{helpers.RateLimitKeys{IP: "6.6.6.6", PhoneNumber: "+84912123123"}, false, config.GlobalRateLimitDimension},
+	}
+
+	for i, test := range expected {
+		rateLimit, dimension, err := rateLimitPolicies.Allow("/api/generate_otp", test.keys)
+		if err != nil || rateLimit.Allowed != test.allowed || dimension != test.dimension {
+			t.Fatalf("expected %v %s at request %d, got %v %s %v", test.allowed, test.dimension, i, rateLimit.Allowed, dimension, err)
+		}
+	}
+
+	if rateLimitPolicies.HasLimits("/api/login") {
+		t.Fatalf("expected false")
+	}
+}
+
+func TestRateLimitPolicies_Allow_Refund(t *testing.T) {
+	rateLimitStore := stores.NewMemoryRateLimitStore()
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		PhonePrefixLength: 5,
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/generate_otp", Limits: []config.RateLimitPolicy{
+				{Dimension: config.IPRateLimitDimension, Limit: 0, Burst: 2},
+				{Dimension: config.PhonePrefixRateLimitDimension, Limit: 0, Burst: 1},
+			}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewStoreRateLimiter(rateLimitStore, name, r, b)
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// Requests refused by their phone prefix do not spend the limit of their IP.
+	expected := []struct {
+		phoneNumber string
+		allowed     bool
+		dimension   string
+	}{
+		{"+84967477177", true, ""},
+		{"+84967477178", false, config.PhonePrefixRateLimitDimension},
+		{"+84967477179", false, config.PhonePrefixRateLimitDimension},
+		{"+84988123123", true, ""},
+		{"+84912123123", false, config.IPRateLimitDimension},
+	}
+
+	for i, test := range expected {
+		rateLimit, dimension, err := rateLimitPolicies.Allow("/api/generate_otp", helpers.RateLimitKeys{IP: "1.1.1.1", PhoneNumber: test.phoneNumber})
+		if err != nil || rateLimit.Allowed != test.allowed || dimension != test.dimension {
+			t.Fatalf("expected %v %s at request %d, got %v %s %v", test.allowed, test.dimension, i, rateLimit.Allowed, dimension, err)
+		}
+	}
+}
+
+type keyRecorder struct {
+	keys []string
+}
+
+func (r *keyRecorder) Allow(key string) (helpers.RateLimit, error) {
+	r.keys = append(r.keys, key)
+	return helpers.RateLimit{Allowed: true, Limit: 1, Remaining: 1}, nil
+}
+
+func (r *keyRecorder) Refund(key string) error {
+	return nil
+}
+
+func TestRateLimitPolicies_Allow_DeviceKey(t *testing.T) {
+	recorder := &keyRecorder{}
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/generate_otp", Limits: []config.RateLimitPolicy{{Dimension: config.DeviceRateLimitDimension, Limit: 0, Burst: 1}}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return recorder
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for _, deviceID := range []string{"device", strings.Repeat("d", 1000), strings.Repeat("d", 1000) + "e", ""} {
+		if _, _, err := rateLimitPolicies.Allow("/api/generate_otp", helpers.RateLimitKeys{DeviceID: deviceID}); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+
+	// Requests without a device are not limited by device, long devices are kept apart within the size of a key.
+	if len(recorder.keys) != 3 || recorder.keys[1] == recorder.keys[2] {
+		t.Fatalf("expected 3 different keys, got %v", recorder.keys)
+	}
+
+	for _, key := range recorder.keys {
+		if len(key) != 64 {
+			t.Fatalf("expected a key of 64 characters, got %s", key)
+		}
+	}
+}
+
+func TestRateLimitPolicies_UnknownDimension(t *testing.T) {
+	_, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/login", Limits: []config.RateLimitPolicy{{Dimension: "country", Limit: 1, Burst: 1}}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewPhoneNumberRateLimiters(r, b)
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
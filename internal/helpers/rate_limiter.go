@@ -1,46 +1,310 @@
 package helpers
 
 import (
-	"golang.org/x/time/rate"
+	"container/list"
+	"context"
+	"math"
 	"sync"
+	"sync/atomic"
+	"tbox_backend/internal/stores"
+	"time"
 )
 
+const (
+	limiterShards      = 16
+	defaultMaxLimiters = 100000
+	// maxLimiterIdleTime bounds how long an idle limiter is kept when its bucket never refills, e.g. with a rate of 0.
+	maxLimiterIdleTime = 24 * time.Hour
+)
+
+// IRateLimiter limits the rate of requests per key with a token bucket. Refund gives back the token of an allowed
+// request which was refused by another limit.
+type IRateLimiter interface {
+	Allow(key string) (RateLimit, error)
+	Refund(key string) error
+}
+
+// RateLimit is the state of the bucket of a key after a request. Limit is the size of the bucket, Remaining the
+// whole tokens left, Reset the time until the bucket is full again and RetryAfter, when the request was refused,
+// the time until the next token.
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// NewRateLimit describes a bucket of b tokens refilling at r tokens per second, with tokens left.
+func NewRateLimit(allowed bool, tokens float64, r float64, b int) RateLimit {
+	rateLimit := RateLimit{
+		Allowed:   allowed,
+		Limit:     b,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     refillDuration(float64(b)-tokens, r),
+	}
+
+	if !allowed {
+		rateLimit.RetryAfter = refillDuration(1-tokens, r)
+	}
+
+	return rateLimit
+}
+
+// refillDuration is the time a bucket refilling at r tokens per second takes to gain tokens, at most
+// maxLimiterIdleTime as the bucket is dropped by then.
+func refillDuration(tokens float64, r float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	} else if r <= 0 || tokens/r > maxLimiterIdleTime.Seconds() {
+		return maxLimiterIdleTime
+	}
+
+	return time.Duration(tokens / r * float64(time.Second))
+}
+
+// TokenBucket starts full with b tokens and refills at r tokens per second like rate.Limiter, and reports
+// its state after each request.
+type TokenBucket struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	r         float64
+	b         int
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newTokenBucket(r float64, b int, now time.Time, clock func() time.Time) *TokenBucket {
+	return &TokenBucket{now: clock, r: r, b: b, tokens: float64(b), updatedAt: now}
+}
+
+func (t *TokenBucket) Allow() bool {
+	return t.Take().Allowed
+}
+
+// Take removes a token from the bucket, the bucket is left as it is when it has no token left.
+func (t *TokenBucket) Take() RateLimit {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The clock is read before locking, a concurrent request may have updated the bucket after now.
+	if now.After(t.updatedAt) {
+		t.tokens = math.Min(float64(t.b), t.tokens+now.Sub(t.updatedAt).Seconds()*t.r)
+		t.updatedAt = now
+	}
+
+	allowed := t.tokens >= 1
+	if allowed {
+		t.tokens--
+	}
+
+	return NewRateLimit(allowed, t.tokens, t.r, t.b)
+}
+
+// Refund gives back a token taken from the bucket, the bucket holds b tokens at most.
+func (t *TokenBucket) Refund() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens = math.Min(float64(t.b), t.tokens+1)
+}
+
+// PhoneNumberRateLimiters keeps a TokenBucket per key in process, so every instance of the API has limits
+// of its own and they are reset by a restart. At most maxSize limiters are kept, the least recently used one
+// is dropped to make room for a new key. Keys are spread over shards, each with a lock of its own.
 type PhoneNumberRateLimiters struct {
-	phoneNumbers map[string]*rate.Limiter
-	mu           *sync.RWMutex
-	r            rate.Limit
-	b            int
+	// The counters are accessed atomically, they come first to be 64-bit aligned.
+	evictions   int64
+	expirations int64
+	shards      [limiterShards]*limiterShard
+	now         func() time.Time
+	r           float64
+	b           int
+	shardSize   int
+	idleTime    time.Duration
+}
+
+// PhoneNumberRateLimitersStats counts the limiters kept and the ones dropped since the process started. Evictions
+// are limiters dropped to make room for new keys, Expirations idle limiters whose buckets were full again.
+type PhoneNumberRateLimitersStats struct {
+	Size        int   `json:"size"`
+	MaxSize     int   `json:"max_size"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+}
+
+type limiterShard struct {
+	mu       sync.Mutex
+	limiters map[string]*list.Element
+	lru      *list.List
+}
+
+type limiterEntry struct {
+	key      string
+	limiter  *TokenBucket
+	lastUsed time.Time
 }
 
 func NewPhoneNumberRateLimiters(r float64, b int) *PhoneNumberRateLimiters {
-	i := &PhoneNumberRateLimiters{
-		phoneNumbers: make(map[string]*rate.Limiter),
-		mu:           &sync.RWMutex{},
-		r:            rate.Limit(r),
-		b:            b,
+	return NewBoundedPhoneNumberRateLimiters(r, b, defaultMaxLimiters)
+}
+
+func NewBoundedPhoneNumberRateLimiters(r float64, b int, maxSize int) *PhoneNumberRateLimiters {
+	return NewPhoneNumberRateLimitersWithClock(r, b, maxSize, time.Now)
+}
+
+func NewPhoneNumberRateLimitersWithClock(r float64, b int, maxSize int, now func() time.Time) *PhoneNumberRateLimiters {
+	if maxSize <= 0 {
+		maxSize = defaultMaxLimiters
+	}
+
+	// A limiter left alone for b/r seconds has a full bucket, dropping it changes nothing.
+	l := &PhoneNumberRateLimiters{
+		now:       now,
+		r:         r,
+		b:         b,
+		shardSize: (maxSize + limiterShards - 1) / limiterShards,
+		idleTime:  refillDuration(float64(b), r),
+	}
+
+	for i := range l.shards {
+		l.shards[i] = &limiterShard{limiters: make(map[string]*list.Element), lru: list.New()}
+	}
+
+	return l
+}
+
+func (l *PhoneNumberRateLimiters) GetLimiter(phoneNumber string) *TokenBucket {
+	// Read the clock before locking, it is the slowest step.
+	now := l.now()
+	shard := l.shard(phoneNumber)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if element, exists := shard.limiters[phoneNumber]; exists {
+		entry := element.Value.(*limiterEntry)
+		entry.lastUsed = now
+		shard.lru.MoveToFront(element)
+		return entry.limiter
+	}
+
+	if shard.lru.Len() >= l.shardSize {
+		oldest := shard.lru.Back()
+		shard.lru.Remove(oldest)
+		delete(shard.limiters, oldest.Value.(*limiterEntry).key)
+		atomic.AddInt64(&l.evictions, 1)
 	}
 
-	return i
+	entry := &limiterEntry{key: phoneNumber, limiter: newTokenBucket(l.r, l.b, now, l.now), lastUsed: now}
+	shard.limiters[phoneNumber] = shard.lru.PushFront(entry)
+	return entry.limiter
+}
+
+func (l *PhoneNumberRateLimiters) Allow(key string) (RateLimit, error) {
+	return l.GetLimiter(key).Take(), nil
 }
 
-func (l *PhoneNumberRateLimiters) addPhoneNumber(phoneNumber string) *rate.Limiter {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+func (l *PhoneNumberRateLimiters) Refund(key string) error {
+	l.GetLimiter(key).Refund()
+	return nil
+}
+
+// Evict drops the limiters whose buckets are full again. They are the least recently used ones, so each shard
+// is only scanned until its first limiter still in use.
+func (l *PhoneNumberRateLimiters) Evict() int {
+	evicted := 0
+	for _, shard := range l.shards {
+		shard.mu.Lock()
+		idleSince := l.now().Add(-l.idleTime)
+		for element := shard.lru.Back(); element != nil; element = shard.lru.Back() {
+			entry := element.Value.(*limiterEntry)
+			if entry.lastUsed.After(idleSince) {
+				break
+			}
 
-	limiter := rate.NewLimiter(l.r, l.b)
-	l.phoneNumbers[phoneNumber] = limiter
+			shard.lru.Remove(element)
+			delete(shard.limiters, entry.key)
+			evicted++
+		}
 
-	return limiter
+		shard.mu.Unlock()
+	}
+
+	atomic.AddInt64(&l.expirations, int64(evicted))
+	return evicted
 }
 
-func (l *PhoneNumberRateLimiters) GetLimiter(phoneNumber string) *rate.Limiter {
-	l.mu.Lock()
-	limiter, exists := l.phoneNumbers[phoneNumber]
-	if !exists {
-		l.mu.Unlock()
-		return l.addPhoneNumber(phoneNumber)
+// Run evicts idle limiters every interval until the context is done.
+func (l *PhoneNumberRateLimiters) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Evict()
+		}
 	}
+}
+
+func (l *PhoneNumberRateLimiters) Stats() PhoneNumberRateLimitersStats {
+	size := 0
+	for _, shard := range l.shards {
+		shard.mu.Lock()
+		size += shard.lru.Len()
+		shard.mu.Unlock()
+	}
+
+	return PhoneNumberRateLimitersStats{
+		Size:        size,
+		MaxSize:     l.shardSize * limiterShards,
+		Evictions:   atomic.LoadInt64(&l.evictions),
+		Expirations: atomic.LoadInt64(&l.expirations),
+	}
+}
+
+// shard hashes the key with FNV-1a, inlined to hash the string without copying it.
+func (l *PhoneNumberRateLimiters) shard(key string) *limiterShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return l.shards[hash%limiterShards]
+}
+
+// StoreRateLimiter keeps its token buckets in a rate limit store shared by every instance of the API. Its buckets
+// behave like TokenBucket: they start full with b tokens and refill at r tokens per second.
+type StoreRateLimiter struct {
+	store stores.IRateLimitStore
+	name  string
+	r     float64
+	b     int
+}
+
+// NewStoreRateLimiter names the limiter, limiters sharing a store must have different names.
+func NewStoreRateLimiter(store stores.IRateLimitStore, name string, r float64, b int) *StoreRateLimiter {
+	return &StoreRateLimiter{
+		store: store,
+		name:  name,
+		r:     r,
+		b:     b,
+	}
+}
+
+func (l *StoreRateLimiter) Allow(key string) (RateLimit, error) {
+	allowed, tokens, err := l.store.Take(l.name+":"+key, l.r, l.b)
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	return NewRateLimit(allowed, tokens, l.r, l.b), nil
+}
 
-	l.mu.Unlock()
-	return limiter
+func (l *StoreRateLimiter) Refund(key string) error {
+	return l.store.Refund(l.name+":"+key, l.b)
 }
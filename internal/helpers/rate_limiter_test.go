@@ -1,8 +1,14 @@
 package helpers_test
 
 import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"sync"
 	"tbox_backend/internal/helpers"
+	"tbox_backend/internal/stores"
 	"testing"
+	"time"
 )
 
 func TestPhoneNumberRateLimiter_Limit(t *testing.T) {
@@ -36,3 +42,209 @@ func TestPhoneNumberRateLimiter_DifferentPhoneNumber(t *testing.T) {
 		t.Fatalf("expected true")
 	}
 }
+
+func TestStoreRateLimiter_Limit(t *testing.T) {
+	now := time.Now()
+	rateLimitStore := stores.NewMemoryRateLimitStoreWithClock(func() time.Time { return now })
+	limiter := helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", 0.5, 2)
+
+	expected := []struct {
+		elapsed time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{time.Second, false},
+		{time.Second, true},
+		{0, false},
+		{time.Hour, true},
+		{0, true},
+		{0, false},
+	}
+
+	for i, test := range expected {
+		now = now.Add(test.elapsed)
+		rateLimit, err := limiter.Allow("+84967477177")
+		if err != nil || rateLimit.Allowed != test.allowed {
+			t.Fatalf("expected %v at request %d, got %v %v", test.allowed, i, rateLimit.Allowed, err)
+		}
+	}
+}
+
+func TestStoreRateLimiter_SharedStore(t *testing.T) {
+	rateLimitStore := stores.NewMemoryRateLimitStore()
+	replica1 := helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", 1, 1)
+	replica2 := helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", 1, 1)
+	voiceLimiter := helpers.NewStoreRateLimiter(rateLimitStore, "voice", 1, 1)
+
+	if rateLimit, _ := replica1.Allow("+84967467177"); !rateLimit.Allowed {
+		t.Fatalf("expected true")
+	}
+
+	if rateLimit, _ := replica2.Allow("+84967467177"); rateLimit.Allowed {
+		t.Fatalf("expected the limit to be shared")
+	}
+
+	if rateLimit, _ := voiceLimiter.Allow("+84967467177"); !rateLimit.Allowed {
+		t.Fatalf("expected limiters with different names to be apart")
+	}
+}
+
+func TestRateLimiters_State(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	limiters := map[string]helpers.IRateLimiter{
+		"memory": helpers.NewPhoneNumberRateLimitersWithClock(0.5, 2, 100, clock),
+		"store":  helpers.NewStoreRateLimiter(stores.NewMemoryRateLimitStoreWithClock(clock), "phone_number", 0.5, 2),
+	}
+
+	expected := []helpers.RateLimit{
+		{Allowed: true, Limit: 2, Remaining: 1, Reset: 2 * time.Second},
+		{Allowed: true, Limit: 2, Remaining: 0, Reset: 4 * time.Second},
+		{Allowed: false, Limit: 2, Remaining: 0, Reset: 4 * time.Second, RetryAfter: 2 * time.Second},
+	}
+
+	for name, limiter := range limiters {
+		for i, test := range expected {
+			rateLimit, err := limiter.Allow("+84967477177")
+			if err != nil || rateLimit != test {
+				t.Fatalf("expected %+v from %s at request %d, got %+v %v", test, name, i, rateLimit, err)
+			}
+		}
+	}
+}
+
+func TestMemoryRateLimitStore_DeleteExpired(t *testing.T) {
+	now := time.Now()
+	rateLimitStore := stores.NewMemoryRateLimitStoreWithClock(func() time.Time { return now })
+	_, _, _ = rateLimitStore.Take("refilled", 1, 2)
+	_, _, _ = rateLimitStore.Take("refilling", 0.1, 2)
+
+	now = now.Add(3 * time.Second)
+	deleted, err := rateLimitStore.DeleteExpired()
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected the full bucket to be deleted, got %d %v", deleted, err)
+	}
+}
+
+func TestPhoneNumberRateLimiters_MaxSize(t *testing.T) {
+	phoneNumberLimiters := helpers.NewBoundedPhoneNumberRateLimiters(1, 1, 32)
+	for i := 0; i < 1000; i++ {
+		phoneNumberLimiters.GetLimiter(fmt.Sprintf("+8496%07d", i))
+	}
+
+	stats := phoneNumberLimiters.Stats()
+	if stats.Size > stats.MaxSize || stats.MaxSize != 32 || stats.Evictions != int64(1000-stats.Size) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPhoneNumberRateLimiters_KeepsRecentlyUsed(t *testing.T) {
+	phoneNumberLimiters := helpers.NewBoundedPhoneNumberRateLimiters(0.001, 1, 16*4)
+	if !phoneNumberLimiters.GetLimiter("+84967467177").Allow() {
+		t.Fatalf("expected true")
+	}
+
+	for i := 0; i < 1000; i++ {
+		phoneNumberLimiters.GetLimiter("+84967467177")
+		phoneNumberLimiters.GetLimiter(fmt.Sprintf("+8496%07d", i))
+	}
+
+	if phoneNumberLimiters.GetLimiter("+84967467177").Allow() {
+		t.Fatalf("expected the limiter in use to be kept")
+	}
+}
+
+func TestPhoneNumberRateLimiters_Evict(t *testing.T) {
+	now := time.Now()
+	phoneNumberLimiters := helpers.NewPhoneNumberRateLimitersWithClock(0.5, 2, 100, func() time.Time { return now })
+	phoneNumberLimiters.GetLimiter("+84967467177").Allow()
+	now = now.Add(3 * time.Second)
+	phoneNumberLimiters.GetLimiter("+84988123123").Allow()
+
+	// The bucket of the first number is full again after 4 seconds, the second one is still refilling.
+	now = now.Add(time.Second)
+	if evicted := phoneNumberLimiters.Evict(); evicted != 1 {
+		t.Fatalf("expected 1 eviction, got %d", evicted)
+	}
+
+	stats := phoneNumberLimiters.Stats()
+	if stats.Size != 1 || stats.Expirations != 1 || stats.Evictions != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPhoneNumberRateLimiters_Run(t *testing.T) {
+	phoneNumberLimiters := helpers.NewPhoneNumberRateLimiters(1000, 1)
+	phoneNumberLimiters.GetLimiter("+84967467177")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		phoneNumberLimiters.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for phoneNumberLimiters.Stats().Size > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the idle limiter to be evicted")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+// globalMutexRateLimiters is the limiter map as it was before sharding, a single lock guards every key.
+type globalMutexRateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func (l *globalMutexRateLimiters) GetLimiter(phoneNumber string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, exists := l.limiters[phoneNumber]
+	if !exists {
+		limiter = rate.NewLimiter(1, 3)
+		l.limiters[phoneNumber] = limiter
+	}
+
+	return limiter
+}
+
+// The benchmarks compare the sharded limiter map to a single global mutex as goroutines are added,
+// run them with make bench.
+func benchmarkPhoneNumbers() []string {
+	phoneNumbers := make([]string, 10000)
+	for i := range phoneNumbers {
+		phoneNumbers[i] = fmt.Sprintf("+8496%07d", i)
+	}
+
+	return phoneNumbers
+}
+
+func BenchmarkPhoneNumberRateLimiters_GetLimiter(b *testing.B) {
+	phoneNumbers := benchmarkPhoneNumbers()
+	phoneNumberLimiters := helpers.NewPhoneNumberRateLimiters(1, 3)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			phoneNumberLimiters.GetLimiter(phoneNumbers[i%len(phoneNumbers)])
+		}
+	})
+}
+
+func BenchmarkGlobalMutexRateLimiters_GetLimiter(b *testing.B) {
+	phoneNumbers := benchmarkPhoneNumbers()
+	limiters := &globalMutexRateLimiters{limiters: make(map[string]*rate.Limiter)}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			limiters.GetLimiter(phoneNumbers[i%len(phoneNumbers)])
+		}
+	})
+}
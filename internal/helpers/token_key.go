@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"tbox_backend/config"
+	"tbox_backend/internal/dto"
+)
+
+const hmacKeyID = "default"
+
+type TokenKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// SignKey is nil for retired keys, they are only used to verify tokens issued before the rotation.
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+type TokenKeySet struct {
+	activeKey TokenKey
+	keys      map[string]TokenKey
+}
+
+// NewTokenKeySet loads the signing keys from config. Without configured keys it falls back to HS256 with the secret key.
+func NewTokenKeySet(cfg config.Token) (*TokenKeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return NewHmacTokenKeySet(cfg.SecretKey), nil
+	}
+
+	keys := make([]TokenKey, 0, len(cfg.Keys))
+	for _, keyCfg := range cfg.Keys {
+		var privatePem, publicPem []byte
+		var err error
+		if keyCfg.PrivateKeyFile != "" {
+			privatePem, err = ioutil.ReadFile(keyCfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if keyCfg.PublicKeyFile != "" {
+			publicPem, err = ioutil.ReadFile(keyCfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		key, err := ParseTokenKey(keyCfg.ID, keyCfg.Algorithm, privatePem, publicPem)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewTokenKeySetFromKeys(cfg.ActiveKeyID, keys...)
+}
+
+func NewHmacTokenKeySet(secretKey string) *TokenKeySet {
+	key := TokenKey{
+		ID:        hmacKeyID,
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   []byte(secretKey),
+		VerifyKey: []byte(secretKey),
+	}
+
+	return &TokenKeySet{
+		activeKey: key,
+		keys:      map[string]TokenKey{key.ID: key},
+	}
+}
+
+func NewTokenKeySetFromKeys(activeKeyID string, keys ...TokenKey) (*TokenKeySet, error) {
+	keySet := &TokenKeySet{keys: make(map[string]TokenKey)}
+	for _, key := range keys {
+		if _, exists := keySet.keys[key.ID]; exists {
+			return nil, fmt.Errorf("Duplicated token key %s ", key.ID)
+		}
+
+		keySet.keys[key.ID] = key
+	}
+
+	activeKey, exists := keySet.keys[activeKeyID]
+	if !exists {
+		return nil, fmt.Errorf("Active token key %s is not found ", activeKeyID)
+	} else if activeKey.SignKey == nil {
+		return nil, fmt.Errorf("Active token key %s has no private key ", activeKeyID)
+	}
+
+	keySet.activeKey = activeKey
+	return keySet, nil
+}
+
+// ParseTokenKey builds a RS256 or ES256 key from PEM encoded keys. The public key is derived
+// from the private key when only the latter is given.
+func ParseTokenKey(id string, algorithm string, privatePem []byte, publicPem []byte) (TokenKey, error) {
+	key := TokenKey{ID: id}
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+		if privatePem != nil {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePem)
+			if err != nil {
+				return TokenKey{}, err
+			}
+
+			key.SignKey = privateKey
+			key.VerifyKey = &privateKey.PublicKey
+		}
+
+		if publicPem != nil {
+			key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPem)
+		}
+	case jwt.SigningMethodES256.Alg():
+		key.Method = jwt.SigningMethodES256
+		if privatePem != nil {
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM(privatePem)
+			if err != nil {
+				return TokenKey{}, err
+			}
+
+			key.SignKey = privateKey
+			key.VerifyKey = &privateKey.PublicKey
+		}
+
+		if publicPem != nil {
+			key.VerifyKey, err = jwt.ParseECPublicKeyFromPEM(publicPem)
+		}
+	default:
+		return TokenKey{}, fmt.Errorf("Token key %s has unsupported algorithm %s ", id, algorithm)
+	}
+
+	if err != nil {
+		return TokenKey{}, err
+	} else if key.VerifyKey == nil {
+		return TokenKey{}, fmt.Errorf("Token key %s has no key ", id)
+	}
+
+	return key, nil
+}
+
+func (s *TokenKeySet) ActiveKey() TokenKey {
+	return s.activeKey
+}
+
+func (s *TokenKeySet) Key(id string) (TokenKey, bool) {
+	key, exists := s.keys[id]
+	return key, exists
+}
+
+// Jwks returns the public keys in JSON Web Key Set format. Symmetric keys are never published.
+func (s *TokenKeySet) Jwks() dto.Jwks {
+	jwks := dto.Jwks{Keys: []dto.Jwk{}}
+	for _, key := range s.keys {
+		switch verifyKey := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, dto.Jwk{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(verifyKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(verifyKey.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (verifyKey.Curve.Params().BitSize + 7) / 8
+			jwks.Keys = append(jwks.Keys, dto.Jwk{
+				Kty: "EC",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: verifyKey.Curve.Params().Name,
+				X:   base64.RawURLEncoding.EncodeToString(padBytes(verifyKey.X.Bytes(), size)),
+				Y:   base64.RawURLEncoding.EncodeToString(padBytes(verifyKey.Y.Bytes(), size)),
+			})
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func padBytes(buf []byte, size int) []byte {
+	if len(buf) >= size {
+		return buf
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(buf):], buf)
+	return padded
+}
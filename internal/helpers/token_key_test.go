@@ -0,0 +1,219 @@
+package helpers_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"tbox_backend/config"
+	e "tbox_backend/internal/errors"
+	"tbox_backend/internal/helpers"
+	"testing"
+)
+
+func writeRsaKey(t *testing.T, dir string, name string) (string, string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicDer, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateFile := filepath.Join(dir, name+".pem")
+	publicFile := filepath.Join(dir, name+".pub.pem")
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+	if err := ioutil.WriteFile(privateFile, privatePem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(publicFile, publicPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return privateFile, publicFile
+}
+
+func writeEcKey(t *testing.T, dir string, name string) string {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDer, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateFile := filepath.Join(dir, name+".pem")
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateDer})
+	if err := ioutil.WriteFile(privateFile, privatePem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return privateFile
+}
+
+func TestTokenKeySet_Rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	oldPrivateFile, oldPublicFile := writeRsaKey(t, dir, "old")
+	newPrivateFile := writeEcKey(t, dir, "new")
+	cfg := config.Token{
+		Issuer:      "tbox_backend",
+		Audience:    "tbox_app",
+		ExpiredTime: 900,
+		ActiveKeyID: "old",
+		Keys: []config.TokenKey{
+			{ID: "old", Algorithm: "RS256", PrivateKeyFile: oldPrivateFile},
+		},
+	}
+
+	oldKeySet, err := helpers.NewTokenKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldToken, err := helpers.NewUserHelper(cfg, oldKeySet).GenerateToken(1, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old key is retired: only its public key is kept.
+	cfg.ActiveKeyID = "new"
+	cfg.Keys = []config.TokenKey{
+		{ID: "old", Algorithm: "RS256", PublicKeyFile: oldPublicFile},
+		{ID: "new", Algorithm: "ES256", PrivateKeyFile: newPrivateFile},
+	}
+
+	keySet, err := helpers.NewTokenKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userHelper := helpers.NewUserHelper(cfg, keySet)
+	newToken, err := userHelper.GenerateToken(2, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenInfo, err := userHelper.ParseToken(oldToken)
+	if err != nil || tokenInfo.UserID != 1 {
+		t.Fatalf("expected retired key to verify old token")
+	}
+
+	tokenInfo, err = userHelper.ParseToken(newToken)
+	if err != nil || tokenInfo.UserID != 2 {
+		t.Fatalf("expected active key to verify new token")
+	}
+
+	// Tokens signed by a key which is removed from the set are rejected.
+	cfg.Keys = []config.TokenKey{
+		{ID: "other", Algorithm: "RS256", PublicKeyFile: oldPublicFile},
+		{ID: "new", Algorithm: "ES256", PrivateKeyFile: newPrivateFile},
+	}
+
+	keySet, err = helpers.NewTokenKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = helpers.NewUserHelper(cfg, keySet).ParseToken(oldToken)
+	if _, ok := err.(e.InvalidTokenError); !ok {
+		t.Fatalf("expected InvalidTokenError")
+	}
+}
+
+func TestTokenKeySet_Jwks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	rsaPrivateFile, _ := writeRsaKey(t, dir, "rsa")
+	ecPrivateFile := writeEcKey(t, dir, "ec")
+	keySet, err := helpers.NewTokenKeySet(config.Token{
+		ActiveKeyID: "rsa",
+		Keys: []config.TokenKey{
+			{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaPrivateFile},
+			{ID: "ec", Algorithm: "ES256", PrivateKeyFile: ecPrivateFile},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := keySet.Jwks()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys")
+	}
+
+	ec, rsaKey := jwks.Keys[0], jwks.Keys[1]
+	if ec.Kid != "ec" || ec.Kty != "EC" || ec.Alg != "ES256" || ec.Crv != "P-256" || len(ec.X) != 43 || len(ec.Y) != 43 {
+		t.Fatalf("wrong EC key: %v", ec)
+	}
+
+	if rsaKey.Kid != "rsa" || rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.E != "AQAB" || rsaKey.N == "" {
+		t.Fatalf("wrong RSA key: %v", rsaKey)
+	}
+}
+
+func TestTokenKeySet_Hmac(t *testing.T) {
+	keySet, err := helpers.NewTokenKeySet(config.Token{SecretKey: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keySet.ActiveKey().Method.Alg() != "HS256" {
+		t.Fatalf("expected HS256")
+	}
+
+	if len(keySet.Jwks().Keys) != 0 {
+		t.Fatalf("expected secret key not to be published")
+	}
+}
+
+func TestTokenKeySet_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	_, publicFile := writeRsaKey(t, dir, "rsa")
+	invalidConfigs := []config.Token{
+		{ActiveKeyID: "rsa", Keys: []config.TokenKey{{ID: "rsa", Algorithm: "RS256", PublicKeyFile: publicFile}}},
+		{ActiveKeyID: "other", Keys: []config.TokenKey{{ID: "rsa", Algorithm: "RS256", PublicKeyFile: publicFile}}},
+		{ActiveKeyID: "rsa", Keys: []config.TokenKey{{ID: "rsa", Algorithm: "HS512", PublicKeyFile: publicFile}}},
+		{ActiveKeyID: "rsa", Keys: []config.TokenKey{{ID: "rsa", Algorithm: "RS256", PublicKeyFile: filepath.Join(dir, "missing.pem")}}},
+	}
+
+	for _, cfg := range invalidConfigs {
+		if _, err := helpers.NewTokenKeySet(cfg); err == nil {
+			t.Fatalf("expected error for %v", cfg)
+		}
+	}
+}
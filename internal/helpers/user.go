@@ -1,24 +1,195 @@
 package helpers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"github.com/dgrijalva/jwt-go"
+	"strconv"
+	"tbox_backend/config"
+	"tbox_backend/internal/dto"
+	e "tbox_backend/internal/errors"
+	"time"
 )
 
+const refreshTokenSize = 32
+const tokenIDSize = 16
+const deviceIDSize = 16
+const deviceSecretSize = 32
+
+// magicLinkAudienceSuffix keeps magic link tokens and access tokens from being accepted as each other.
+const magicLinkAudienceSuffix = "/magic_link"
+
 type IUserHelper interface {
-	GenerateToken(userID int) (string, error)
+	GenerateToken(userID int, tokenGeneration int, otpVerified bool) (string, error)
+	ParseToken(token string) (dto.TokenInfo, error)
+	GenerateRefreshToken() (string, error)
+	HashRefreshToken(refreshToken string) string
+	GenerateMagicLinkToken(userID int) (string, error)
+	ParseMagicLinkToken(token string) (dto.TokenInfo, error)
+	GenerateDeviceCredential() (dto.DeviceCredential, error)
+	HashDeviceSecret(deviceSecret string) string
+	Jwks() dto.Jwks
+}
+
+type UserClaims struct {
+	jwt.StandardClaims
+	UserID   int    `json:"user_id"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	// TokenGeneration lets every token of a user be revoked at once, tokens issued before it was used have none.
+	TokenGeneration int `json:"gen,omitempty"`
+	// OtpVerified is set on tokens issued by an OTP login.
+	OtpVerified bool `json:"otp,omitempty"`
 }
 
 type UserHelper struct {
-	secretKey string
+	cfg    config.Token
+	keySet *TokenKeySet
+}
+
+func NewUserHelper(cfg config.Token, keySet *TokenKeySet) *UserHelper {
+	return &UserHelper{
+		cfg:    cfg,
+		keySet: keySet,
+	}
+}
+
+func (c UserHelper) GenerateToken(userID int, tokenGeneration int, otpVerified bool) (string, error) {
+	return c.signToken(userID, tokenGeneration, otpVerified, c.cfg.Audience, c.cfg.ExpiredTime, c.cfg.Scope, c.cfg.ClientID)
+}
+
+// ParseToken verifies signature, expiry, issuer and audience of an access token issued by GenerateToken.
+// The verification key is looked up by the kid header, so tokens signed by retired keys are still accepted.
+func (c UserHelper) ParseToken(token string) (dto.TokenInfo, error) {
+	return c.parseToken(token, c.cfg.Audience)
 }
 
-func NewUserHelper(secretKey string) *UserHelper {
-	return &UserHelper{secretKey: secretKey}
+// GenerateMagicLinkToken signs the token of a magic link, it expires after MagicLinkExpiredTime seconds.
+// Its id lets the token be revoked once it has been used.
+func (c UserHelper) GenerateMagicLinkToken(userID int) (string, error) {
+	return c.signToken(userID, 0, false, c.cfg.Audience+magicLinkAudienceSuffix, c.cfg.MagicLinkExpiredTime, "", "")
 }
 
-func (c UserHelper) GenerateToken(userID int) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = userID
-	return token.SignedString([]byte(c.secretKey))
+func (c UserHelper) ParseMagicLinkToken(token string) (dto.TokenInfo, error) {
+	return c.parseToken(token, c.cfg.Audience+magicLinkAudienceSuffix)
+}
+
+func (c UserHelper) signToken(userID int, tokenGeneration int, otpVerified bool, audience string, expiredTime int, scope string, clientID string) (string, error) {
+	tokenID, err := randomString(tokenIDSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	claims := UserClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Issuer:    c.cfg.Issuer,
+			Audience:  audience,
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Duration(expiredTime) * time.Second).Unix(),
+		},
+		UserID:          userID,
+		Scope:           scope,
+		ClientID:        clientID,
+		TokenGeneration: tokenGeneration,
+		OtpVerified:     otpVerified,
+	}
+
+	activeKey := c.keySet.ActiveKey()
+	token := jwt.NewWithClaims(activeKey.Method, claims)
+	token.Header["kid"] = activeKey.ID
+	return token.SignedString(activeKey.SignKey)
+}
+
+func (c UserHelper) parseToken(token string, audience string) (dto.TokenInfo, error) {
+	claims := UserClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		key := c.keySet.ActiveKey()
+		if kid, ok := token.Header["kid"].(string); ok {
+			var exists bool
+			key, exists = c.keySet.Key(kid)
+			if !exists {
+				return nil, e.InvalidTokenError{}
+			}
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, e.InvalidTokenError{}
+		}
+
+		return key.VerifyKey, nil
+	})
+
+	if err != nil {
+		if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors == jwt.ValidationErrorExpired {
+			return dto.TokenInfo{}, e.ExpiredTokenError{}
+		}
+
+		return dto.TokenInfo{}, e.InvalidTokenError{}
+	}
+
+	if !claims.VerifyIssuer(c.cfg.Issuer, true) ||
+		!claims.VerifyAudience(audience, true) ||
+		claims.ExpiresAt == 0 ||
+		claims.Id == "" ||
+		claims.UserID == 0 {
+		return dto.TokenInfo{}, e.InvalidTokenError{}
+	}
+
+	return dto.TokenInfo{
+		ID:              claims.Id,
+		UserID:          claims.UserID,
+		Scope:           claims.Scope,
+		ClientID:        claims.ClientID,
+		IssuedAt:        time.Unix(claims.IssuedAt, 0).UTC(),
+		ExpiredAt:       time.Unix(claims.ExpiresAt, 0).UTC(),
+		TokenGeneration: claims.TokenGeneration,
+		OtpVerified:     claims.OtpVerified,
+	}, nil
+}
+
+// GenerateRefreshToken returns an opaque random token. Only its hash is persisted.
+func (c UserHelper) GenerateRefreshToken() (string, error) {
+	return randomString(refreshTokenSize)
+}
+
+func (c UserHelper) HashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c UserHelper) GenerateDeviceCredential() (dto.DeviceCredential, error) {
+	deviceID, err := randomString(deviceIDSize)
+	if err != nil {
+		return dto.DeviceCredential{}, err
+	}
+
+	deviceSecret, err := randomString(deviceSecretSize)
+	if err != nil {
+		return dto.DeviceCredential{}, err
+	}
+
+	return dto.DeviceCredential{DeviceID: deviceID, DeviceSecret: deviceSecret}, nil
+}
+
+func (c UserHelper) HashDeviceSecret(deviceSecret string) string {
+	hash := sha256.Sum256([]byte(deviceSecret))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c UserHelper) Jwks() dto.Jwks {
+	return c.keySet.Jwks()
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
@@ -1,31 +1,99 @@
 package helpers
 
 import (
-	"math/rand"
-	"time"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"tbox_backend/config"
+	"tbox_backend/internal/constants"
+	e "tbox_backend/internal/errors"
 )
 
+const otpSaltSize = 16
+
+// otpStatusTokenPrefix is hashed in place of an OTP, no OTP alphabet has a colon so it cannot collide with an OTP hash.
+const otpStatusTokenPrefix = "status:"
+
 type IUserOtpHelper interface {
-	GenerateRandomOtp(size int) string
+	GenerateRandomOtp(size int, alphabet string) (string, error)
+	HashOtp(otp string) (string, string, error)
+	VerifyOtp(otp string, otpHash string, otpSalt string) bool
+	OtpStatusToken(otpSalt string) string
+	VerifyOtpStatusToken(statusToken string, otpSalt string) bool
+	OtpMessage(locale string, purpose string, otp string) string
+	OtpSpeech(locale string, purpose string, otp string) string
+	OtpEmail(locale string, purpose string, otp string) (string, string)
+	MagicLinkEmail(locale string, link string, expiredTime int) (string, string)
 }
 
-type UserOtpHelper struct{}
+type UserOtpHelper struct {
+	cfg config.Otp
+}
 
-func NewUserOtpHelper() *UserOtpHelper {
-	return &UserOtpHelper{}
+func NewUserOtpHelper(cfg config.Otp) *UserOtpHelper {
+	return &UserOtpHelper{cfg: cfg}
 }
 
-func (UserOtpHelper) GenerateRandomOtp(size int) string {
-	var sources = [...]byte{
-		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
+// GenerateRandomOtp picks every character uniformly from the alphabet using crypto/rand.
+func (UserOtpHelper) GenerateRandomOtp(size int, alphabet string) (string, error) {
+	characters, exists := constants.OtpCharacters(alphabet)
+	if !exists {
+		return "", e.InvalidOtpAlphabetError{Alphabet: alphabet}
 	}
 
-	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	max := big.NewInt(int64(len(characters)))
 	otp := make([]byte, size)
 	for i := 0; i < size; i++ {
-		index := random.Int() % len(sources)
-		otp[i] = sources[index]
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		otp[i] = characters[index.Int64()]
+	}
+
+	return string(otp), nil
+}
+
+// HashOtp returns the keyed hash of the otp and the random salt it was computed with.
+func (h UserOtpHelper) HashOtp(otp string) (string, string, error) {
+	salt := make([]byte, otpSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", err
+	}
+
+	otpSalt := hex.EncodeToString(salt)
+	return h.hash(otp, otpSalt), otpSalt, nil
+}
+
+func (h UserOtpHelper) VerifyOtp(otp string, otpHash string, otpSalt string) bool {
+	if otpHash == "" || otpSalt == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(h.hash(otp, otpSalt)), []byte(otpHash)) == 1
+}
+
+// OtpStatusToken proves the status of the OTP hashed with otpSalt was asked for by whoever requested it. The salt
+// is renewed with every OTP and cleared once it is used, so a token only holds for the pending OTP.
+func (h UserOtpHelper) OtpStatusToken(otpSalt string) string {
+	return h.hash(otpStatusTokenPrefix, otpSalt)
+}
+
+func (h UserOtpHelper) VerifyOtpStatusToken(statusToken string, otpSalt string) bool {
+	if statusToken == "" || otpSalt == "" {
+		return false
 	}
 
-	return string(otp)
+	return subtle.ConstantTimeCompare([]byte(h.OtpStatusToken(otpSalt)), []byte(statusToken)) == 1
+}
+
+func (h UserOtpHelper) hash(otp string, otpSalt string) string {
+	mac := hmac.New(sha256.New, []byte(h.cfg.Pepper))
+	mac.Write([]byte(otpSalt))
+	mac.Write([]byte(otp))
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -1,18 +1,188 @@
 package helpers_test
 
 import (
+	"strings"
+	"tbox_backend/config"
+	"tbox_backend/internal/constants"
+	e "tbox_backend/internal/errors"
 	"tbox_backend/internal/helpers"
 	"tbox_backend/internal/validator"
 	"testing"
 )
 
 func TestUserOtpHelper_GenerateRandomOtp(t *testing.T) {
-	userOtpHelper := helpers.NewUserOtpHelper()
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{})
 	userOtpValidator := validator.NewUserOtpValidator()
 
 	size := 6
-	otp := userOtpHelper.GenerateRandomOtp(size)
-	if !userOtpValidator.IsOtpValid(otp, size) {
+	otp, err := userOtpHelper.GenerateRandomOtp(size, constants.OtpNumericAlphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !userOtpValidator.IsOtpValid(otp, size, constants.OtpNumericAlphabet) {
 		t.Fatalf("expected true")
 	}
 }
+
+func TestUserOtpHelper_GenerateRandomOtp_Alphanumeric(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{})
+	userOtpValidator := validator.NewUserOtpValidator()
+
+	size := 8
+	for i := 0; i < 100; i++ {
+		otp, err := userOtpHelper.GenerateRandomOtp(size, constants.OtpAlphanumericAlphabet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !userOtpValidator.IsOtpValid(otp, size, constants.OtpAlphanumericAlphabet) {
+			t.Fatalf("expected %s to be valid", otp)
+		}
+
+		if strings.ContainsAny(otp, "0O1IL") {
+			t.Fatalf("expected no ambiguous characters in %s", otp)
+		}
+	}
+}
+
+func TestUserOtpHelper_GenerateRandomOtp_Distribution(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{})
+
+	counts := map[rune]int{}
+	otp, err := userOtpHelper.GenerateRandomOtp(10000, constants.OtpNumericAlphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range otp {
+		counts[c]++
+	}
+
+	// Every digit is expected 1000 times, a biased generator drifts far outside this range.
+	for _, c := range "0123456789" {
+		if counts[c] < 800 || counts[c] > 1200 {
+			t.Fatalf("expected uniform distribution, got %d for %c", counts[c], c)
+		}
+	}
+}
+
+func TestUserOtpHelper_GenerateRandomOtp_InvalidAlphabet(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{})
+
+	_, err := userOtpHelper.GenerateRandomOtp(6, "emoji")
+	if _, ok := err.(e.InvalidOtpAlphabetError); !ok {
+		t.Fatalf("expected InvalidOtpAlphabetError")
+	}
+}
+
+func TestUserOtpHelper_HashOtp(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{Pepper: "pepper"})
+	otpHash, otpSalt, err := userOtpHelper.HashOtp("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if otpHash == "" || otpSalt == "" || otpHash == "123456" {
+		t.Fatalf("expected hashed otp")
+	}
+
+	otherHash, otherSalt, err := userOtpHelper.HashOtp("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if otherSalt == otpSalt || otherHash == otpHash {
+		t.Fatalf("expected salted hash")
+	}
+
+	if !userOtpHelper.VerifyOtp("123456", otpHash, otpSalt) {
+		t.Fatalf("expected true")
+	}
+
+	if userOtpHelper.VerifyOtp("654321", otpHash, otpSalt) {
+		t.Fatalf("expected false")
+	}
+
+	if helpers.NewUserOtpHelper(config.Otp{Pepper: "other"}).VerifyOtp("123456", otpHash, otpSalt) {
+		t.Fatalf("expected pepper to be part of the hash")
+	}
+
+	if userOtpHelper.VerifyOtp("123456", "", "") {
+		t.Fatalf("expected invalidated otp to never match")
+	}
+}
+
+func newOtpMessageHelper() *helpers.UserOtpHelper {
+	return helpers.NewUserOtpHelper(config.Otp{
+		ExpiredTime: 90,
+		Message: config.OtpMessage{
+			AppName:       "TBOX",
+			DefaultLocale: "vi",
+			Templates: map[string]map[string]string{
+				"vi": {constants.OtpLoginPurpose: "{code} la ma dang nhap {app_name}, het han sau {expiry_minutes} phut"},
+				"en": {constants.OtpLoginPurpose: "{code} is your {app_name} code, it expires in {expiry_minutes} minutes"},
+			},
+		},
+	})
+}
+
+func TestUserOtpHelper_OtpMessage(t *testing.T) {
+	userOtpHelper := newOtpMessageHelper()
+
+	tests := []struct {
+		locale   string
+		purpose  string
+		expected string
+	}{
+		{"vi", constants.OtpLoginPurpose, "123456 la ma dang nhap TBOX, het han sau 2 phut"},
+		{"en-US", constants.OtpLoginPurpose, "123456 is your TBOX code, it expires in 2 minutes"},
+		{"fr-FR,en;q=0.8,vi;q=0.9", constants.OtpLoginPurpose, "123456 la ma dang nhap TBOX, het han sau 2 phut"},
+		{"vi;q=0,en_GB", constants.OtpLoginPurpose, "123456 is your TBOX code, it expires in 2 minutes"},
+		{"fr", constants.OtpLoginPurpose, "123456 la ma dang nhap TBOX, het han sau 2 phut"},
+		{"", constants.OtpLoginPurpose, "123456 la ma dang nhap TBOX, het han sau 2 phut"},
+		{"en", constants.OtpPhoneChangePurpose, "Your OTP is: 123456"},
+	}
+
+	for _, test := range tests {
+		if message := userOtpHelper.OtpMessage(test.locale, test.purpose, "123456"); message != test.expected {
+			t.Fatalf("expected %q for locale %q, got %q", test.expected, test.locale, message)
+		}
+	}
+}
+
+func TestUserOtpHelper_OtpMessage_Autofill(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{
+		Message: config.OtpMessage{
+			AndroidAppHash: "FA+9qCX9VSu",
+			OriginDomain:   "tbox.vn",
+		},
+	})
+
+	message := userOtpHelper.OtpMessage("vi", constants.OtpLoginPurpose, "123456")
+	if message != "Your OTP is: 123456\n\n@tbox.vn #123456\nFA+9qCX9VSu" {
+		t.Fatalf("unexpected message %q", message)
+	}
+
+	userOtpHelper = helpers.NewUserOtpHelper(config.Otp{Message: config.OtpMessage{OriginDomain: "tbox.vn"}})
+	message = userOtpHelper.OtpMessage("vi", constants.OtpLoginPurpose, "123456")
+	if message != "Your OTP is: 123456\n\n@tbox.vn #123456" {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
+
+func TestUserOtpHelper_OtpSpeech(t *testing.T) {
+	userOtpHelper := helpers.NewUserOtpHelper(config.Otp{
+		Message: config.OtpMessage{
+			AppName: "TBOX",
+			Templates: map[string]map[string]string{
+				"en": {constants.OtpVoiceLoginPurpose: "Your {app_name} code is: {code}."},
+			},
+		},
+	})
+
+	speech := userOtpHelper.OtpSpeech("en", constants.OtpVoiceLoginPurpose, "1234")
+	if speech != "Your TBOX code is: 1, 2, 3, 4. Your TBOX code is: 1, 2, 3, 4." {
+		t.Fatalf("unexpected speech %q", speech)
+	}
+}
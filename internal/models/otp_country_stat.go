@@ -0,0 +1,38 @@
+package models
+
+import (
+	"tbox_backend/internal/dto"
+	"time"
+)
+
+type OtpCountryStat struct {
+	CountryCode  string    `db:"country_code"`
+	Day          time.Time `db:"day"`
+	SentCount    int       `db:"sent_count"`
+	BlockedCount int       `db:"blocked_count"`
+	CappedCount  int       `db:"capped_count"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+func (o OtpCountryStat) ToDto() dto.OtpCountryStat {
+	return dto.OtpCountryStat{
+		CountryCode:  o.CountryCode,
+		Day:          o.Day,
+		SentCount:    o.SentCount,
+		BlockedCount: o.BlockedCount,
+		CappedCount:  o.CappedCount,
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+	}
+}
+
+func (o *OtpCountryStat) FromDto(otpCountryStatDto dto.OtpCountryStat) {
+	o.CountryCode = otpCountryStatDto.CountryCode
+	o.Day = otpCountryStatDto.Day
+	o.SentCount = otpCountryStatDto.SentCount
+	o.BlockedCount = otpCountryStatDto.BlockedCount
+	o.CappedCount = otpCountryStatDto.CappedCount
+	o.CreatedAt = otpCountryStatDto.CreatedAt
+	o.UpdatedAt = otpCountryStatDto.UpdatedAt
+}
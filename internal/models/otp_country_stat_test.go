@@ -0,0 +1,60 @@
+package models_test
+
+import (
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestOtpCountryStat_ToDto(t *testing.T) {
+	now := time.Now()
+
+	otpCountryStatModel := models.OtpCountryStat{
+		CountryCode:  "VN",
+		Day:          now,
+		SentCount:    1,
+		BlockedCount: 2,
+		CappedCount:  3,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	otpCountryStatDto := otpCountryStatModel.ToDto()
+	if otpCountryStatDto.CountryCode != otpCountryStatModel.CountryCode ||
+		otpCountryStatDto.Day != otpCountryStatModel.Day ||
+		otpCountryStatDto.SentCount != otpCountryStatModel.SentCount ||
+		otpCountryStatDto.BlockedCount != otpCountryStatModel.BlockedCount ||
+		otpCountryStatDto.CappedCount != otpCountryStatModel.CappedCount ||
+		otpCountryStatDto.CreatedAt != otpCountryStatModel.CreatedAt ||
+		otpCountryStatDto.UpdatedAt != otpCountryStatModel.UpdatedAt {
+		t.Fatalf("Expected: %v", otpCountryStatModel)
+	}
+}
+
+func TestOtpCountryStat_FromDto(t *testing.T) {
+	now := time.Now()
+
+	otpCountryStatDto := dto.OtpCountryStat{
+		CountryCode:  "VN",
+		Day:          now,
+		SentCount:    1,
+		BlockedCount: 2,
+		CappedCount:  3,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	otpCountryStatModel := &models.OtpCountryStat{}
+	otpCountryStatModel.FromDto(otpCountryStatDto)
+
+	if otpCountryStatModel.CountryCode != otpCountryStatDto.CountryCode ||
+		otpCountryStatModel.Day != otpCountryStatDto.Day ||
+		otpCountryStatModel.SentCount != otpCountryStatDto.SentCount ||
+		otpCountryStatModel.BlockedCount != otpCountryStatDto.BlockedCount ||
+		otpCountryStatModel.CappedCount != otpCountryStatDto.CappedCount ||
+		otpCountryStatModel.CreatedAt != otpCountryStatDto.CreatedAt ||
+		otpCountryStatModel.UpdatedAt != otpCountryStatDto.UpdatedAt {
+		t.Fatalf("Expected: %v", otpCountryStatDto)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"tbox_backend/internal/dto"
+	"time"
+)
+
+type RefreshToken struct {
+	RefreshTokenID int       `db:"refresh_token_id"`
+	UserID         int       `db:"user_id"`
+	TokenHash      string    `db:"token_hash"`
+	Revoked        bool      `db:"revoked"`
+	ExpiredAt      time.Time `db:"expired_at"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func (r RefreshToken) ToDto() dto.RefreshToken {
+	return dto.RefreshToken{
+		ID:        r.RefreshTokenID,
+		UserID:    r.UserID,
+		TokenHash: r.TokenHash,
+		Revoked:   r.Revoked,
+		ExpiredAt: r.ExpiredAt,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func (r *RefreshToken) FromDto(refreshTokenDto dto.RefreshToken) {
+	r.RefreshTokenID = refreshTokenDto.ID
+	r.UserID = refreshTokenDto.UserID
+	r.TokenHash = refreshTokenDto.TokenHash
+	r.Revoked = refreshTokenDto.Revoked
+	r.ExpiredAt = refreshTokenDto.ExpiredAt
+	r.CreatedAt = refreshTokenDto.CreatedAt
+	r.UpdatedAt = refreshTokenDto.UpdatedAt
+}
@@ -0,0 +1,60 @@
+package models_test
+
+import (
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestRefreshToken_ToDto(t *testing.T) {
+	now := time.Now()
+
+	refreshTokenModel := models.RefreshToken{
+		RefreshTokenID: 1,
+		UserID:         2,
+		TokenHash:      "abc",
+		Revoked:        true,
+		ExpiredAt:      now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	refreshTokenDto := refreshTokenModel.ToDto()
+	if refreshTokenDto.ID != refreshTokenModel.RefreshTokenID ||
+		refreshTokenDto.UserID != refreshTokenModel.UserID ||
+		refreshTokenDto.TokenHash != refreshTokenModel.TokenHash ||
+		refreshTokenDto.Revoked != refreshTokenModel.Revoked ||
+		refreshTokenDto.ExpiredAt != refreshTokenModel.ExpiredAt ||
+		refreshTokenDto.CreatedAt != refreshTokenModel.CreatedAt ||
+		refreshTokenDto.UpdatedAt != refreshTokenModel.UpdatedAt {
+		t.Fatalf("Expected: %v", refreshTokenModel)
+	}
+}
+
+func TestRefreshToken_FromDto(t *testing.T) {
+	now := time.Now()
+
+	refreshTokenDto := dto.RefreshToken{
+		ID:        1,
+		UserID:    2,
+		TokenHash: "abc",
+		Revoked:   true,
+		ExpiredAt: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	refreshTokenModel := &models.RefreshToken{}
+	refreshTokenModel.FromDto(refreshTokenDto)
+
+	if refreshTokenModel.RefreshTokenID != refreshTokenDto.ID ||
+		refreshTokenModel.UserID != refreshTokenDto.UserID ||
+		refreshTokenModel.TokenHash != refreshTokenDto.TokenHash ||
+		refreshTokenModel.Revoked != refreshTokenDto.Revoked ||
+		refreshTokenModel.ExpiredAt != refreshTokenDto.ExpiredAt ||
+		refreshTokenModel.CreatedAt != refreshTokenDto.CreatedAt ||
+		refreshTokenModel.UpdatedAt != refreshTokenDto.UpdatedAt {
+		t.Fatalf("Expected: %v", refreshTokenDto)
+	}
+}
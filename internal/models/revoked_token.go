@@ -0,0 +1,32 @@
+package models
+
+import (
+	"tbox_backend/internal/dto"
+	"time"
+)
+
+type RevokedToken struct {
+	RevokedTokenID int       `db:"revoked_token_id"`
+	TokenID        string    `db:"token_id"`
+	UserID         int       `db:"user_id"`
+	ExpiredAt      time.Time `db:"expired_at"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (r RevokedToken) ToDto() dto.RevokedToken {
+	return dto.RevokedToken{
+		ID:        r.RevokedTokenID,
+		TokenID:   r.TokenID,
+		UserID:    r.UserID,
+		ExpiredAt: r.ExpiredAt,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func (r *RevokedToken) FromDto(revokedTokenDto dto.RevokedToken) {
+	r.RevokedTokenID = revokedTokenDto.ID
+	r.TokenID = revokedTokenDto.TokenID
+	r.UserID = revokedTokenDto.UserID
+	r.ExpiredAt = revokedTokenDto.ExpiredAt
+	r.CreatedAt = revokedTokenDto.CreatedAt
+}
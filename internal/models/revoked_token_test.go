@@ -0,0 +1,52 @@
+package models_test
+
+import (
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestRevokedToken_ToDto(t *testing.T) {
+	now := time.Now()
+
+	revokedTokenModel := models.RevokedToken{
+		RevokedTokenID: 1,
+		TokenID:        "jti",
+		UserID:         2,
+		ExpiredAt:      now,
+		CreatedAt:      now,
+	}
+
+	revokedTokenDto := revokedTokenModel.ToDto()
+	if revokedTokenDto.ID != revokedTokenModel.RevokedTokenID ||
+		revokedTokenDto.TokenID != revokedTokenModel.TokenID ||
+		revokedTokenDto.UserID != revokedTokenModel.UserID ||
+		revokedTokenDto.ExpiredAt != revokedTokenModel.ExpiredAt ||
+		revokedTokenDto.CreatedAt != revokedTokenModel.CreatedAt {
+		t.Fatalf("Expected: %v", revokedTokenModel)
+	}
+}
+
+func TestRevokedToken_FromDto(t *testing.T) {
+	now := time.Now()
+
+	revokedTokenDto := dto.RevokedToken{
+		ID:        1,
+		TokenID:   "jti",
+		UserID:    2,
+		ExpiredAt: now,
+		CreatedAt: now,
+	}
+
+	revokedTokenModel := &models.RevokedToken{}
+	revokedTokenModel.FromDto(revokedTokenDto)
+
+	if revokedTokenModel.RevokedTokenID != revokedTokenDto.ID ||
+		revokedTokenModel.TokenID != revokedTokenDto.TokenID ||
+		revokedTokenModel.UserID != revokedTokenDto.UserID ||
+		revokedTokenModel.ExpiredAt != revokedTokenDto.ExpiredAt ||
+		revokedTokenModel.CreatedAt != revokedTokenDto.CreatedAt {
+		t.Fatalf("Expected: %v", revokedTokenDto)
+	}
+}
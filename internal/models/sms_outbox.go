@@ -0,0 +1,59 @@
+package models
+
+import (
+	"tbox_backend/internal/dto"
+	"time"
+)
+
+type SmsOutbox struct {
+	SmsOutboxID       int       `db:"sms_outbox_id"`
+	PhoneNumber       string    `db:"phone_number"`
+	Channel           string    `db:"channel"`
+	Content           string    `db:"content"`
+	Status            int       `db:"status"`
+	Attempts          int       `db:"attempts"`
+	NextAttemptAt     time.Time `db:"next_attempt_at"`
+	LastError         string    `db:"last_error"`
+	Provider          string    `db:"provider"`
+	ProviderMessageID string    `db:"provider_message_id"`
+	DeliveryStatus    string    `db:"delivery_status"`
+	ClaimToken        string    `db:"claim_token"`
+	CreatedAt         time.Time `db:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at"`
+}
+
+func (s SmsOutbox) ToDto() dto.SmsOutbox {
+	return dto.SmsOutbox{
+		ID:                s.SmsOutboxID,
+		PhoneNumber:       s.PhoneNumber,
+		Channel:           s.Channel,
+		Content:           s.Content,
+		Status:            s.Status,
+		Attempts:          s.Attempts,
+		NextAttemptAt:     s.NextAttemptAt,
+		LastError:         s.LastError,
+		Provider:          s.Provider,
+		ProviderMessageID: s.ProviderMessageID,
+		DeliveryStatus:    s.DeliveryStatus,
+		ClaimToken:        s.ClaimToken,
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+	}
+}
+
+func (s *SmsOutbox) FromDto(smsOutboxDto dto.SmsOutbox) {
+	s.SmsOutboxID = smsOutboxDto.ID
+	s.PhoneNumber = smsOutboxDto.PhoneNumber
+	s.Channel = smsOutboxDto.Channel
+	s.Content = smsOutboxDto.Content
+	s.Status = smsOutboxDto.Status
+	s.Attempts = smsOutboxDto.Attempts
+	s.NextAttemptAt = smsOutboxDto.NextAttemptAt
+	s.LastError = smsOutboxDto.LastError
+	s.Provider = smsOutboxDto.Provider
+	s.ProviderMessageID = smsOutboxDto.ProviderMessageID
+	s.DeliveryStatus = smsOutboxDto.DeliveryStatus
+	s.ClaimToken = smsOutboxDto.ClaimToken
+	s.CreatedAt = smsOutboxDto.CreatedAt
+	s.UpdatedAt = smsOutboxDto.UpdatedAt
+}
@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSmsOutbox_ToDto(t *testing.T) {
+	now := time.Now()
+
+	smsOutboxModel := models.SmsOutbox{
+		SmsOutboxID:       1,
+		PhoneNumber:       "0961234567",
+		Channel:           "voice",
+		Content:           "Your OTP is: 123456",
+		Status:            1,
+		Attempts:          2,
+		NextAttemptAt:     now,
+		LastError:         "timeout",
+		Provider:          "twilio",
+		ProviderMessageID: "SM123",
+		DeliveryStatus:    "delivered",
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	smsOutboxDto := smsOutboxModel.ToDto()
+	if smsOutboxDto.ID != smsOutboxModel.SmsOutboxID ||
+		smsOutboxDto.PhoneNumber != smsOutboxModel.PhoneNumber ||
+		smsOutboxDto.Channel != smsOutboxModel.Channel ||
+		smsOutboxDto.Content != smsOutboxModel.Content ||
+		smsOutboxDto.Status != smsOutboxModel.Status ||
+		smsOutboxDto.Attempts != smsOutboxModel.Attempts ||
+		smsOutboxDto.NextAttemptAt != smsOutboxModel.NextAttemptAt ||
+		smsOutboxDto.LastError != smsOutboxModel.LastError ||
+		smsOutboxDto.Provider != smsOutboxModel.Provider ||
+		smsOutboxDto.ProviderMessageID != smsOutboxModel.ProviderMessageID ||
+		smsOutboxDto.DeliveryStatus != smsOutboxModel.DeliveryStatus {
+		t.Fatalf("Expected: %v", smsOutboxModel)
+	}
+}
+
+func TestSmsOutbox_FromDto(t *testing.T) {
+	now := time.Now()
+
+	smsOutboxDto := dto.SmsOutbox{
+		ID:                1,
+		PhoneNumber:       "0961234567",
+		Channel:           "voice",
+		Content:           "Your OTP is: 123456",
+		Status:            1,
+		Attempts:          2,
+		NextAttemptAt:     now,
+		LastError:         "timeout",
+		Provider:          "twilio",
+		ProviderMessageID: "SM123",
+		DeliveryStatus:    "delivered",
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	smsOutboxModel := &models.SmsOutbox{}
+	smsOutboxModel.FromDto(smsOutboxDto)
+
+	if smsOutboxModel.SmsOutboxID != smsOutboxDto.ID ||
+		smsOutboxModel.PhoneNumber != smsOutboxDto.PhoneNumber ||
+		smsOutboxModel.Channel != smsOutboxDto.Channel ||
+		smsOutboxModel.Content != smsOutboxDto.Content ||
+		smsOutboxModel.Status != smsOutboxDto.Status ||
+		smsOutboxModel.Attempts != smsOutboxDto.Attempts ||
+		smsOutboxModel.NextAttemptAt != smsOutboxDto.NextAttemptAt ||
+		smsOutboxModel.LastError != smsOutboxDto.LastError ||
+		smsOutboxModel.Provider != smsOutboxDto.Provider ||
+		smsOutboxModel.ProviderMessageID != smsOutboxDto.ProviderMessageID ||
+		smsOutboxModel.DeliveryStatus != smsOutboxDto.DeliveryStatus {
+		t.Fatalf("Expected: %v", smsOutboxDto)
+	}
+}
@@ -6,27 +6,36 @@ import (
 )
 
 type User struct {
-	UserID      int       `db:"user_id"`
-	PhoneNumber string    `db:"phone_number"`
-	Status      int       `db:"status"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+	UserID          int       `db:"user_id"`
+	PhoneNumber     string    `db:"phone_number"`
+	CountryCode     string    `db:"country_code"`
+	Email           string    `db:"email"`
+	Status          int       `db:"status"`
+	TokenGeneration int       `db:"token_generation"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
 }
 
 func (u User) ToDto() dto.User {
 	return dto.User{
-		ID:          u.UserID,
-		PhoneNumber: u.PhoneNumber,
-		Status:      u.Status,
-		CreatedAt:   u.CreatedAt,
-		UpdatedAt:   u.UpdatedAt,
+		ID:              u.UserID,
+		PhoneNumber:     u.PhoneNumber,
+		CountryCode:     u.CountryCode,
+		Email:           u.Email,
+		Status:          u.Status,
+		TokenGeneration: u.TokenGeneration,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
 	}
 }
 
 func (u *User) FromDto(userDto *dto.User) {
 	u.UserID = userDto.ID
 	u.PhoneNumber = userDto.PhoneNumber
+	u.CountryCode = userDto.CountryCode
+	u.Email = userDto.Email
 	u.Status = userDto.Status
+	u.TokenGeneration = userDto.TokenGeneration
 	u.CreatedAt = userDto.CreatedAt
 	u.UpdatedAt = userDto.UpdatedAt
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql"
+	"tbox_backend/internal/dto"
+	"time"
+)
+
+type UserDevice struct {
+	UserDeviceID int          `db:"user_device_id"`
+	DeviceID     string       `db:"device_id"`
+	UserID       int          `db:"user_id"`
+	Name         string       `db:"name"`
+	SecretHash   string       `db:"secret_hash"`
+	Revoked      bool         `db:"revoked"`
+	LastUsedAt   sql.NullTime `db:"last_used_at"`
+	LastUsedIP   string       `db:"last_used_ip"`
+	CreatedAt    time.Time    `db:"created_at"`
+	UpdatedAt    time.Time    `db:"updated_at"`
+}
+
+func (u UserDevice) ToDto() dto.UserDevice {
+	return dto.UserDevice{
+		ID:         u.UserDeviceID,
+		DeviceID:   u.DeviceID,
+		UserID:     u.UserID,
+		Name:       u.Name,
+		SecretHash: u.SecretHash,
+		Revoked:    u.Revoked,
+		LastUsedAt: u.LastUsedAt.Time,
+		LastUsedIP: u.LastUsedIP,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+func (u *UserDevice) FromDto(userDeviceDto dto.UserDevice) {
+	u.UserDeviceID = userDeviceDto.ID
+	u.DeviceID = userDeviceDto.DeviceID
+	u.UserID = userDeviceDto.UserID
+	u.Name = userDeviceDto.Name
+	u.SecretHash = userDeviceDto.SecretHash
+	u.Revoked = userDeviceDto.Revoked
+	u.LastUsedAt = sql.NullTime{Time: userDeviceDto.LastUsedAt, Valid: !userDeviceDto.LastUsedAt.IsZero()}
+	u.LastUsedIP = userDeviceDto.LastUsedIP
+	u.CreatedAt = userDeviceDto.CreatedAt
+	u.UpdatedAt = userDeviceDto.UpdatedAt
+}
@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestUserDevice_ToDto(t *testing.T) {
+	now := time.Now()
+
+	userDeviceModel := models.UserDevice{
+		UserDeviceID: 1,
+		DeviceID:     "device",
+		UserID:       2,
+		Name:         "Pixel",
+		SecretHash:   "hash",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	userDeviceDto := userDeviceModel.ToDto()
+	if userDeviceDto.ID != userDeviceModel.UserDeviceID ||
+		userDeviceDto.DeviceID != userDeviceModel.DeviceID ||
+		userDeviceDto.UserID != userDeviceModel.UserID ||
+		userDeviceDto.Name != userDeviceModel.Name ||
+		userDeviceDto.SecretHash != userDeviceModel.SecretHash ||
+		!userDeviceDto.LastUsedAt.IsZero() ||
+		userDeviceDto.CreatedAt != userDeviceModel.CreatedAt ||
+		userDeviceDto.UpdatedAt != userDeviceModel.UpdatedAt {
+		t.Fatalf("Expected: %v", userDeviceModel)
+	}
+}
+
+func TestUserDevice_FromDto(t *testing.T) {
+	now := time.Now()
+
+	userDeviceDto := dto.UserDevice{
+		ID:         1,
+		DeviceID:   "device",
+		UserID:     2,
+		Name:       "Pixel",
+		SecretHash: "hash",
+		LastUsedAt: now,
+		LastUsedIP: "127.0.0.1",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	userDeviceModel := &models.UserDevice{}
+	userDeviceModel.FromDto(userDeviceDto)
+
+	if userDeviceModel.UserDeviceID != userDeviceDto.ID ||
+		userDeviceModel.DeviceID != userDeviceDto.DeviceID ||
+		userDeviceModel.UserID != userDeviceDto.UserID ||
+		userDeviceModel.SecretHash != userDeviceDto.SecretHash ||
+		!userDeviceModel.LastUsedAt.Valid ||
+		userDeviceModel.LastUsedAt.Time != userDeviceDto.LastUsedAt ||
+		userDeviceModel.LastUsedIP != userDeviceDto.LastUsedIP ||
+		userDeviceModel.CreatedAt != userDeviceDto.CreatedAt {
+		t.Fatalf("Expected: %v", userDeviceDto)
+	}
+}
@@ -1,32 +1,45 @@
 package models
 
 import (
+	"database/sql"
 	"tbox_backend/internal/dto"
 	"time"
 )
 
 type UserOtp struct {
-	UserOtpID int       `db:"user_otp_id"`
-	UserID    int       `db:"user_id"`
-	Otp       string    `db:"otp"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	UserOtpID        int          `db:"user_otp_id"`
+	UserID           int          `db:"user_id"`
+	OtpHash          string       `db:"otp_hash"`
+	OtpSalt          string       `db:"otp_salt"`
+	FailedAttempts   int          `db:"failed_attempts"`
+	InvalidatedCount int          `db:"invalidated_count"`
+	LockedUntil      sql.NullTime `db:"locked_until"`
+	CreatedAt        time.Time    `db:"created_at"`
+	UpdatedAt        time.Time    `db:"updated_at"`
 }
 
 func (u UserOtp) ToOtp() dto.UserOtp {
 	return dto.UserOtp{
-		ID:        u.UserOtpID,
-		UserID:    u.UserID,
-		Otp:       u.Otp,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.UserOtpID,
+		UserID:           u.UserID,
+		OtpHash:          u.OtpHash,
+		OtpSalt:          u.OtpSalt,
+		FailedAttempts:   u.FailedAttempts,
+		InvalidatedCount: u.InvalidatedCount,
+		LockedUntil:      u.LockedUntil.Time,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
 
 func (u *UserOtp) FromDto(userOtpDto dto.UserOtp) {
 	u.UserOtpID = userOtpDto.ID
 	u.UserID = userOtpDto.UserID
-	u.Otp = userOtpDto.Otp
+	u.OtpHash = userOtpDto.OtpHash
+	u.OtpSalt = userOtpDto.OtpSalt
+	u.FailedAttempts = userOtpDto.FailedAttempts
+	u.InvalidatedCount = userOtpDto.InvalidatedCount
+	u.LockedUntil = sql.NullTime{Time: userOtpDto.LockedUntil, Valid: !userOtpDto.LockedUntil.IsZero()}
 	u.CreatedAt = userOtpDto.CreatedAt
 	u.UpdatedAt = userOtpDto.UpdatedAt
 }
@@ -13,7 +13,8 @@ func TestUserOtp_ToDto(t *testing.T) {
 	userOtpModel := models.UserOtp{
 		UserOtpID: 1,
 		UserID:    2,
-		Otp:       "12345",
+		OtpHash:   "hash",
+		OtpSalt:   "salt",
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -21,14 +22,16 @@ func TestUserOtp_ToDto(t *testing.T) {
 	expectedUserOtpDto := dto.UserOtp{
 		ID:        1,
 		UserID:    2,
-		Otp:       "12345",
+		OtpHash:   "hash",
+		OtpSalt:   "salt",
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
 	if userOtpModel.UserOtpID != expectedUserOtpDto.ID ||
 		userOtpModel.UserID != expectedUserOtpDto.UserID ||
-		userOtpModel.Otp != expectedUserOtpDto.Otp ||
+		userOtpModel.OtpHash != expectedUserOtpDto.OtpHash ||
+		userOtpModel.OtpSalt != expectedUserOtpDto.OtpSalt ||
 		userOtpModel.CreatedAt != expectedUserOtpDto.CreatedAt ||
 		userOtpModel.UpdatedAt != expectedUserOtpDto.UpdatedAt {
 		t.Fatalf("Expected: %v", expectedUserOtpDto)
@@ -41,7 +44,8 @@ func TestUserOtp_FromDto(t *testing.T) {
 	userOtpDto := dto.UserOtp{
 		ID:        1,
 		UserID:    2,
-		Otp:       "123456",
+		OtpHash:   "hash",
+		OtpSalt:   "salt",
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -49,7 +53,8 @@ func TestUserOtp_FromDto(t *testing.T) {
 	expectedUserOtpModel := models.UserOtp{
 		UserOtpID: 1,
 		UserID:    2,
-		Otp:       "123456",
+		OtpHash:   "hash",
+		OtpSalt:   "salt",
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -59,7 +64,8 @@ func TestUserOtp_FromDto(t *testing.T) {
 
 	if userOtpModel.UserOtpID != expectedUserOtpModel.UserOtpID ||
 		userOtpModel.UserID != expectedUserOtpModel.UserID ||
-		userOtpModel.Otp != expectedUserOtpModel.Otp ||
+		userOtpModel.OtpHash != expectedUserOtpModel.OtpHash ||
+		userOtpModel.OtpSalt != expectedUserOtpModel.OtpSalt ||
 		userOtpModel.CreatedAt != expectedUserOtpModel.CreatedAt ||
 		userOtpModel.UpdatedAt != expectedUserOtpModel.UpdatedAt {
 		t.Fatalf("Expected: %v", expectedUserOtpModel)
@@ -12,7 +12,9 @@ func TestUser_ToDto(t *testing.T) {
 
 	userModel := models.User{
 		UserID:      1,
-		PhoneNumber: "0967212212",
+		PhoneNumber: "+84967212212",
+		CountryCode: "VN",
+		Email:       "user@tbox.vn",
 		Status:      0,
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -20,7 +22,9 @@ func TestUser_ToDto(t *testing.T) {
 
 	expectedUserDto := dto.User{
 		ID:          1,
-		PhoneNumber: "0967212212",
+		PhoneNumber: "+84967212212",
+		CountryCode: "VN",
+		Email:       "user@tbox.vn",
 		Status:      0,
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -28,6 +32,8 @@ func TestUser_ToDto(t *testing.T) {
 
 	if userModel.UserID != expectedUserDto.ID ||
 		userModel.PhoneNumber != expectedUserDto.PhoneNumber ||
+		userModel.CountryCode != expectedUserDto.CountryCode ||
+		userModel.Email != expectedUserDto.Email ||
 		userModel.Status != expectedUserDto.Status ||
 		userModel.CreatedAt != expectedUserDto.CreatedAt ||
 		userModel.UpdatedAt != expectedUserDto.UpdatedAt {
@@ -40,7 +46,9 @@ func TestUser_FromDto(t *testing.T) {
 
 	userDto := dto.User{
 		ID:          1,
-		PhoneNumber: "0967212212",
+		PhoneNumber: "+84967212212",
+		CountryCode: "VN",
+		Email:       "user@tbox.vn",
 		Status:      0,
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -48,7 +56,9 @@ func TestUser_FromDto(t *testing.T) {
 
 	expectedUserModel := models.User{
 		UserID:      1,
-		PhoneNumber: "0967212212",
+		PhoneNumber: "+84967212212",
+		CountryCode: "VN",
+		Email:       "user@tbox.vn",
 		Status:      0,
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -59,6 +69,8 @@ func TestUser_FromDto(t *testing.T) {
 
 	if userModel.UserID != expectedUserModel.UserID ||
 		userModel.PhoneNumber != expectedUserModel.PhoneNumber ||
+		userModel.CountryCode != expectedUserModel.CountryCode ||
+		userModel.Email != expectedUserModel.Email ||
 		userModel.Status != expectedUserModel.Status ||
 		userModel.CreatedAt != expectedUserModel.CreatedAt ||
 		userModel.UpdatedAt != expectedUserModel.UpdatedAt {
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+	e "tbox_backend/internal/errors"
+	"tbox_backend/internal/helpers"
+	"tbox_backend/internal/stores"
+	"time"
+)
+
+// firstTimeLimiterKey is the key of the single bucket shared by OTP requests for numbers without a user.
+const firstTimeLimiterKey = "first_time"
+
+type IOtpChallengeService interface {
+	Check(ctx context.Context, phoneNumber string, countryCode string, clientIP string, solution dto.OtpChallengeSolution) error
+	NewChallenge(phoneNumber string, reason string) (dto.OtpChallenge, error)
+}
+
+// OtpChallengeService decides which OTP requests have to solve a challenge first, to make SMS pumping costly.
+// A request is challenged when its client IP sent too many requests, when a burst of numbers without a user asks
+// for OTPs, or when its number belongs to a country no OTP was sent to lately.
+type OtpChallengeService struct {
+	cfg                 config.Config
+	otpChallengeHelper  helpers.IOtpChallengeHelper
+	userStore           stores.IUserStore
+	otpCountryStatStore stores.IOtpCountryStatStore
+	captchaVerifier     external.ICaptchaVerifier
+	ipLimiter           helpers.IRateLimiter
+	firstTimeLimiter    helpers.IRateLimiter
+	solvedLimiter       helpers.IRateLimiter
+}
+
+// NewOtpChallengeService takes the limiters of the risk triggers. solvedLimiter must let each key through once
+// until the challenge expires, so a challenge is only solved once. captchaVerifier may be nil.
+func NewOtpChallengeService(
+	cfg config.Config,
+	otpChallengeHelper helpers.IOtpChallengeHelper,
+	userStore stores.IUserStore,
+	otpCountryStatStore stores.IOtpCountryStatStore,
+	captchaVerifier external.ICaptchaVerifier,
+	ipLimiter helpers.IRateLimiter,
+	firstTimeLimiter helpers.IRateLimiter,
+	solvedLimiter helpers.IRateLimiter,
+) *OtpChallengeService {
+	return &OtpChallengeService{
+		cfg:                 cfg,
+		otpChallengeHelper:  otpChallengeHelper,
+		userStore:           userStore,
+		otpCountryStatStore: otpCountryStatStore,
+		captchaVerifier:     captchaVerifier,
+		ipLimiter:           ipLimiter,
+		firstTimeLimiter:    firstTimeLimiter,
+		solvedLimiter:       solvedLimiter,
+	}
+}
+
+// Check returns OtpChallengeRequiredError when the OTP request has to solve a challenge, or when the solution
+// it was sent with is invalid. A solved challenge lets the request through whatever its risk.
+func (s OtpChallengeService) Check(ctx context.Context, phoneNumber string, countryCode string, clientIP string, solution dto.OtpChallengeSolution) error {
+	if solution.Challenge != "" {
+		return s.verify(ctx, phoneNumber, clientIP, solution)
+	}
+
+	if clientIP != "" {
+		rateLimit, err := s.ipLimiter.Allow(clientIP)
+		if err != nil {
+			return err
+		} else if !rateLimit.Allowed {
+			return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeIPReason}
+		}
+	}
+
+	_, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		rateLimit, err := s.firstTimeLimiter.Allow(firstTimeLimiterKey)
+		if err != nil {
+			return err
+		} else if !rateLimit.Allowed {
+			return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeFirstTimeReason}
+		}
+	}
+
+	days := s.cfg.OtpChallenge.NewCountryDays
+	if days > 0 && countryCode != "" {
+		sent, err := s.otpCountryStatStore.HasSentSince(countryCode, time.Now().UTC().AddDate(0, 0, -days))
+		if err != nil {
+			return err
+		} else if !sent {
+			return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeNewCountryReason}
+		}
+	}
+
+	return nil
+}
+
+func (s OtpChallengeService) verify(ctx context.Context, phoneNumber string, clientIP string, solution dto.OtpChallengeSolution) error {
+	claims, err := s.otpChallengeHelper.ParseChallenge(solution.Challenge, phoneNumber)
+	if err != nil {
+		return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeInvalidReason}
+	}
+
+	var solved bool
+	if solution.CaptchaToken != "" && s.captchaVerifier != nil {
+		solved, err = s.captchaVerifier.Verify(ctx, solution.CaptchaToken, clientIP)
+		if err != nil {
+			return err
+		}
+	} else {
+		// The difficulty of the claims is not trusted, it is only given to clients.
+		solved = helpers.VerifyProofOfWork(solution.Challenge, solution.Nonce, s.otpChallengeHelper.Difficulty())
+	}
+
+	if !solved {
+		return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeInvalidReason}
+	}
+
+	rateLimit, err := s.solvedLimiter.Allow(claims.Id)
+	if err != nil {
+		return err
+	} else if !rateLimit.Allowed {
+		return e.OtpChallengeRequiredError{Reason: constants.OtpChallengeInvalidReason}
+	}
+
+	return nil
+}
+
+func (s OtpChallengeService) NewChallenge(phoneNumber string, reason string) (dto.OtpChallenge, error) {
+	challenge, claims, err := s.otpChallengeHelper.NewChallenge(phoneNumber, time.Now().UTC())
+	if err != nil {
+		return dto.OtpChallenge{}, err
+	}
+
+	otpChallenge := dto.OtpChallenge{
+		Challenge:  challenge,
+		Reason:     reason,
+		Difficulty: claims.Difficulty,
+		ExpiresAt:  time.Unix(claims.ExpiresAt, 0).UTC(),
+	}
+
+	if s.captchaVerifier != nil {
+		otpChallenge.CaptchaSiteKey = s.cfg.OtpChallenge.Captcha.SiteKey
+	}
+
+	return otpChallenge, nil
+}
@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"strconv"
+	"tbox_backend/config"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+	e "tbox_backend/internal/errors"
+	"tbox_backend/internal/helpers"
+	"tbox_backend/internal/services"
+	mockExternal "tbox_backend/mock/external"
+	mockStores "tbox_backend/mock/stores"
+	"testing"
+	"time"
+)
+
+func expectOtpChallengeReason(t *testing.T, err error, reason string) {
+	challengeRequiredErr, ok := err.(e.OtpChallengeRequiredError)
+	if !ok || challengeRequiredErr.Reason != reason {
+		t.Fatalf("expected challenge for %s, got %v", reason, err)
+	}
+}
+
+func TestOtpChallengeService_Check_IPVolume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userStore := mockStores.NewMockIUserStore(ctrl)
+	userStore.EXPECT().GetByPhoneNumber(gomock.Any()).Return(&dto.User{ID: 1}, true, nil).Times(2)
+	otpChallengeService := services.NewOtpChallengeService(
+		config.Config{},
+		helpers.NewOtpChallengeHelper(config.OtpChallenge{}, "secret"),
+		userStore,
+		mockStores.NewMockIOtpCountryStatStore(ctrl),
+		nil,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", dto.OtpChallengeSolution{}); err != nil {
+			t.Fatalf("expected nil at request %d, got %v", i, err)
+		}
+	}
+
+	err := otpChallengeService.Check(context.Background(), "+84967288124", "VN", "203.0.113.7", dto.OtpChallengeSolution{})
+	expectOtpChallengeReason(t, err, constants.OtpChallengeIPReason)
+}
+
+func TestOtpChallengeService_Check_FirstTimeBurst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userStore := mockStores.NewMockIUserStore(ctrl)
+	userStore.EXPECT().GetByPhoneNumber(gomock.Any()).Return(nil, false, nil).Times(2)
+	otpChallengeService := services.NewOtpChallengeService(
+		config.Config{},
+		helpers.NewOtpChallengeHelper(config.OtpChallenge{}, "secret"),
+		userStore,
+		mockStores.NewMockIOtpCountryStatStore(ctrl),
+		nil,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	if err := otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", dto.OtpChallengeSolution{}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	err := otpChallengeService.Check(context.Background(), "+84967288124", "VN", "203.0.113.8", dto.OtpChallengeSolution{})
+	expectOtpChallengeReason(t, err, constants.OtpChallengeFirstTimeReason)
+}
+
+func TestOtpChallengeService_Check_NewCountry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Config{}
+	cfg.OtpChallenge.NewCountryDays = 7
+	cfg.Otp.CountryPolicy.DefaultDailyLimit = 500
+	cfg.Otp.CountryPolicy.DailyLimits = map[string]int{"vn": 0}
+	userStore := mockStores.NewMockIUserStore(ctrl)
+	userStore.EXPECT().GetByPhoneNumber(gomock.Any()).Return(&dto.User{ID: 1}, true, nil).Times(3)
+	otpCountryStatStore := mockStores.NewMockIOtpCountryStatStore(ctrl)
+	otpCountryStatStore.EXPECT().HasSentSince(gomock.Eq("TH"), gomock.Any()).Return(false, nil)
+	otpCountryStatStore.EXPECT().HasSentSince(gomock.Eq("SG"), gomock.Any()).Return(true, nil)
+	otpCountryStatStore.EXPECT().HasSentSince(gomock.Eq("VN"), gomock.Any()).Return(false, nil)
+	otpChallengeService := services.NewOtpChallengeService(
+		cfg,
+		helpers.NewOtpChallengeHelper(cfg.OtpChallenge, "secret"),
+		userStore,
+		otpCountryStatStore,
+		nil,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	err := otpChallengeService.Check(context.Background(), "+66812345678", "TH", "", dto.OtpChallengeSolution{})
+	expectOtpChallengeReason(t, err, constants.OtpChallengeNewCountryReason)
+
+	if err := otpChallengeService.Check(context.Background(), "+6581234567", "SG", "", dto.OtpChallengeSolution{}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// OTPs to countries without a daily limit are counted too, they can be new.
+	err = otpChallengeService.Check(context.Background(), "+84967288123", "VN", "", dto.OtpChallengeSolution{})
+	expectOtpChallengeReason(t, err, constants.OtpChallengeNewCountryReason)
+}
+
+func TestOtpChallengeService_Check_ProofOfWork(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Config{}
+	cfg.OtpChallenge.ExpiredTime = 300
+	cfg.OtpChallenge.Difficulty = 8
+	otpChallengeService := services.NewOtpChallengeService(
+		cfg,
+		helpers.NewOtpChallengeHelper(cfg.OtpChallenge, "secret"),
+		mockStores.NewMockIUserStore(ctrl),
+		mockStores.NewMockIOtpCountryStatStore(ctrl),
+		nil,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	challenge, err := otpChallengeService.NewChallenge("+84967288123", constants.OtpChallengeIPReason)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if challenge.Difficulty != 8 || challenge.Reason != constants.OtpChallengeIPReason || challenge.CaptchaSiteKey != "" || !challenge.ExpiresAt.After(time.Now()) {
+		t.Fatalf("unexpected challenge %+v", challenge)
+	}
+
+	solution := dto.OtpChallengeSolution{Challenge: challenge.Challenge, Nonce: helpers.SolveProofOfWork(challenge.Challenge, challenge.Difficulty)}
+	if err := otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", solution); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	// A challenge is solved once.
+	err = otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", solution)
+	expectOtpChallengeReason(t, err, constants.OtpChallengeInvalidReason)
+
+	err = otpChallengeService.Check(context.Background(), "+84967288124", "VN", "203.0.113.7", solution)
+	expectOtpChallengeReason(t, err, constants.OtpChallengeInvalidReason)
+}
+
+func TestOtpChallengeService_Check_ForgedDifficulty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Config{}
+	cfg.OtpChallenge.ExpiredTime = 300
+	cfg.OtpChallenge.Difficulty = 8
+	otpChallengeService := services.NewOtpChallengeService(
+		cfg,
+		helpers.NewOtpChallengeHelper(cfg.OtpChallenge, "secret"),
+		mockStores.NewMockIUserStore(ctrl),
+		mockStores.NewMockIOtpCountryStatStore(ctrl),
+		nil,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	// A challenge claiming no difficulty, signed with the same key, still has to be solved with the configured one.
+	challenge, _, err := helpers.NewOtpChallengeHelper(config.OtpChallenge{ExpiredTime: 300}, "secret").NewChallenge("+84967288123", time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := 0
+	for helpers.VerifyProofOfWork(challenge, strconv.Itoa(nonce), 8) {
+		nonce++
+	}
+
+	solution := dto.OtpChallengeSolution{Challenge: challenge, Nonce: strconv.Itoa(nonce)}
+	err = otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", solution)
+	expectOtpChallengeReason(t, err, constants.OtpChallengeInvalidReason)
+}
+
+func TestOtpChallengeService_Check_Captcha(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Config{}
+	cfg.OtpChallenge.Captcha.SiteKey = "site_key"
+	captchaVerifier := mockExternal.NewMockICaptchaVerifier(ctrl)
+	captchaVerifier.EXPECT().Verify(gomock.Any(), gomock.Eq("unsolved"), gomock.Eq("203.0.113.7")).Return(false, nil)
+	captchaVerifier.EXPECT().Verify(gomock.Any(), gomock.Eq("solved"), gomock.Eq("203.0.113.7")).Return(true, nil)
+	cfg.OtpChallenge.ExpiredTime = 300
+	otpChallengeService := services.NewOtpChallengeService(
+		cfg,
+		helpers.NewOtpChallengeHelper(cfg.OtpChallenge, "secret"),
+		mockStores.NewMockIUserStore(ctrl),
+		mockStores.NewMockIOtpCountryStatStore(ctrl),
+		captchaVerifier,
+		helpers.NewPhoneNumberRateLimiters(0, 2),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+		helpers.NewPhoneNumberRateLimiters(0, 1),
+	)
+
+	challenge, _ := otpChallengeService.NewChallenge("+84967288123", constants.OtpChallengeIPReason)
+	if challenge.CaptchaSiteKey != "site_key" {
+		t.Fatalf("expected the site key, got %+v", challenge)
+	}
+
+	err := otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", dto.OtpChallengeSolution{Challenge: challenge.Challenge, CaptchaToken: "unsolved"})
+	expectOtpChallengeReason(t, err, constants.OtpChallengeInvalidReason)
+
+	err = otpChallengeService.Check(context.Background(), "+84967288123", "VN", "203.0.113.7", dto.OtpChallengeSolution{Challenge: challenge.Challenge, CaptchaToken: "solved"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"log"
+	"tbox_backend/internal/stores"
+	"time"
+)
+
+// RateLimitPurger periodically deletes the token buckets which are full again.
+type RateLimitPurger struct {
+	rateLimitStore stores.IRateLimitStore
+	interval       time.Duration
+}
+
+func NewRateLimitPurger(rateLimitStore stores.IRateLimitStore, interval time.Duration) *RateLimitPurger {
+	return &RateLimitPurger{
+		rateLimitStore: rateLimitStore,
+		interval:       interval,
+	}
+}
+
+func (p *RateLimitPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Purge()
+		}
+	}
+}
+
+func (p *RateLimitPurger) Purge() {
+	deleted, err := p.rateLimitStore.DeleteExpired()
+	if err != nil {
+		log.Println("Failed to purge rate limits", err)
+		return
+	}
+
+	if deleted > 0 {
+		log.Printf("Purged %d rate limits", deleted)
+	}
+}
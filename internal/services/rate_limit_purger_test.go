@@ -0,0 +1,23 @@
+package services_test
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"tbox_backend/internal/services"
+	mockStores "tbox_backend/mock/stores"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPurger_Purge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rateLimitStore := mockStores.NewMockIRateLimitStore(ctrl)
+	rateLimitStore.EXPECT().DeleteExpired().Return(int64(2), nil)
+	rateLimitStore.EXPECT().DeleteExpired().Return(int64(0), errors.New("Something went wrong "))
+
+	purger := services.NewRateLimitPurger(rateLimitStore, time.Hour)
+	purger.Purge()
+	purger.Purge()
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"log"
+	"tbox_backend/internal/stores"
+	"time"
+)
+
+// RevokedTokenPurger periodically deletes revoked tokens which are already expired.
+type RevokedTokenPurger struct {
+	revokedTokenStore stores.IRevokedTokenStore
+	interval          time.Duration
+}
+
+func NewRevokedTokenPurger(revokedTokenStore stores.IRevokedTokenStore, interval time.Duration) *RevokedTokenPurger {
+	return &RevokedTokenPurger{
+		revokedTokenStore: revokedTokenStore,
+		interval:          interval,
+	}
+}
+
+func (p *RevokedTokenPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Purge()
+		}
+	}
+}
+
+func (p *RevokedTokenPurger) Purge() {
+	deleted, err := p.revokedTokenStore.DeleteExpired(time.Now().UTC())
+	if err != nil {
+		log.Println("Failed to purge revoked tokens", err)
+		return
+	}
+
+	if deleted > 0 {
+		log.Printf("Purged %d revoked tokens", deleted)
+	}
+}
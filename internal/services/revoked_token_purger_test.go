@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"tbox_backend/internal/services"
+	mockStores "tbox_backend/mock/stores"
+	"testing"
+	"time"
+)
+
+func TestRevokedTokenPurger_Purge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	revokedTokenStore := mockStores.NewMockIRevokedTokenStore(ctrl)
+	revokedTokenStore.EXPECT().DeleteExpired(gomock.Any()).Return(int64(2), nil)
+	revokedTokenStore.EXPECT().DeleteExpired(gomock.Any()).Return(int64(0), errors.New("Something went wrong "))
+
+	purger := services.NewRevokedTokenPurger(revokedTokenStore, time.Hour)
+	purger.Purge()
+	purger.Purge()
+}
+
+func TestRevokedTokenPurger_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	purged := make(chan struct{}, 1)
+	revokedTokenStore := mockStores.NewMockIRevokedTokenStore(ctrl)
+	revokedTokenStore.EXPECT().DeleteExpired(gomock.Any()).Do(func(now time.Time) {
+		select {
+		case purged <- struct{}{}:
+		default:
+		}
+	}).Return(int64(0), nil).MinTimes(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		services.NewRevokedTokenPurger(revokedTokenStore, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-purged:
+	case <-time.After(time.Second):
+		t.Fatalf("expected purge")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/stores"
+	"time"
+)
+
+const maxSmsErrorSize = 1024
+
+// SmsDispatcher sends the messages queued in the sms outbox with a pool of workers, messages of the voice
+// channel are read out by a call. Failed messages are retried with exponential backoff and jitter and
+// dead-lettered after MaxAttempts. Messages still unsent when the OTP they carry expires are failed instead.
+type SmsDispatcher struct {
+	cfg            config.SmsOutbox
+	otpExpiredTime time.Duration
+	smsOutboxStore stores.ISmsOutboxStore
+	smsService     external.ISmsService
+	voiceService   external.IVoiceService
+	mutex          sync.Mutex
+	random         *rand.Rand
+}
+
+func NewSmsDispatcher(
+	cfg config.SmsOutbox,
+	otpExpiredTime time.Duration,
+	smsOutboxStore stores.ISmsOutboxStore,
+	smsService external.ISmsService,
+	voiceService external.IVoiceService,
+) *SmsDispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = cfg.Workers
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 1
+	}
+
+	return &SmsDispatcher{
+		cfg:            cfg,
+		otpExpiredTime: otpExpiredTime,
+		smsOutboxStore: smsOutboxStore,
+		smsService:     smsService,
+		voiceService:   voiceService,
+		random:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (d *SmsDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(d.cfg.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep draining while full batches are claimed, so a backlog does not wait for the next tick.
+			for d.Dispatch(ctx) == d.cfg.BatchSize && ctx.Err() == nil {
+			}
+		}
+	}
+}
+
+// Dispatch claims one batch of due messages, sends them and returns how many were claimed. Sending is
+// cancelled when ctx is done, the messages are then retried like failed ones.
+func (d *SmsDispatcher) Dispatch(ctx context.Context) int {
+	now := time.Now().UTC()
+	smsOutboxes, err := d.smsOutboxStore.Claim(now, d.cfg.BatchSize, time.Duration(d.cfg.LeaseTime)*time.Second)
+	if err != nil {
+		log.Println("Failed to claim sms outbox", err)
+		return 0
+	} else if len(smsOutboxes) == 0 {
+		return 0
+	}
+
+	jobs := make(chan dto.SmsOutbox)
+	var wg sync.WaitGroup
+	for i := 0; i < d.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for smsOutbox := range jobs {
+				d.send(ctx, smsOutbox)
+			}
+		}()
+	}
+
+	for _, smsOutbox := range smsOutboxes {
+		jobs <- smsOutbox
+	}
+
+	close(jobs)
+	wg.Wait()
+	return len(smsOutboxes)
+}
+
+func (d *SmsDispatcher) send(ctx context.Context, smsOutbox dto.SmsOutbox) {
+	now := time.Now().UTC()
+	if d.otpExpiredTime > 0 && now.Sub(smsOutbox.CreatedAt) > d.otpExpiredTime {
+		// The OTP can no longer be used, it is not delivered and not kept.
+		smsOutbox.Status = constants.SmsOutboxDeadStatus
+		smsOutbox.DeliveryStatus = external.SmsStatusFailed
+		smsOutbox.Content = ""
+		smsOutbox.LastError = "OTP expired before it was sent"
+		smsOutbox.UpdatedAt = now
+		d.update(smsOutbox)
+		return
+	}
+
+	receipt, err := d.deliver(ctx, smsOutbox)
+
+	now = time.Now().UTC()
+	smsOutbox.Attempts++
+	smsOutbox.UpdatedAt = now
+	if err == nil {
+		// The content holds the OTP, it is not kept once it is no longer needed.
+		smsOutbox.Status = constants.SmsOutboxSentStatus
+		smsOutbox.Content = ""
+		smsOutbox.LastError = ""
+		smsOutbox.Provider = receipt.Provider
+		smsOutbox.ProviderMessageID = receipt.MessageID
+		smsOutbox.DeliveryStatus = external.SmsStatusSent
+	} else {
+		smsOutbox.LastError = err.Error()
+		if len(smsOutbox.LastError) > maxSmsErrorSize {
+			smsOutbox.LastError = smsOutbox.LastError[:maxSmsErrorSize]
+		}
+
+		if smsOutbox.Attempts >= d.cfg.MaxAttempts {
+			log.Printf("Giving up sending sms %d after %d attempts: %v", smsOutbox.ID, smsOutbox.Attempts, err)
+			smsOutbox.Status = constants.SmsOutboxDeadStatus
+			smsOutbox.DeliveryStatus = external.SmsStatusFailed
+			smsOutbox.Content = ""
+		} else {
+			smsOutbox.NextAttemptAt = now.Add(d.Backoff(smsOutbox.Attempts))
+		}
+	}
+
+	d.update(smsOutbox)
+}
+
+func (d *SmsDispatcher) update(smsOutbox dto.SmsOutbox) {
+	updated, err := d.smsOutboxStore.Update(smsOutbox)
+	if err != nil {
+		log.Printf("Failed to update sms outbox %d: %v", smsOutbox.ID, err)
+	} else if !updated {
+		log.Printf("Lost the lease of sms outbox %d, its result is left to the dispatcher which claimed it again", smsOutbox.ID)
+	}
+}
+
+func (d *SmsDispatcher) deliver(ctx context.Context, smsOutbox dto.SmsOutbox) (external.SmsReceipt, error) {
+	if smsOutbox.Channel == constants.OtpVoiceChannel {
+		voiceReceipt, err := d.voiceService.Call(ctx, smsOutbox.PhoneNumber, smsOutbox.Content)
+		return external.SmsReceipt{Provider: voiceReceipt.Provider, MessageID: voiceReceipt.CallID}, err
+	}
+
+	return d.smsService.Send(ctx, smsOutbox.PhoneNumber, smsOutbox.Content)
+}
+
+// Backoff returns the delay before the next attempt: BaseBackoff doubled per attempt, capped at MaxBackoff,
+// with a random jitter of up to half of it so failed messages do not retry in lockstep.
+func (d *SmsDispatcher) Backoff(attempts int) time.Duration {
+	backoff := time.Duration(d.cfg.BaseBackoff) * time.Second
+	maxBackoff := time.Duration(d.cfg.MaxBackoff) * time.Second
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if maxBackoff > 0 && backoff >= maxBackoff {
+			break
+		}
+	}
+
+	if maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	if backoff <= 1 {
+		return backoff
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return backoff - time.Duration(d.random.Int63n(int64(backoff/2)+1))
+}
@@ -0,0 +1,205 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"tbox_backend/config"
+	"tbox_backend/external"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/services"
+	mockExternal "tbox_backend/mock/external"
+	mockStores "tbox_backend/mock/stores"
+	"testing"
+	"time"
+)
+
+func TestSmsDispatcher_Dispatch_Sent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutbox := dto.SmsOutbox{ID: 1, PhoneNumber: "0961234567", Content: "Your OTP is: 123456", Status: constants.SmsOutboxPendingStatus, ClaimToken: "claim", CreatedAt: time.Now().UTC()}
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Eq(10), gomock.Eq(time.Minute)).Return([]dto.SmsOutbox{smsOutbox}, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Do(func(updated dto.SmsOutbox) {
+		if updated.Status != constants.SmsOutboxSentStatus || updated.Attempts != 1 || updated.Content != "" || updated.ClaimToken != "claim" {
+			t.Fatalf("expected sent sms without content, got %v", updated)
+		}
+
+		if updated.Provider != "mockapi" || updated.ProviderMessageID != "42" || updated.DeliveryStatus != external.SmsStatusSent {
+			t.Fatalf("expected sms receipt to be recorded, got %v", updated)
+		}
+	}).Return(true, nil)
+
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().Send(gomock.Any(), gomock.Eq("0961234567"), gomock.Eq("Your OTP is: 123456")).Return(external.SmsReceipt{Provider: "mockapi", MessageID: "42"}, nil)
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, smsService, mockExternal.NewMockIVoiceService(ctrl))
+	if dispatched := dispatcher.Dispatch(context.Background()); dispatched != 1 {
+		t.Fatalf("expected 1 dispatched sms, got %d", dispatched)
+	}
+}
+
+func TestSmsDispatcher_Dispatch_Voice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutbox := dto.SmsOutbox{ID: 1, PhoneNumber: "0961234567", Channel: constants.OtpVoiceChannel, Content: "1, 2, 3.", Status: constants.SmsOutboxPendingStatus, ClaimToken: "claim", CreatedAt: time.Now().UTC()}
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return([]dto.SmsOutbox{smsOutbox}, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Do(func(updated dto.SmsOutbox) {
+		if updated.Status != constants.SmsOutboxSentStatus || updated.Provider != "voice" || updated.ProviderMessageID != "CA123" {
+			t.Fatalf("expected call receipt to be recorded, got %v", updated)
+		}
+	}).Return(true, nil)
+
+	voiceService := mockExternal.NewMockIVoiceService(ctrl)
+	voiceService.EXPECT().Call(gomock.Any(), gomock.Eq("0961234567"), gomock.Eq("1, 2, 3.")).Return(external.VoiceReceipt{Provider: "voice", CallID: "CA123"}, nil)
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, mockExternal.NewMockISmsService(ctrl), voiceService)
+	dispatcher.Dispatch(context.Background())
+}
+
+func TestSmsDispatcher_Dispatch_Retry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutbox := dto.SmsOutbox{ID: 1, PhoneNumber: "0961234567", Content: "Your OTP is: 123456", Status: constants.SmsOutboxPendingStatus, ClaimToken: "claim", CreatedAt: time.Now().UTC(), Attempts: 1}
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return([]dto.SmsOutbox{smsOutbox}, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Do(func(updated dto.SmsOutbox) {
+		if updated.Status != constants.SmsOutboxPendingStatus ||
+			updated.Attempts != 2 ||
+			updated.Content == "" ||
+			updated.LastError == "" ||
+			!updated.NextAttemptAt.After(time.Now()) {
+			t.Fatalf("expected sms to be retried later, got %v", updated)
+		}
+	}).Return(true, nil)
+
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(external.SmsReceipt{}, errors.New("All SMS providers failed "))
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, smsService, mockExternal.NewMockIVoiceService(ctrl))
+	dispatcher.Dispatch(context.Background())
+}
+
+func TestSmsDispatcher_Dispatch_DeadLetter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutbox := dto.SmsOutbox{ID: 1, PhoneNumber: "0961234567", Content: "Your OTP is: 123456", Status: constants.SmsOutboxPendingStatus, ClaimToken: "claim", CreatedAt: time.Now().UTC(), Attempts: 2}
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return([]dto.SmsOutbox{smsOutbox}, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Do(func(updated dto.SmsOutbox) {
+		if updated.Status != constants.SmsOutboxDeadStatus || updated.Attempts != 3 || updated.Content != "" {
+			t.Fatalf("expected dead lettered sms, got %v", updated)
+		}
+	}).Return(true, nil)
+
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(external.SmsReceipt{}, errors.New("All SMS providers failed "))
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, smsService, mockExternal.NewMockIVoiceService(ctrl))
+	dispatcher.Dispatch(context.Background())
+}
+
+func TestSmsDispatcher_Dispatch_OtpExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// A message claimed after its OTP expired, e.g. while the providers were down, is failed without being sent.
+	smsOutbox := dto.SmsOutbox{ID: 1, PhoneNumber: "0961234567", Content: "Your OTP is: 123456", Status: constants.SmsOutboxPendingStatus, ClaimToken: "claim", CreatedAt: time.Now().UTC().Add(-2 * time.Minute)}
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return([]dto.SmsOutbox{smsOutbox}, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Do(func(updated dto.SmsOutbox) {
+		if updated.Status != constants.SmsOutboxDeadStatus || updated.DeliveryStatus != external.SmsStatusFailed || updated.Content != "" {
+			t.Fatalf("expected failed sms without content, got %v", updated)
+		}
+	}).Return(true, nil)
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, mockExternal.NewMockISmsService(ctrl), mockExternal.NewMockIVoiceService(ctrl))
+	dispatcher.Dispatch(context.Background())
+}
+
+func TestSmsDispatcher_Dispatch_Workers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutboxes := make([]dto.SmsOutbox, 0, 10)
+	for i := 1; i <= 10; i++ {
+		smsOutboxes = append(smsOutboxes, dto.SmsOutbox{ID: i, PhoneNumber: "0961234567", Content: "hello", CreatedAt: time.Now().UTC()})
+	}
+
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(smsOutboxes, nil)
+	smsOutboxStore.EXPECT().Update(gomock.Any()).Return(true, nil).Times(10)
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Return(external.SmsReceipt{}, nil).Times(10)
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, smsService, mockExternal.NewMockIVoiceService(ctrl))
+	if dispatched := dispatcher.Dispatch(context.Background()); dispatched != 10 {
+		t.Fatalf("expected 10 dispatched sms, got %d", dispatched)
+	}
+}
+
+func TestSmsDispatcher_Dispatch_ClaimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("Something went wrong "))
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, mockExternal.NewMockISmsService(ctrl), mockExternal.NewMockIVoiceService(ctrl))
+	if dispatched := dispatcher.Dispatch(context.Background()); dispatched != 0 {
+		t.Fatalf("expected nothing dispatched")
+	}
+}
+
+func TestSmsDispatcher_Backoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, mockStores.NewMockISmsOutboxStore(ctrl), mockExternal.NewMockISmsService(ctrl), mockExternal.NewMockIVoiceService(ctrl))
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, max := range expected {
+		for j := 0; j < 20; j++ {
+			backoff := dispatcher.Backoff(i + 1)
+			if backoff > max || backoff < max/2 {
+				t.Fatalf("expected backoff of attempt %d between %v and %v, got %v", i+1, max/2, max, backoff)
+			}
+		}
+	}
+}
+
+func TestSmsDispatcher_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	claimed := make(chan struct{}, 1)
+	smsOutboxStore := mockStores.NewMockISmsOutboxStore(ctrl)
+	smsOutboxStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(now time.Time, limit int, leaseTime time.Duration) {
+		select {
+		case claimed <- struct{}{}:
+		default:
+		}
+	}).Return(nil, nil).MinTimes(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	dispatcher := services.NewSmsDispatcher(config.SmsOutbox{Workers: 2, BatchSize: 10, PollInterval: 1, LeaseTime: 60, MaxAttempts: 3, BaseBackoff: 2, MaxBackoff: 10}, time.Minute, smsOutboxStore, mockExternal.NewMockISmsService(ctrl), mockExternal.NewMockIVoiceService(ctrl))
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-claimed:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected dispatcher to claim the outbox")
+	}
+
+	cancel()
+	<-done
+}
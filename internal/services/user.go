@@ -1,9 +1,11 @@
 package services
 
 import (
+	"crypto/subtle"
 	"errors"
-	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"tbox_backend/config"
 	"tbox_backend/external"
 	"tbox_backend/internal/constants"
@@ -16,53 +18,106 @@ import (
 )
 
 type IUserService interface {
-	GenerateOtp(phoneNumber string) error
-	ResendOtp(phoneNumber string) error
-	Login(phoneNumber string, otp string) (string, error)
+	GenerateOtp(phoneNumber string, locale string, channel string) (string, error)
+	ResendOtp(phoneNumber string, locale string, channel string) (string, error)
+	Login(phoneNumber string, otp string) (dto.Token, error)
+	GenerateEmailOtp(email string, locale string) error
+	LoginWithEmail(email string, otp string) (dto.Token, error)
+	SendMagicLink(email string, locale string) error
+	LoginWithMagicLink(token string) (dto.Token, error)
+	LoginWithDevice(phoneNumber string, deviceCredential dto.DeviceCredential, ip string) (dto.Token, error)
+	RegisterDevice(user *dto.User, tokenInfo dto.TokenInfo, name string) (dto.DeviceCredential, error)
+	GetDevices(user *dto.User) ([]dto.UserDevice, error)
+	RevokeDevice(user *dto.User, deviceID string) error
+	UpdateSmsDeliveryStatus(report dto.SmsDeliveryReport) error
+	GetOtpStatus(phoneNumber string, statusToken string) (dto.OtpStatus, error)
+	GetOtpCountryStats(day time.Time) ([]dto.OtpCountryStat, error)
+	RefreshToken(refreshToken string) (dto.Token, error)
+	Authenticate(accessToken string) (*dto.User, dto.TokenInfo, error)
+	Logout(tokenInfo dto.TokenInfo, refreshToken string) error
+	LogoutAll(user *dto.User) error
+	GetJwks() dto.Jwks
+	AuthenticateClient(clientID string, clientSecret string) bool
+	IntrospectToken(accessToken string) (dto.TokenInfo, bool, error)
 }
 
 type UserService struct {
-	cfg              config.Config
-	smsService       external.ISmsService
-	userValidator    validator.IUserValidator
-	userOtpValidator validator.IUserOtpValidator
-	userOtpCommon    helpers.IUserOtpHelper
-	userCommon       helpers.IUserHelper
-	userStore        stores.IUserStore
-	userOtpStore     stores.IUserOtpStore
+	cfg                 config.Config
+	userValidator       validator.IUserValidator
+	userOtpValidator    validator.IUserOtpValidator
+	userOtpCommon       helpers.IUserOtpHelper
+	userCommon          helpers.IUserHelper
+	userStore           stores.IUserStore
+	userOtpStore        stores.IUserOtpStore
+	refreshTokenStore   stores.IRefreshTokenStore
+	revokedTokenStore   stores.IRevokedTokenStore
+	userDeviceStore     stores.IUserDeviceStore
+	smsOutboxStore      stores.ISmsOutboxStore
+	emailService        external.IEmailService
+	otpCountryStatStore stores.IOtpCountryStatStore
 }
 
 func NewUserService(
 	cfg config.Config,
-	smsService external.ISmsService,
 	userValidator validator.IUserValidator,
 	userOtpValidator validator.IUserOtpValidator,
 	userOtpCommon helpers.IUserOtpHelper,
 	userCommon helpers.IUserHelper,
 	userStore stores.IUserStore,
 	userOtpStore stores.IUserOtpStore,
+	refreshTokenStore stores.IRefreshTokenStore,
+	revokedTokenStore stores.IRevokedTokenStore,
+	userDeviceStore stores.IUserDeviceStore,
+	smsOutboxStore stores.ISmsOutboxStore,
+	emailService external.IEmailService,
+	otpCountryStatStore stores.IOtpCountryStatStore,
 ) *UserService {
 	return &UserService{
-		cfg:              cfg,
-		smsService:       smsService,
-		userValidator:    userValidator,
-		userOtpValidator: userOtpValidator,
-		userOtpCommon:    userOtpCommon,
-		userCommon:       userCommon,
-		userStore:        userStore,
-		userOtpStore:     userOtpStore,
+		cfg:                 cfg,
+		userValidator:       userValidator,
+		userOtpValidator:    userOtpValidator,
+		userOtpCommon:       userOtpCommon,
+		userCommon:          userCommon,
+		userStore:           userStore,
+		userOtpStore:        userOtpStore,
+		refreshTokenStore:   refreshTokenStore,
+		revokedTokenStore:   revokedTokenStore,
+		userDeviceStore:     userDeviceStore,
+		smsOutboxStore:      smsOutboxStore,
+		emailService:        emailService,
+		otpCountryStatStore: otpCountryStatStore,
 	}
 }
 
-func (s UserService) GenerateOtp(phoneNumber string) error {
+// GenerateOtp sends a new OTP to the phone number through the channel, an SMS or a voice call. locale picks
+// the language of the message, it may be a single tag or an Accept-Language header. Phone numbers are
+// stored and messaged in their E.164 form, so every way of writing a number reaches the same user. The status
+// token of the OTP is returned, GetOtpStatus requires it.
+func (s UserService) GenerateOtp(phoneNumber string, locale string, channel string) (string, error) {
+	if valid := s.userOtpValidator.IsOtpChannelValid(channel); !valid {
+		return "", e.InvalidOtpChannelError{Channel: channel}
+	}
+
+	normalizedPhoneNumber, countryCode, valid := s.userValidator.NormalizePhoneNumber(phoneNumber)
+	if !valid {
+		return "", e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
+	}
+
+	phoneNumber = normalizedPhoneNumber
+	err := s.checkOtpCountryPolicy(phoneNumber, countryCode)
+	if err != nil {
+		return "", err
+	}
+
 	user, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if !exists {
 		user = &dto.User{
 			PhoneNumber: phoneNumber,
+			CountryCode: countryCode,
 			Status:      constants.UserInitStatus,
 			CreatedAt:   time.Now().UTC(),
 			UpdatedAt:   time.Now().UTC(),
@@ -70,139 +125,852 @@ func (s UserService) GenerateOtp(phoneNumber string) error {
 
 		err := s.userStore.Save(user)
 		if err != nil {
-			return err
+			return "", err
 		}
-	} else if user.Status == constants.UserVerifiedStatus {
-		return e.VerifiedPhoneNumberError{PhoneNumber: phoneNumber}
 	}
 
 	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if exists {
 		now := time.Now().UTC()
+		if userOtp.LockedUntil.After(now) {
+			return "", e.LockedPhoneNumberError{PhoneNumber: phoneNumber, LockedUntil: userOtp.LockedUntil}
+		}
+
 		if now.Sub(userOtp.UpdatedAt).Seconds() > float64(s.cfg.Otp.ExpiredTime) {
-			otp := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size)
-			userOtp.Otp = otp
-			userOtp.UpdatedAt = time.Now().UTC()
-			err := s.userOtpStore.UpdateOtp(userOtp)
+			otp, err := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size, s.cfg.Otp.Alphabet)
 			if err != nil {
-				return err
+				return "", err
 			}
 
-			err = s.smsService.SendOtp(phoneNumber, otp)
+			userOtp.OtpHash, userOtp.OtpSalt, err = s.userOtpCommon.HashOtp(otp)
 			if err != nil {
-				log.Println(fmt.Sprintf("Failed to send sms to %s", phoneNumber), err)
+				return "", err
 			}
 
-			return nil
+			userOtp.UpdatedAt = time.Now().UTC()
+			err = s.userOtpStore.UpdateOtp(userOtp, s.otpCountryQuota(countryCode), s.newOtpSms(phoneNumber, locale, channel, otp))
+			if err != nil {
+				return "", s.otpStoreError(phoneNumber, countryCode, err)
+			}
+
+			return s.userOtpCommon.OtpStatusToken(userOtp.OtpSalt), nil
 		} else {
-			return e.GeneratedOtpError{}
+			return "", e.GeneratedOtpError{RetryAfter: otpCooldown(userOtp.UpdatedAt, s.cfg.Otp.ExpiredTime, now)}
 		}
 	} else {
-		otp := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size)
-		err := s.userOtpStore.Save(dto.UserOtp{
+		otp, err := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size, s.cfg.Otp.Alphabet)
+		if err != nil {
+			return "", err
+		}
+
+		otpHash, otpSalt, err := s.userOtpCommon.HashOtp(otp)
+		if err != nil {
+			return "", err
+		}
+
+		err = s.userOtpStore.Save(dto.UserOtp{
 			UserID:    user.ID,
-			Otp:       otp,
+			OtpHash:   otpHash,
+			OtpSalt:   otpSalt,
 			CreatedAt: time.Now().UTC(),
 			UpdatedAt: time.Now().UTC(),
-		})
-
+		}, s.otpCountryQuota(countryCode), s.newOtpSms(phoneNumber, locale, channel, otp))
 		if err != nil {
-			return err
+			return "", s.otpStoreError(phoneNumber, countryCode, err)
 		}
 
-		err = s.smsService.SendOtp(phoneNumber, otp)
-		if err != nil {
-			log.Println(fmt.Sprintf("Failed to send sms to %s", phoneNumber), err)
+		return s.userOtpCommon.OtpStatusToken(otpSalt), nil
+	}
+}
+
+// checkOtpCountryPolicy refuses the phone numbers the OTP country policy does not send OTPs to, refusals are
+// counted in the stats of the country.
+func (s UserService) checkOtpCountryPolicy(phoneNumber string, countryCode string) error {
+	policy := s.cfg.Otp.CountryPolicy
+	reason := ""
+	if (len(policy.AllowedCountries) > 0 && !containsCountry(policy.AllowedCountries, countryCode)) ||
+		containsCountry(policy.BlockedCountries, countryCode) {
+		reason = constants.OtpCountryNotAllowedReason
+	}
+
+	for _, prefix := range policy.BlockedPrefixes {
+		// Prefixes may be written with or without the + of E.164.
+		if reason == "" && strings.HasPrefix(phoneNumber[1:], strings.TrimPrefix(prefix, "+")) {
+			reason = constants.OtpPrefixBlockedReason
 		}
+	}
 
+	if reason == "" {
 		return nil
 	}
+
+	err := s.otpCountryStatStore.IncreaseBlocked(countryCode, time.Now().UTC())
+	if err != nil {
+		log.Printf("Could not count blocked OTP of %s: %v", countryCode, err)
+	}
+
+	return e.BlockedPhoneNumberError{PhoneNumber: phoneNumber, Reason: reason}
 }
 
-func (s UserService) ResendOtp(phoneNumber string) error {
+// otpCountryQuota is the daily limit of the country an OTP is counted against when it is stored. OTPs to countries
+// without a limit are counted too, they are only never capped.
+func (s UserService) otpCountryQuota(countryCode string) dto.OtpCountryQuota {
+	return dto.OtpCountryQuota{
+		CountryCode: countryCode,
+		Day:         time.Now().UTC(),
+		Limit:       s.cfg.Otp.CountryPolicy.DailyLimit(countryCode),
+	}
+}
+
+// otpStoreError refuses the phone number of an OTP which was not stored because its country has been sent its
+// daily limit, the refusal is counted in the stats of the country.
+func (s UserService) otpStoreError(phoneNumber string, countryCode string, err error) error {
+	if err != stores.ErrOtpDailyLimitReached {
+		return err
+	}
+
+	err = s.otpCountryStatStore.IncreaseCapped(countryCode, time.Now().UTC())
+	if err != nil {
+		log.Printf("Could not count capped OTP of %s: %v", countryCode, err)
+	}
+
+	return e.BlockedPhoneNumberError{PhoneNumber: phoneNumber, Reason: constants.OtpDailyLimitReason}
+}
+
+// otpCooldown is the time left before an OTP sent at updatedAt can be replaced, waitingTime is in seconds.
+func otpCooldown(updatedAt time.Time, waitingTime int, now time.Time) time.Duration {
+	return updatedAt.Add(time.Duration(waitingTime) * time.Second).Sub(now)
+}
+
+func containsCountry(countryCodes []string, countryCode string) bool {
+	for _, code := range countryCodes {
+		if strings.EqualFold(code, countryCode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newOtpSms queues the OTP message. It is sent by the SmsDispatcher once the OTP is stored.
+func (s UserService) newOtpSms(phoneNumber string, locale string, channel string, otp string) dto.SmsOutbox {
+	content := s.userOtpCommon.OtpMessage(locale, constants.OtpLoginPurpose, otp)
+	if channel == constants.OtpVoiceChannel {
+		content = s.userOtpCommon.OtpSpeech(locale, constants.OtpVoiceLoginPurpose, otp)
+	} else {
+		channel = constants.OtpSmsChannel
+	}
+
+	now := time.Now().UTC()
+	return dto.SmsOutbox{
+		PhoneNumber:    phoneNumber,
+		Channel:        channel,
+		Content:        content,
+		Status:         constants.SmsOutboxPendingStatus,
+		DeliveryStatus: external.SmsStatusQueued,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// ResendOtp replaces the OTP of the phone number once its resend waiting time is over, it returns the status token
+// of the new OTP.
+func (s UserService) ResendOtp(phoneNumber string, locale string, channel string) (string, error) {
+	if valid := s.userOtpValidator.IsOtpChannelValid(channel); !valid {
+		return "", e.InvalidOtpChannelError{Channel: channel}
+	}
+
+	normalizedPhoneNumber, countryCode, valid := s.userValidator.NormalizePhoneNumber(phoneNumber)
+	if !valid {
+		return "", e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
+	}
+
+	phoneNumber = normalizedPhoneNumber
+	err := s.checkOtpCountryPolicy(phoneNumber, countryCode)
+	if err != nil {
+		return "", err
+	}
+
 	user, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
 	if err != nil {
-		return err
+		return "", err
 	} else if !exists {
-		return e.NotExistsPhoneNumberError{PhoneNumber: phoneNumber}
-	} else if user.Status == constants.UserVerifiedStatus {
-		return e.VerifiedPhoneNumberError{PhoneNumber: phoneNumber}
+		return "", e.NotExistsPhoneNumberError{PhoneNumber: phoneNumber}
 	}
 
 	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
 	if err != nil {
-		return err
+		return "", err
 	} else if !exists {
-		return errors.New("Could not resend OTP ")
+		return "", errors.New("Could not resend OTP ")
 	}
 
 	now := time.Now().UTC()
-	if now.Sub(userOtp.UpdatedAt).Seconds() > float64(s.cfg.Otp.ResendWaitingTime) {
-		otp := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size)
-		userOtp.Otp = otp
-		userOtp.UpdatedAt = time.Now().UTC()
-		err := s.userOtpStore.UpdateOtp(userOtp)
+	if userOtp.LockedUntil.After(now) {
+		return "", e.LockedPhoneNumberError{PhoneNumber: phoneNumber, LockedUntil: userOtp.LockedUntil}
+	}
+
+	resendWaitingTime := s.cfg.Otp.ResendWaitingTime
+	if channel == constants.OtpVoiceChannel {
+		resendWaitingTime = s.cfg.Otp.VoiceResendWaitingTime
+	}
+
+	if now.Sub(userOtp.UpdatedAt).Seconds() > float64(resendWaitingTime) {
+		otp, err := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size, s.cfg.Otp.Alphabet)
 		if err != nil {
-			return err
+			return "", err
 		}
 
-		err = s.smsService.SendOtp(phoneNumber, otp)
+		userOtp.OtpHash, userOtp.OtpSalt, err = s.userOtpCommon.HashOtp(otp)
 		if err != nil {
-			log.Println(fmt.Sprintf("Failed to send sms to %s", phoneNumber), err)
+			return "", err
 		}
 
-		return nil
+		userOtp.UpdatedAt = time.Now().UTC()
+		err = s.userOtpStore.UpdateOtp(userOtp, s.otpCountryQuota(countryCode), s.newOtpSms(phoneNumber, locale, channel, otp))
+		if err != nil {
+			return "", s.otpStoreError(phoneNumber, countryCode, err)
+		}
+
+		return s.userOtpCommon.OtpStatusToken(userOtp.OtpSalt), nil
 	} else {
-		return e.GeneratedOtpError{}
+		return "", e.GeneratedOtpError{RetryAfter: otpCooldown(userOtp.UpdatedAt, resendWaitingTime, now)}
 	}
 }
 
-func (s UserService) Login(phoneNumber string, otp string) (string, error) {
-	if valid := s.userValidator.IsPhoneNumberValid(phoneNumber); !valid {
-		return "", e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
+func (s UserService) Login(phoneNumber string, otp string) (dto.Token, error) {
+	normalizedPhoneNumber, _, valid := s.userValidator.NormalizePhoneNumber(phoneNumber)
+	if !valid {
+		return dto.Token{}, e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
 	}
 
+	phoneNumber = normalizedPhoneNumber
+
 	user, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
 	if err != nil {
-		return "", err
+		return dto.Token{}, err
 	} else if !exists {
-		return "", e.NotExistsPhoneNumberError{PhoneNumber: phoneNumber}
-	} else if user.Status == constants.UserVerifiedStatus {
-		token, _ := s.userCommon.GenerateToken(user.ID)
-		return token, nil
+		return dto.Token{}, e.NotExistsPhoneNumberError{PhoneNumber: phoneNumber}
 	}
 
-	if valid := s.userOtpValidator.IsOtpValid(otp, s.cfg.Otp.Size); !valid {
-		return "", e.InvalidOtpError{Otp: otp}
+	return s.loginWithOtp(user, otp, func(lockedUntil time.Time) error {
+		return e.LockedPhoneNumberError{PhoneNumber: phoneNumber, LockedUntil: lockedUntil}
+	})
+}
+
+// loginWithOtp verifies the OTP of the user, whichever channel it was sent through. lockedError reports
+// the lock in terms of the phone number or the email the user logs in with.
+func (s UserService) loginWithOtp(user *dto.User, otp string, lockedError func(lockedUntil time.Time) error) (dto.Token, error) {
+	// Alphanumeric OTPs are upper case, accept them however the user typed them.
+	otp = strings.ToUpper(otp)
+	if valid := s.userOtpValidator.IsOtpValid(otp, s.cfg.Otp.Size, s.cfg.Otp.Alphabet); !valid {
+		return dto.Token{}, e.InvalidOtpError{Otp: otp}
 	}
 
 	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
 	if err != nil {
-		return "", err
+		return dto.Token{}, err
 	} else if !exists {
-		return "", e.IncorrectOtpError{Otp: otp}
+		return dto.Token{}, e.IncorrectOtpError{Otp: otp}
 	}
 
 	now := time.Now().UTC()
+	if userOtp.LockedUntil.After(now) {
+		return dto.Token{}, lockedError(userOtp.LockedUntil)
+	} else if s.cfg.Otp.MaxAttempts > 0 && userOtp.FailedAttempts >= s.cfg.Otp.MaxAttempts {
+		return dto.Token{}, e.TooManyOtpAttemptsError{}
+	}
+
 	if now.Sub(userOtp.UpdatedAt).Seconds() <= float64(s.cfg.Otp.ExpiredTime) {
-		if otp == userOtp.Otp {
-			user.Status = constants.UserVerifiedStatus
-			user.UpdatedAt = time.Now().UTC()
-			err := s.userStore.UpdateStatus(user)
+		if s.userOtpCommon.VerifyOtp(otp, userOtp.OtpHash, userOtp.OtpSalt) {
+			// The OTP can only be used once, concurrent logins with the same OTP are refused but one.
+			consumed, err := s.userOtpStore.ConsumeOtp(userOtp)
 			if err != nil {
-				return "", err
+				return dto.Token{}, err
+			} else if !consumed {
+				return dto.Token{}, e.IncorrectOtpError{Otp: otp}
+			}
+
+			err = s.verifyUser(user)
+			if err != nil {
+				return dto.Token{}, err
 			}
 
-			token, _ := s.userCommon.GenerateToken(user.ID)
-			return token, nil
+			return s.issueToken(user, true)
 		} else {
-			return "", e.IncorrectOtpError{Otp: otp}
+			return dto.Token{}, s.failOtpAttempt(userOtp, otp, lockedError)
 		}
 	} else {
-		return "", e.ExpiredOtpError{Otp: otp}
+		return dto.Token{}, e.ExpiredOtpError{Otp: otp}
+	}
+}
+
+// verifyUser marks the user verified on their first login.
+func (s UserService) verifyUser(user *dto.User) error {
+	if user.Status == constants.UserVerifiedStatus {
+		return nil
+	}
+
+	user.Status = constants.UserVerifiedStatus
+	user.UpdatedAt = time.Now().UTC()
+	return s.userStore.UpdateStatus(user)
+}
+
+// GenerateEmailOtp sends an OTP to the email, creating the user on first use. The OTP, its attempts and
+// the lock are shared with the phone channels of the same user.
+func (s UserService) GenerateEmailOtp(email string, locale string) error {
+	if valid := s.userValidator.IsEmailValid(email); !valid {
+		return e.InvalidEmailError{Email: email}
+	}
+
+	user, err := s.getOrCreateUserByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if exists && userOtp.LockedUntil.After(now) {
+		return e.LockedEmailError{Email: email, LockedUntil: userOtp.LockedUntil}
+	} else if exists && now.Sub(userOtp.UpdatedAt).Seconds() <= float64(s.cfg.Otp.ResendWaitingTime) {
+		return e.GeneratedOtpError{RetryAfter: otpCooldown(userOtp.UpdatedAt, s.cfg.Otp.ResendWaitingTime, now)}
+	}
+
+	otp, err := s.userOtpCommon.GenerateRandomOtp(s.cfg.Otp.Size, s.cfg.Otp.Alphabet)
+	if err != nil {
+		return err
+	}
+
+	otpHash, otpSalt, err := s.userOtpCommon.HashOtp(otp)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		userOtp.OtpHash, userOtp.OtpSalt = otpHash, otpSalt
+		userOtp.UpdatedAt = now
+		err = s.userOtpStore.UpdateOtp(userOtp, dto.OtpCountryQuota{})
+	} else {
+		err = s.userOtpStore.Save(dto.UserOtp{
+			UserID:    user.ID,
+			OtpHash:   otpHash,
+			OtpSalt:   otpSalt,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, dto.OtpCountryQuota{})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	subject, body := s.userOtpCommon.OtpEmail(locale, constants.OtpEmailLoginPurpose, otp)
+	return s.emailService.Send(email, subject, body)
+}
+
+func (s UserService) LoginWithEmail(email string, otp string) (dto.Token, error) {
+	if valid := s.userValidator.IsEmailValid(email); !valid {
+		return dto.Token{}, e.InvalidEmailError{Email: email}
+	}
+
+	user, exists, err := s.userStore.GetByEmail(email)
+	if err != nil {
+		return dto.Token{}, err
+	} else if !exists {
+		return dto.Token{}, e.NotExistsEmailError{Email: email}
+	}
+
+	return s.loginWithOtp(user, otp, func(lockedUntil time.Time) error {
+		return e.LockedEmailError{Email: email, LockedUntil: lockedUntil}
+	})
+}
+
+// SendMagicLink emails a link which logs the user in once, creating the user on first use.
+func (s UserService) SendMagicLink(email string, locale string) error {
+	if valid := s.userValidator.IsEmailValid(email); !valid {
+		return e.InvalidEmailError{Email: email}
+	}
+
+	user, err := s.getOrCreateUserByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	} else if exists && userOtp.LockedUntil.After(time.Now().UTC()) {
+		return e.LockedEmailError{Email: email, LockedUntil: userOtp.LockedUntil}
+	}
+
+	token, err := s.userCommon.GenerateMagicLinkToken(user.ID)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.cfg.EmailService.MagicLinkUrl)
+	if err != nil {
+		return err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	subject, body := s.userOtpCommon.MagicLinkEmail(locale, link.String(), s.cfg.Token.MagicLinkExpiredTime)
+	return s.emailService.Send(email, subject, body)
+}
+
+// LoginWithMagicLink exchanges the token of a magic link for an access token. The token is revoked
+// on first use, so a link can not log in twice.
+func (s UserService) LoginWithMagicLink(token string) (dto.Token, error) {
+	tokenInfo, err := s.userCommon.ParseMagicLinkToken(token)
+	if err != nil {
+		return dto.Token{}, e.InvalidMagicLinkError{}
+	}
+
+	consumed, err := s.revokedTokenStore.Consume(dto.RevokedToken{
+		TokenID:   tokenInfo.ID,
+		UserID:    tokenInfo.UserID,
+		ExpiredAt: tokenInfo.ExpiredAt,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if err != nil {
+		return dto.Token{}, err
+	} else if !consumed {
+		return dto.Token{}, e.InvalidMagicLinkError{}
+	}
+
+	user, exists, err := s.userStore.GetByID(tokenInfo.UserID)
+	if err != nil {
+		return dto.Token{}, err
+	} else if !exists {
+		return dto.Token{}, e.InvalidMagicLinkError{}
 	}
+
+	err = s.verifyUser(user)
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	return s.issueToken(user, false)
+}
+
+func (s UserService) getOrCreateUserByEmail(email string) (*dto.User, error) {
+	user, exists, err := s.userStore.GetByEmail(email)
+	if err != nil || exists {
+		return user, err
+	}
+
+	user = &dto.User{
+		Email:     email,
+		Status:    constants.UserInitStatus,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	err = s.userStore.Save(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LoginWithDevice lets a user log in again without an OTP from a device registered after an OTP login.
+func (s UserService) LoginWithDevice(phoneNumber string, deviceCredential dto.DeviceCredential, ip string) (dto.Token, error) {
+	normalizedPhoneNumber, _, valid := s.userValidator.NormalizePhoneNumber(phoneNumber)
+	if !valid {
+		return dto.Token{}, e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
+	}
+
+	phoneNumber = normalizedPhoneNumber
+
+	user, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
+	if err != nil {
+		return dto.Token{}, err
+	} else if !exists {
+		return dto.Token{}, e.NotExistsPhoneNumberError{PhoneNumber: phoneNumber}
+	}
+
+	if deviceCredential.DeviceID == "" || deviceCredential.DeviceSecret == "" {
+		return dto.Token{}, e.InvalidDeviceCredentialError{}
+	}
+
+	userDevice, exists, err := s.userDeviceStore.GetByDeviceID(deviceCredential.DeviceID)
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	secretHash := s.userCommon.HashDeviceSecret(deviceCredential.DeviceSecret)
+	if !exists ||
+		userDevice.Revoked ||
+		userDevice.UserID != user.ID ||
+		subtle.ConstantTimeCompare([]byte(secretHash), []byte(userDevice.SecretHash)) != 1 {
+		return dto.Token{}, e.InvalidDeviceCredentialError{}
+	}
+
+	userDevice.LastUsedAt = time.Now().UTC()
+	userDevice.LastUsedIP = ip
+	userDevice.UpdatedAt = userDevice.LastUsedAt
+	err = s.userDeviceStore.UpdateLastUsed(userDevice)
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	return s.issueToken(user, false)
+}
+
+// RegisterDevice trusts a device of the user. The device logs in without an OTP from then on, so it is only
+// registered with the access token of an OTP login of the last DeviceRegistrationTime seconds.
+func (s UserService) RegisterDevice(user *dto.User, tokenInfo dto.TokenInfo, name string) (dto.DeviceCredential, error) {
+	registrationTime := time.Duration(s.cfg.Token.DeviceRegistrationTime) * time.Second
+	if !tokenInfo.OtpVerified || time.Now().UTC().Sub(tokenInfo.IssuedAt) > registrationTime {
+		return dto.DeviceCredential{}, e.OtpLoginRequiredError{}
+	}
+
+	deviceCredential, err := s.userCommon.GenerateDeviceCredential()
+	if err != nil {
+		return dto.DeviceCredential{}, err
+	}
+
+	err = s.userDeviceStore.Save(dto.UserDevice{
+		DeviceID:   deviceCredential.DeviceID,
+		UserID:     user.ID,
+		Name:       name,
+		SecretHash: s.userCommon.HashDeviceSecret(deviceCredential.DeviceSecret),
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	})
+
+	if err != nil {
+		return dto.DeviceCredential{}, err
+	}
+
+	return deviceCredential, nil
+}
+
+func (s UserService) GetDevices(user *dto.User) ([]dto.UserDevice, error) {
+	return s.userDeviceStore.GetByUserID(user.ID)
+}
+
+func (s UserService) RevokeDevice(user *dto.User, deviceID string) error {
+	revoked, err := s.userDeviceStore.Revoke(user.ID, deviceID)
+	if err != nil {
+		return err
+	} else if !revoked {
+		return e.NotExistsDeviceError{DeviceID: deviceID}
+	}
+
+	return nil
+}
+
+// smsDeliveryRanks orders delivery statuses, so reports arriving out of order can not move a message back.
+var smsDeliveryRanks = map[string]int{
+	external.SmsStatusQueued:    1,
+	external.SmsStatusSent:      2,
+	external.SmsStatusDelivered: 3,
+	external.SmsStatusFailed:    3,
+}
+
+// UpdateSmsDeliveryStatus applies a verified delivery report. Reports for unknown messages are ignored,
+// so the provider does not keep retrying them.
+func (s UserService) UpdateSmsDeliveryStatus(report dto.SmsDeliveryReport) error {
+	smsOutbox, exists, err := s.smsOutboxStore.GetByProviderMessageID(report.Provider, report.MessageID)
+	if err != nil {
+		return err
+	} else if !exists {
+		log.Printf("Ignored delivery report of unknown sms %s from %s", report.MessageID, report.Provider)
+		return nil
+	} else if smsDeliveryRanks[report.Status] <= smsDeliveryRanks[smsOutbox.DeliveryStatus] {
+		return nil
+	}
+
+	smsOutbox.DeliveryStatus = report.Status
+	smsOutbox.UpdatedAt = time.Now().UTC()
+	return s.smsOutboxStore.UpdateDeliveryStatus(smsOutbox)
+}
+
+// GetOtpStatus returns the delivery status of the latest OTP sent to the phone number. Only whoever requested
+// the pending OTP of the number holds its status token, any other request is told no OTP was sent, so the status
+// cannot be used to find out which numbers receive OTPs.
+func (s UserService) GetOtpStatus(phoneNumber string, statusToken string) (dto.OtpStatus, error) {
+	normalizedPhoneNumber, _, valid := s.userValidator.NormalizePhoneNumber(phoneNumber)
+	if !valid {
+		return dto.OtpStatus{}, e.InvalidPhoneNumberError{PhoneNumber: phoneNumber}
+	}
+
+	phoneNumber = normalizedPhoneNumber
+
+	user, exists, err := s.userStore.GetByPhoneNumber(phoneNumber)
+	if err != nil {
+		return dto.OtpStatus{}, err
+	} else if !exists {
+		return dto.OtpStatus{}, e.NotSentOtpError{PhoneNumber: phoneNumber}
+	}
+
+	userOtp, exists, err := s.userOtpStore.GetByUserID(user.ID)
+	if err != nil {
+		return dto.OtpStatus{}, err
+	} else if !exists || !s.userOtpCommon.VerifyOtpStatusToken(statusToken, userOtp.OtpSalt) {
+		return dto.OtpStatus{}, e.NotSentOtpError{PhoneNumber: phoneNumber}
+	}
+
+	smsOutbox, exists, err := s.smsOutboxStore.GetLatestByPhoneNumber(phoneNumber)
+	if err != nil {
+		return dto.OtpStatus{}, err
+	} else if !exists {
+		return dto.OtpStatus{}, e.NotSentOtpError{PhoneNumber: phoneNumber}
+	}
+
+	// ResendOtp waits for the waiting time of the channel an OTP is resent through, the status assumes the channel
+	// of the latest message.
+	resendWaitingTime := s.cfg.Otp.ResendWaitingTime
+	if smsOutbox.Channel == constants.OtpVoiceChannel {
+		resendWaitingTime = s.cfg.Otp.VoiceResendWaitingTime
+	}
+
+	return dto.OtpStatus{
+		DeliveryStatus:    smsOutbox.DeliveryStatus,
+		SentAt:            smsOutbox.CreatedAt,
+		UpdatedAt:         smsOutbox.UpdatedAt,
+		ResendAvailableAt: userOtp.UpdatedAt.Add(time.Duration(resendWaitingTime) * time.Second),
+	}, nil
+}
+
+// GetOtpCountryStats returns the OTP counters of every country on the UTC day.
+func (s UserService) GetOtpCountryStats(day time.Time) ([]dto.OtpCountryStat, error) {
+	return s.otpCountryStatStore.GetByDay(day)
+}
+
+// failOtpAttempt counts an incorrect OTP. The OTP is invalidated once MaxAttempts is reached and the phone
+// number or the email is locked for LockTime seconds after MaxInvalidations OTPs have been invalidated in a row.
+// Attempts against an OTP which a new one has replaced meanwhile are not counted.
+func (s UserService) failOtpAttempt(userOtp dto.UserOtp, otp string, lockedError func(lockedUntil time.Time) error) error {
+	failedAttempts, counted, err := s.userOtpStore.IncreaseFailedAttempts(userOtp)
+	if err != nil {
+		return err
+	} else if !counted {
+		return e.IncorrectOtpError{Otp: otp}
+	}
+
+	if s.cfg.Otp.MaxAttempts <= 0 || failedAttempts < s.cfg.Otp.MaxAttempts {
+		return e.IncorrectOtpError{Otp: otp}
+	} else if failedAttempts > s.cfg.Otp.MaxAttempts {
+		// A concurrent attempt has already invalidated the OTP.
+		return e.TooManyOtpAttemptsError{}
+	}
+
+	userOtp.FailedAttempts = failedAttempts
+	userOtp.InvalidatedCount++
+	locked := s.cfg.Otp.MaxInvalidations > 0 && userOtp.InvalidatedCount >= s.cfg.Otp.MaxInvalidations
+	if locked {
+		userOtp.InvalidatedCount = 0
+		userOtp.LockedUntil = time.Now().UTC().Add(time.Duration(s.cfg.Otp.LockTime) * time.Second)
+	}
+
+	invalidated, err := s.userOtpStore.InvalidateOtp(userOtp)
+	if err != nil {
+		return err
+	} else if !invalidated {
+		return e.IncorrectOtpError{Otp: otp}
+	} else if locked {
+		return lockedError(userOtp.LockedUntil)
+	}
+
+	return e.TooManyOtpAttemptsError{}
+}
+
+func (s UserService) RefreshToken(refreshToken string) (dto.Token, error) {
+	if refreshToken == "" {
+		return dto.Token{}, e.InvalidRefreshTokenError{}
+	}
+
+	storedToken, exists, err := s.refreshTokenStore.GetByTokenHash(s.userCommon.HashRefreshToken(refreshToken))
+	if err != nil {
+		return dto.Token{}, err
+	} else if !exists {
+		return dto.Token{}, e.InvalidRefreshTokenError{}
+	} else if storedToken.Revoked {
+		// A rotated refresh token is presented again, it may be stolen. Revoke the whole session family.
+		err := s.refreshTokenStore.RevokeByUserID(storedToken.UserID)
+		if err != nil {
+			return dto.Token{}, err
+		}
+
+		return dto.Token{}, e.InvalidRefreshTokenError{}
+	}
+
+	if time.Now().UTC().After(storedToken.ExpiredAt) {
+		return dto.Token{}, e.ExpiredRefreshTokenError{}
+	}
+
+	revoked, err := s.refreshTokenStore.Revoke(storedToken)
+	if err != nil {
+		return dto.Token{}, err
+	} else if !revoked {
+		return dto.Token{}, e.InvalidRefreshTokenError{}
+	}
+
+	user, exists, err := s.userStore.GetByID(storedToken.UserID)
+	if err != nil {
+		return dto.Token{}, err
+	} else if !exists {
+		return dto.Token{}, e.InvalidRefreshTokenError{}
+	}
+
+	return s.issueToken(user, false)
+}
+
+// Authenticate verifies the access token and returns the user it was issued to.
+func (s UserService) Authenticate(accessToken string) (*dto.User, dto.TokenInfo, error) {
+	tokenInfo, err := s.userCommon.ParseToken(accessToken)
+	if err != nil {
+		return nil, dto.TokenInfo{}, err
+	}
+
+	revoked, err := s.revokedTokenStore.Exists(tokenInfo.ID)
+	if err != nil {
+		return nil, dto.TokenInfo{}, err
+	} else if revoked {
+		return nil, dto.TokenInfo{}, e.RevokedTokenError{}
+	}
+
+	user, exists, err := s.userStore.GetByID(tokenInfo.UserID)
+	if err != nil {
+		return nil, dto.TokenInfo{}, err
+	} else if !exists || user.Status != constants.UserVerifiedStatus {
+		return nil, dto.TokenInfo{}, e.InvalidTokenError{}
+	} else if tokenInfo.TokenGeneration < user.TokenGeneration {
+		return nil, dto.TokenInfo{}, e.RevokedTokenError{}
+	}
+
+	return user, tokenInfo, nil
+}
+
+// Logout revokes the access token and, when given, the refresh token of the same session.
+func (s UserService) Logout(tokenInfo dto.TokenInfo, refreshToken string) error {
+	err := s.revokedTokenStore.Save(dto.RevokedToken{
+		TokenID:   tokenInfo.ID,
+		UserID:    tokenInfo.UserID,
+		ExpiredAt: tokenInfo.ExpiredAt,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	storedToken, exists, err := s.refreshTokenStore.GetByTokenHash(s.userCommon.HashRefreshToken(refreshToken))
+	if err != nil {
+		return err
+	} else if !exists || storedToken.UserID != tokenInfo.UserID {
+		return e.InvalidRefreshTokenError{}
+	}
+
+	_, err = s.refreshTokenStore.Revoke(storedToken)
+	return err
+}
+
+// LogoutAll revokes every access token issued to the user so far and all of their refresh tokens.
+func (s UserService) LogoutAll(user *dto.User) error {
+	// Tokens are revoked by generation rather than by issued time, which has a second precision and could not
+	// tell tokens issued in the second of the logout from tokens issued right after it.
+	user.TokenGeneration++
+	user.UpdatedAt = time.Now().UTC()
+	err := s.userStore.IncreaseTokenGeneration(user)
+	if err != nil {
+		return err
+	}
+
+	err = s.refreshTokenStore.RevokeByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	// A compromised device would log in again right after the logout.
+	return s.userDeviceStore.RevokeByUserID(user.ID)
+}
+
+func (s UserService) GetJwks() dto.Jwks {
+	return s.userCommon.Jwks()
+}
+
+// AuthenticateClient checks the credentials of an OAuth client allowed to introspect tokens.
+func (s UserService) AuthenticateClient(clientID string, clientSecret string) bool {
+	if clientID == "" || clientSecret == "" {
+		return false
+	}
+
+	authenticated := false
+	for _, client := range s.cfg.OAuth.Clients {
+		if subtle.ConstantTimeCompare([]byte(client.ClientID), []byte(clientID)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) == 1 {
+			authenticated = true
+		}
+	}
+
+	return authenticated
+}
+
+// IntrospectToken reports whether the access token is active. An error is returned only when
+// the token state could not be determined.
+func (s UserService) IntrospectToken(accessToken string) (dto.TokenInfo, bool, error) {
+	_, tokenInfo, err := s.Authenticate(accessToken)
+	if err != nil {
+		switch err.(type) {
+		case e.InvalidTokenError, e.ExpiredTokenError, e.RevokedTokenError:
+			return dto.TokenInfo{}, false, nil
+		default:
+			return dto.TokenInfo{}, false, err
+		}
+	}
+
+	return tokenInfo, true, nil
+}
+
+// issueToken issues the tokens of a login, otpVerified is set by OTP logins.
+func (s UserService) issueToken(user *dto.User, otpVerified bool) (dto.Token, error) {
+	accessToken, err := s.userCommon.GenerateToken(user.ID, user.TokenGeneration, otpVerified)
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	refreshToken, err := s.userCommon.GenerateRefreshToken()
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	now := time.Now().UTC()
+	err = s.refreshTokenStore.Save(dto.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: s.userCommon.HashRefreshToken(refreshToken),
+		ExpiredAt: now.Add(time.Duration(s.cfg.Token.RefreshExpiredTime) * time.Second),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	if err != nil {
+		return dto.Token{}, err
+	}
+
+	return dto.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.cfg.Token.ExpiredTime,
+	}, nil
 }
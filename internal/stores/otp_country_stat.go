@@ -0,0 +1,155 @@
+package stores
+
+import (
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"time"
+)
+
+// dayFormat is the format of the DATE column day.
+const dayFormat = "2006-01-02"
+
+type IOtpCountryStatStore interface {
+	IncreaseBlocked(countryCode string, day time.Time) error
+	IncreaseCapped(countryCode string, day time.Time) error
+	GetByDay(day time.Time) ([]dto.OtpCountryStat, error)
+	HasSentSince(countryCode string, day time.Time) (bool, error)
+}
+
+// OtpCountryStatStore keeps a row of counters per country and UTC day, shared by every instance of the API
+// so the daily limits of the OTP country policy hold across all of them.
+type OtpCountryStatStore struct {
+	client *sqlx.DB
+}
+
+func NewOtpCountryStatStore(client *sqlx.DB) *OtpCountryStatStore {
+	return &OtpCountryStatStore{client: client}
+}
+
+// IncreaseBlocked counts an OTP refused by the allowed countries, blocked countries or blocked prefixes.
+func (s *OtpCountryStatStore) IncreaseBlocked(countryCode string, day time.Time) error {
+	return s.increase("blocked_count", countryCode, day)
+}
+
+// IncreaseCapped counts an OTP refused by the daily limit of the country.
+func (s *OtpCountryStatStore) IncreaseCapped(countryCode string, day time.Time) error {
+	return s.increase("capped_count", countryCode, day)
+}
+
+func (s *OtpCountryStatStore) GetByDay(day time.Time) ([]dto.OtpCountryStat, error) {
+	query := `
+	SELECT o.country_code,
+	o.day,
+	o.sent_count,
+	o.blocked_count,
+	o.capped_count,
+	o.created_at,
+	o.updated_at
+	FROM otp_country_stats o
+	WHERE o.day = ?
+	ORDER BY o.country_code
+	`
+
+	var otpCountryStatModels []models.OtpCountryStat
+	err := s.client.Select(&otpCountryStatModels, query, day.Format(dayFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	otpCountryStats := make([]dto.OtpCountryStat, 0, len(otpCountryStatModels))
+	for _, otpCountryStatModel := range otpCountryStatModels {
+		otpCountryStats = append(otpCountryStats, otpCountryStatModel.ToDto())
+	}
+
+	return otpCountryStats, nil
+}
+
+// HasSentSince reports whether an OTP was sent to the country on day or after.
+func (s *OtpCountryStatStore) HasSentSince(countryCode string, day time.Time) (bool, error) {
+	query := `
+	SELECT EXISTS(
+		SELECT 1 FROM otp_country_stats o
+		WHERE o.country_code = ? AND o.day >= ? AND o.sent_count > 0
+	)
+	`
+
+	var sent bool
+	err := s.client.Get(&sent, query, countryCode, day.Format(dayFormat))
+	if err != nil {
+		return false, err
+	}
+
+	return sent, nil
+}
+
+// increase adds one to a counter column, the column is never taken from user input.
+func (s *OtpCountryStatStore) increase(column string, countryCode string, day time.Time) error {
+	now := time.Now().UTC()
+	err := ensureOtpCountryStat(s.client, countryCode, day, now)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE otp_country_stats SET ` + column + ` = ` + column + ` + 1, updated_at = ?
+	WHERE country_code = ? AND day = ?
+	`
+
+	_, err = s.client.Exec(query, now, countryCode, day.Format(dayFormat))
+	return err
+}
+
+// ErrOtpDailyLimitReached refuses to store an OTP whose country has already been sent its daily limit.
+var ErrOtpDailyLimitReached = errors.New("OTP daily limit of the country is reached ")
+
+// increaseOtpSent counts the OTP stored in the transaction against the quota of its country. It returns
+// ErrOtpDailyLimitReached without counting it when the country has a limit and it has already been sent.
+// OTPs without a country, sent by email, are not counted.
+func increaseOtpSent(tx *sqlx.Tx, otpCountryQuota dto.OtpCountryQuota) error {
+	if otpCountryQuota.CountryCode == "" {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	err := ensureOtpCountryStat(tx, otpCountryQuota.CountryCode, otpCountryQuota.Day, now)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE otp_country_stats SET sent_count = sent_count + 1, updated_at = ?
+	WHERE country_code = ? AND day = ?
+	`
+	args := []interface{}{now, otpCountryQuota.CountryCode, otpCountryQuota.Day.Format(dayFormat)}
+	if otpCountryQuota.Limit > 0 {
+		query += ` AND sent_count < ?`
+		args = append(args, otpCountryQuota.Limit)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrOtpDailyLimitReached
+	}
+
+	return nil
+}
+
+// ensureOtpCountryStat creates the row of the country and day if it does not exist yet.
+func ensureOtpCountryStat(execer sqlx.Execer, countryCode string, day time.Time, now time.Time) error {
+	query := `
+	INSERT IGNORE INTO otp_country_stats (country_code, day, sent_count, blocked_count, capped_count, created_at, updated_at)
+	VALUES (?, ?, 0, 0, 0, ?, ?)
+	`
+
+	_, err := execer.Exec(query, countryCode, day.Format(dayFormat), now, now)
+	return err
+}
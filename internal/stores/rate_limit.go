@@ -0,0 +1,210 @@
+package stores
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxRefillTime bounds how long a bucket is kept when it never refills, e.g. with a rate of 0. Such a bucket is
+// purged after maxRefillTime like a full one, so a rate of 0 allows burst requests per day rather than for ever.
+const maxRefillTime = 24 * time.Hour
+
+// IRateLimitStore keeps token buckets shared by every instance of the API. A bucket of key holds up to burst
+// tokens, starts full and refills at rate tokens per second. Take also returns the tokens left in the bucket.
+type IRateLimitStore interface {
+	Take(key string, rate float64, burst int) (bool, float64, error)
+	Refund(key string, burst int) error
+	DeleteExpired() (int64, error)
+}
+
+type RateLimitStore struct {
+	client *sqlx.DB
+}
+
+func NewRateLimitStore(client *sqlx.DB) *RateLimitStore {
+	return &RateLimitStore{client: client}
+}
+
+// Take removes a token from the bucket of key. It returns false, leaving the bucket as it is, when the bucket
+// has no token left. Buckets are refilled and taken from in a single statement using the clock of the database,
+// so concurrent requests of several instances cannot take the same token. The tokens left are read afterwards,
+// they are only reported to clients and may already include tokens taken by concurrent requests.
+func (s *RateLimitStore) Take(key string, rate float64, burst int) (bool, float64, error) {
+	allowed, tokens, err := s.take(key, rate, burst)
+	if err == errBucketPurged {
+		// The bucket was full, so purged, between the insert and the update. A new one is created.
+		allowed, tokens, err = s.take(key, rate, burst)
+	}
+
+	return allowed, tokens, err
+}
+
+// errBucketPurged is returned by take when the bucket was purged before it could be taken from.
+var errBucketPurged = errors.New("Rate limit bucket was purged ")
+
+func (s *RateLimitStore) take(key string, rate float64, burst int) (bool, float64, error) {
+	refillTime := refillTime(rate, burst).Microseconds()
+	query := `
+	INSERT IGNORE INTO rate_limits (limiter_key, tokens, updated_at, expired_at)
+	VALUES (?, ?, NOW(6), NOW(6) + INTERVAL ? MICROSECOND)
+	`
+
+	_, err := s.client.Exec(query, key, burst, refillTime)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// Columns are assigned from left to right, tokens is computed from the previous updated_at.
+	query = `
+	UPDATE rate_limits
+	SET tokens = LEAST(?, tokens + TIMESTAMPDIFF(MICROSECOND, updated_at, NOW(6)) * ? / 1000000) - 1,
+	updated_at = NOW(6),
+	expired_at = NOW(6) + INTERVAL ? MICROSECOND
+	WHERE limiter_key = ? AND LEAST(?, tokens + TIMESTAMPDIFF(MICROSECOND, updated_at, NOW(6)) * ? / 1000000) >= 1
+	`
+
+	result, err := s.client.Exec(query, burst, rate, refillTime, key, burst, rate)
+	if err != nil {
+		return false, 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, err
+	}
+
+	query = `
+	SELECT LEAST(?, tokens + TIMESTAMPDIFF(MICROSECOND, updated_at, NOW(6)) * ? / 1000000)
+	FROM rate_limits
+	WHERE limiter_key = ?
+	`
+
+	var tokens float64
+	err = s.client.Get(&tokens, query, burst, rate, key)
+	if err == sql.ErrNoRows {
+		// A bucket purged after it was taken from is full again, one purged before was never refused.
+		if affected > 0 {
+			return true, float64(burst), nil
+		}
+
+		return false, 0, errBucketPurged
+	} else if err != nil {
+		return false, 0, err
+	}
+
+	return affected > 0, tokens, nil
+}
+
+// Refund gives back a token taken from the bucket of key. A purged bucket is full, it has nothing to give back.
+func (s *RateLimitStore) Refund(key string, burst int) error {
+	query := `
+	UPDATE rate_limits SET tokens = LEAST(?, tokens + 1) WHERE limiter_key = ?
+	`
+
+	_, err := s.client.Exec(query, burst, key)
+	return err
+}
+
+// DeleteExpired removes the buckets which are full again, a full bucket behaves like a missing one. Expiry is
+// compared with the clock of the database, the one Take sets it with.
+func (s *RateLimitStore) DeleteExpired() (int64, error) {
+	query := `
+	DELETE FROM rate_limits WHERE expired_at < NOW(6)
+	`
+
+	result, err := s.client.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiredAt time.Time
+}
+
+// MemoryRateLimitStore keeps the buckets in process, it stands in for RateLimitStore in tests and
+// single instance setups.
+type MemoryRateLimitStore struct {
+	mutex   sync.Mutex
+	now     func() time.Time
+	buckets map[string]*memoryBucket
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return NewMemoryRateLimitStoreWithClock(time.Now)
+}
+
+func NewMemoryRateLimitStoreWithClock(now func() time.Time) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{now: now, buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rate float64, burst int) (bool, float64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &memoryBucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens := math.Min(float64(burst), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate)
+	if tokens < 1 {
+		return false, tokens, nil
+	}
+
+	bucket.tokens = tokens - 1
+	bucket.updatedAt = now
+	bucket.expiredAt = now.Add(refillTime(rate, burst))
+	return true, bucket.tokens, nil
+}
+
+func (s *MemoryRateLimitStore) Refund(key string, burst int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if bucket, exists := s.buckets[key]; exists {
+		bucket.tokens = math.Min(float64(burst), bucket.tokens+1)
+	}
+
+	return nil
+}
+
+func (s *MemoryRateLimitStore) DeleteExpired() (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	var deleted int64
+	for key, bucket := range s.buckets {
+		if bucket.expiredAt.Before(now) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// refillTime is the time an empty bucket takes to be full again.
+func refillTime(rate float64, burst int) time.Duration {
+	if rate <= 0 {
+		return maxRefillTime
+	}
+
+	refillTime := time.Duration(float64(burst) / rate * float64(time.Second))
+	if refillTime > maxRefillTime {
+		return maxRefillTime
+	}
+
+	return refillTime
+}
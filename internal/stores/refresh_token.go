@@ -0,0 +1,89 @@
+package stores
+
+import (
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"time"
+)
+
+type IRefreshTokenStore interface {
+	GetByTokenHash(tokenHash string) (dto.RefreshToken, bool, error)
+	Save(refreshToken dto.RefreshToken) error
+	Revoke(refreshToken dto.RefreshToken) (bool, error)
+	RevokeByUserID(userID int) error
+}
+
+type RefreshTokenStore struct {
+	client *sqlx.DB
+}
+
+func NewRefreshTokenStore(client *sqlx.DB) *RefreshTokenStore {
+	return &RefreshTokenStore{client: client}
+}
+
+func (s *RefreshTokenStore) GetByTokenHash(tokenHash string) (dto.RefreshToken, bool, error) {
+	query := `
+	SELECT r.refresh_token_id,
+	r.user_id,
+	r.token_hash,
+	r.revoked,
+	r.expired_at,
+	r.created_at,
+	r.updated_at
+	FROM refresh_tokens r
+	WHERE r.token_hash = ?
+	`
+
+	refreshTokenModel := models.RefreshToken{}
+	err := s.client.Get(&refreshTokenModel, query, tokenHash)
+	if err != nil && err == sql.ErrNoRows {
+		return dto.RefreshToken{}, false, nil
+	} else if err != nil {
+		return dto.RefreshToken{}, false, err
+	} else {
+		return refreshTokenModel.ToDto(), true, nil
+	}
+}
+
+func (s *RefreshTokenStore) Save(refreshToken dto.RefreshToken) error {
+	query := `
+	INSERT INTO refresh_tokens (user_id, token_hash, revoked, expired_at, created_at, updated_at)
+	VALUES (:user_id, :token_hash, :revoked, :expired_at, :created_at, :updated_at)
+	`
+
+	refreshTokenModel := &models.RefreshToken{}
+	refreshTokenModel.FromDto(refreshToken)
+	_, err := s.client.NamedExec(query, refreshTokenModel)
+	return err
+}
+
+// Revoke marks the refresh token as used. It returns false when the token has already been revoked,
+// so two concurrent refresh requests cannot both rotate the same token.
+func (s *RefreshTokenStore) Revoke(refreshToken dto.RefreshToken) (bool, error) {
+	query := `
+	UPDATE refresh_tokens SET revoked = 1, updated_at = ? WHERE refresh_token_id = ? AND revoked = 0
+	`
+
+	result, err := s.client.Exec(query, time.Now().UTC(), refreshToken.ID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *RefreshTokenStore) RevokeByUserID(userID int) error {
+	query := `
+	UPDATE refresh_tokens SET revoked = 1, updated_at = ? WHERE user_id = ? AND revoked = 0
+	`
+
+	_, err := s.client.Exec(query, time.Now().UTC(), userID)
+	return err
+}
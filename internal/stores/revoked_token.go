@@ -0,0 +1,86 @@
+package stores
+
+import (
+	"github.com/jmoiron/sqlx"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"time"
+)
+
+type IRevokedTokenStore interface {
+	Exists(tokenID string) (bool, error)
+	Save(revokedToken dto.RevokedToken) error
+	Consume(revokedToken dto.RevokedToken) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type RevokedTokenStore struct {
+	client *sqlx.DB
+}
+
+func NewRevokedTokenStore(client *sqlx.DB) *RevokedTokenStore {
+	return &RevokedTokenStore{client: client}
+}
+
+func (s *RevokedTokenStore) Exists(tokenID string) (bool, error) {
+	query := `
+	SELECT COUNT(1) FROM revoked_tokens r WHERE r.token_id = ?
+	`
+
+	var count int
+	err := s.client.Get(&count, query, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *RevokedTokenStore) Save(revokedToken dto.RevokedToken) error {
+	query := `
+	INSERT IGNORE INTO revoked_tokens (token_id, user_id, expired_at, created_at)
+	VALUES (:token_id, :user_id, :expired_at, :created_at)
+	`
+
+	revokedTokenModel := &models.RevokedToken{}
+	revokedTokenModel.FromDto(revokedToken)
+	_, err := s.client.NamedExec(query, revokedTokenModel)
+	return err
+}
+
+// Consume revokes a single-use token. It returns false when the token has already been revoked,
+// so two concurrent requests cannot both use it.
+func (s *RevokedTokenStore) Consume(revokedToken dto.RevokedToken) (bool, error) {
+	query := `
+	INSERT IGNORE INTO revoked_tokens (token_id, user_id, expired_at, created_at)
+	VALUES (:token_id, :user_id, :expired_at, :created_at)
+	`
+
+	revokedTokenModel := &models.RevokedToken{}
+	revokedTokenModel.FromDto(revokedToken)
+	result, err := s.client.NamedExec(query, revokedTokenModel)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteExpired removes rows of tokens which would be rejected by their expiry anyway.
+func (s *RevokedTokenStore) DeleteExpired(now time.Time) (int64, error) {
+	query := `
+	DELETE FROM revoked_tokens WHERE expired_at < ?
+	`
+
+	result, err := s.client.Exec(query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package stores
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"github.com/jmoiron/sqlx"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"time"
+)
+
+type ISmsOutboxStore interface {
+	Claim(now time.Time, limit int, leaseTime time.Duration) ([]dto.SmsOutbox, error)
+	Update(smsOutbox dto.SmsOutbox) (bool, error)
+	GetByProviderMessageID(provider string, messageID string) (dto.SmsOutbox, bool, error)
+	GetLatestByPhoneNumber(phoneNumber string) (dto.SmsOutbox, bool, error)
+	UpdateDeliveryStatus(smsOutbox dto.SmsOutbox) error
+}
+
+type SmsOutboxStore struct {
+	client *sqlx.DB
+}
+
+func NewSmsOutboxStore(client *sqlx.DB) *SmsOutboxStore {
+	return &SmsOutboxStore{client: client}
+}
+
+// Claim leases up to limit pending messages which are due. Claimed messages are pushed to
+// now + leaseTime, so other dispatchers skip them and they are retried if this one dies.
+func (s *SmsOutboxStore) Claim(now time.Time, limit int, leaseTime time.Duration) ([]dto.SmsOutbox, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	claimToken := hex.EncodeToString(buf)
+	query := `
+	UPDATE sms_outbox SET claim_token = ?, next_attempt_at = ?, updated_at = ?
+	WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY next_attempt_at
+	LIMIT ?
+	`
+
+	_, err := s.client.Exec(query, claimToken, now.Add(leaseTime), now, constants.SmsOutboxPendingStatus, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT s.sms_outbox_id,
+	s.phone_number,
+	s.channel,
+	s.content,
+	s.status,
+	s.attempts,
+	s.next_attempt_at,
+	s.last_error,
+	s.provider,
+	s.provider_message_id,
+	s.delivery_status,
+	s.claim_token,
+	s.created_at,
+	s.updated_at
+	FROM sms_outbox s
+	WHERE s.claim_token = ? AND s.status = ?
+	`
+
+	var smsOutboxModels []models.SmsOutbox
+	err = s.client.Select(&smsOutboxModels, query, claimToken, constants.SmsOutboxPendingStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	smsOutboxes := make([]dto.SmsOutbox, 0, len(smsOutboxModels))
+	for _, smsOutboxModel := range smsOutboxModels {
+		smsOutboxes = append(smsOutboxes, smsOutboxModel.ToDto())
+	}
+
+	return smsOutboxes, nil
+}
+
+// Update stores the result of a delivery attempt and releases the claim. It returns false, storing nothing, when
+// the lease of the claim was lost: the message was claimed again by another dispatcher after it expired.
+func (s *SmsOutboxStore) Update(smsOutbox dto.SmsOutbox) (bool, error) {
+	query := `
+	UPDATE sms_outbox SET content = :content, status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at,
+	last_error = :last_error, provider = :provider, provider_message_id = :provider_message_id,
+	delivery_status = :delivery_status, claim_token = '', updated_at = :updated_at
+	WHERE sms_outbox_id = :sms_outbox_id AND claim_token = :claim_token
+	`
+
+	smsOutboxModel := &models.SmsOutbox{}
+	smsOutboxModel.FromDto(smsOutbox)
+	result, err := s.client.NamedExec(query, smsOutboxModel)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *SmsOutboxStore) GetByProviderMessageID(provider string, messageID string) (dto.SmsOutbox, bool, error) {
+	query := `
+	SELECT s.sms_outbox_id,
+	s.phone_number,
+	s.channel,
+	s.status,
+	s.attempts,
+	s.next_attempt_at,
+	s.last_error,
+	s.provider,
+	s.provider_message_id,
+	s.delivery_status,
+	s.created_at,
+	s.updated_at
+	FROM sms_outbox s
+	WHERE s.provider = ? AND s.provider_message_id = ?
+	`
+
+	return s.get(query, provider, messageID)
+}
+
+func (s *SmsOutboxStore) GetLatestByPhoneNumber(phoneNumber string) (dto.SmsOutbox, bool, error) {
+	query := `
+	SELECT s.sms_outbox_id,
+	s.phone_number,
+	s.channel,
+	s.status,
+	s.attempts,
+	s.next_attempt_at,
+	s.last_error,
+	s.provider,
+	s.provider_message_id,
+	s.delivery_status,
+	s.created_at,
+	s.updated_at
+	FROM sms_outbox s
+	WHERE s.phone_number = ?
+	ORDER BY s.sms_outbox_id DESC
+	LIMIT 1
+	`
+
+	return s.get(query, phoneNumber)
+}
+
+func (s *SmsOutboxStore) UpdateDeliveryStatus(smsOutbox dto.SmsOutbox) error {
+	query := `
+	UPDATE sms_outbox SET delivery_status = :delivery_status, updated_at = :updated_at WHERE sms_outbox_id = :sms_outbox_id
+	`
+
+	smsOutboxModel := &models.SmsOutbox{}
+	smsOutboxModel.FromDto(smsOutbox)
+	_, err := s.client.NamedExec(query, smsOutboxModel)
+	return err
+}
+
+func (s *SmsOutboxStore) get(query string, args ...interface{}) (dto.SmsOutbox, bool, error) {
+	smsOutboxModel := models.SmsOutbox{}
+	err := s.client.Get(&smsOutboxModel, query, args...)
+	if err != nil && err == sql.ErrNoRows {
+		return dto.SmsOutbox{}, false, nil
+	} else if err != nil {
+		return dto.SmsOutbox{}, false, err
+	} else {
+		return smsOutboxModel.ToDto(), true, nil
+	}
+}
+
+// saveSmsOutbox queues a message inside the transaction of the change which triggers it.
+func saveSmsOutbox(tx *sqlx.Tx, smsOutbox dto.SmsOutbox) error {
+	query := `
+	INSERT INTO sms_outbox (phone_number, channel, content, status, attempts, next_attempt_at, last_error, delivery_status, created_at, updated_at)
+	VALUES (:phone_number, :channel, :content, :status, :attempts, :next_attempt_at, :last_error, :delivery_status, :created_at, :updated_at)
+	`
+
+	smsOutboxModel := &models.SmsOutbox{}
+	smsOutboxModel.FromDto(smsOutbox)
+	_, err := tx.NamedExec(query, smsOutboxModel)
+	return err
+}
@@ -10,10 +10,15 @@ import (
 
 type IUserStore interface {
 	GetByPhoneNumber(phoneNo string) (*dto.User, bool, error)
+	GetByEmail(email string) (*dto.User, bool, error)
+	GetByID(userID int) (*dto.User, bool, error)
 	Save(user *dto.User) error
 	UpdateStatus(user *dto.User) error
+	IncreaseTokenGeneration(user *dto.User) error
 }
 
+// UserStore keeps a user reachable by phone number or email, either may be empty. Phone numbers are stored
+// in E.164. Empty values are stored as NULL, so the unique keys only apply to values which are set.
 type UserStore struct {
 	client *sqlx.DB
 }
@@ -25,8 +30,11 @@ func NewUserStore(client *sqlx.DB) *UserStore {
 func (s *UserStore) GetByPhoneNumber(phoneNo string) (*dto.User, bool, error) {
 	query := `
 	SELECT u.user_id,
-	u.phone_number,
+	COALESCE(u.phone_number, '') AS phone_number,
+	COALESCE(u.country_code, '') AS country_code,
+	COALESCE(u.email, '') AS email,
 	u.status,
+	u.token_generation,
 	u.created_at,
 	u.updated_at
 	FROM users u
@@ -45,10 +53,62 @@ func (s *UserStore) GetByPhoneNumber(phoneNo string) (*dto.User, bool, error) {
 	}
 }
 
+func (s *UserStore) GetByEmail(email string) (*dto.User, bool, error) {
+	query := `
+	SELECT u.user_id,
+	COALESCE(u.phone_number, '') AS phone_number,
+	COALESCE(u.country_code, '') AS country_code,
+	COALESCE(u.email, '') AS email,
+	u.status,
+	u.token_generation,
+	u.created_at,
+	u.updated_at
+	FROM users u
+	WHERE u.email = ?
+	`
+
+	userModel := models.User{}
+	err := s.client.Get(&userModel, query, email)
+	if err != nil && err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, true, err
+	} else {
+		userDto := userModel.ToDto()
+		return &userDto, true, nil
+	}
+}
+
+func (s *UserStore) GetByID(userID int) (*dto.User, bool, error) {
+	query := `
+	SELECT u.user_id,
+	COALESCE(u.phone_number, '') AS phone_number,
+	COALESCE(u.country_code, '') AS country_code,
+	COALESCE(u.email, '') AS email,
+	u.status,
+	u.token_generation,
+	u.created_at,
+	u.updated_at
+	FROM users u
+	WHERE u.user_id = ?
+	`
+
+	userModel := models.User{}
+	err := s.client.Get(&userModel, query, userID)
+	if err != nil && err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, true, err
+	} else {
+		userDto := userModel.ToDto()
+		return &userDto, true, nil
+	}
+}
+
 func (s *UserStore) Save(user *dto.User) error {
 	query := `
-	INSERT INTO users (user_id, phone_number, status, created_at, updated_at) 
-	VALUES (:user_id, :phone_number, :status, :created_at, :updated_at)
+	INSERT INTO users (user_id, phone_number, country_code, email, status, created_at, updated_at) 
+	VALUES (:user_id, NULLIF(:phone_number, ''), NULLIF(:country_code, ''), NULLIF(:email, ''), :status, :created_at, :updated_at)
 	`
 
 	userModel := &models.User{}
@@ -81,3 +141,13 @@ func (s *UserStore) UpdateStatus(user *dto.User) error {
 	_, err := s.client.NamedExec(query, userModel)
 	return err
 }
+
+// IncreaseTokenGeneration revokes every access token issued to the user so far, they carry an older generation.
+func (s *UserStore) IncreaseTokenGeneration(user *dto.User) error {
+	query := `
+	UPDATE users SET token_generation = token_generation + 1, updated_at = ? WHERE user_id = ?
+	`
+
+	_, err := s.client.Exec(query, user.UpdatedAt, user.ID)
+	return err
+}
@@ -0,0 +1,137 @@
+package stores
+
+import (
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"tbox_backend/internal/dto"
+	"tbox_backend/internal/models"
+	"time"
+)
+
+type IUserDeviceStore interface {
+	GetByDeviceID(deviceID string) (dto.UserDevice, bool, error)
+	GetByUserID(userID int) ([]dto.UserDevice, error)
+	Save(userDevice dto.UserDevice) error
+	UpdateLastUsed(userDevice dto.UserDevice) error
+	Revoke(userID int, deviceID string) (bool, error)
+	RevokeByUserID(userID int) error
+}
+
+type UserDeviceStore struct {
+	client *sqlx.DB
+}
+
+func NewUserDeviceStore(client *sqlx.DB) *UserDeviceStore {
+	return &UserDeviceStore{client: client}
+}
+
+func (s *UserDeviceStore) GetByDeviceID(deviceID string) (dto.UserDevice, bool, error) {
+	query := `
+	SELECT u.user_device_id,
+	u.device_id,
+	u.user_id,
+	u.name,
+	u.secret_hash,
+	u.revoked,
+	u.last_used_at,
+	u.last_used_ip,
+	u.created_at,
+	u.updated_at
+	FROM user_devices u
+	WHERE u.device_id = ?
+	`
+
+	userDeviceModel := models.UserDevice{}
+	err := s.client.Get(&userDeviceModel, query, deviceID)
+	if err != nil && err == sql.ErrNoRows {
+		return dto.UserDevice{}, false, nil
+	} else if err != nil {
+		return dto.UserDevice{}, false, err
+	} else {
+		return userDeviceModel.ToDto(), true, nil
+	}
+}
+
+// GetByUserID returns the devices of the user which have not been revoked.
+func (s *UserDeviceStore) GetByUserID(userID int) ([]dto.UserDevice, error) {
+	query := `
+	SELECT u.user_device_id,
+	u.device_id,
+	u.user_id,
+	u.name,
+	u.secret_hash,
+	u.revoked,
+	u.last_used_at,
+	u.last_used_ip,
+	u.created_at,
+	u.updated_at
+	FROM user_devices u
+	WHERE u.user_id = ? AND u.revoked = 0
+	ORDER BY u.user_device_id
+	`
+
+	var userDeviceModels []models.UserDevice
+	err := s.client.Select(&userDeviceModels, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userDevices := make([]dto.UserDevice, 0, len(userDeviceModels))
+	for _, userDeviceModel := range userDeviceModels {
+		userDevices = append(userDevices, userDeviceModel.ToDto())
+	}
+
+	return userDevices, nil
+}
+
+func (s *UserDeviceStore) Save(userDevice dto.UserDevice) error {
+	query := `
+	INSERT INTO user_devices (device_id, user_id, name, secret_hash, revoked, created_at, updated_at)
+	VALUES (:device_id, :user_id, :name, :secret_hash, :revoked, :created_at, :updated_at)
+	`
+
+	userDeviceModel := &models.UserDevice{}
+	userDeviceModel.FromDto(userDevice)
+	_, err := s.client.NamedExec(query, userDeviceModel)
+	return err
+}
+
+func (s *UserDeviceStore) UpdateLastUsed(userDevice dto.UserDevice) error {
+	query := `
+	UPDATE user_devices SET last_used_at = :last_used_at, last_used_ip = :last_used_ip, updated_at = :updated_at
+	WHERE user_device_id = :user_device_id
+	`
+
+	userDeviceModel := &models.UserDevice{}
+	userDeviceModel.FromDto(userDevice)
+	_, err := s.client.NamedExec(query, userDeviceModel)
+	return err
+}
+
+// Revoke returns false when the user has no active device with the given id.
+func (s *UserDeviceStore) Revoke(userID int, deviceID string) (bool, error) {
+	query := `
+	UPDATE user_devices SET revoked = 1, updated_at = ? WHERE user_id = ? AND device_id = ? AND revoked = 0
+	`
+
+	result, err := s.client.Exec(query, time.Now().UTC(), userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *UserDeviceStore) RevokeByUserID(userID int) error {
+	query := `
+	UPDATE user_devices SET revoked = 1, updated_at = ? WHERE user_id = ? AND revoked = 0
+	`
+
+	_, err := s.client.Exec(query, time.Now().UTC(), userID)
+	return err
+}
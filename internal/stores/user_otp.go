@@ -9,8 +9,11 @@ import (
 
 type IUserOtpStore interface {
 	GetByUserID(userID int) (dto.UserOtp, bool, error)
-	Save(userOtp dto.UserOtp) error
-	UpdateOtp(userOtp dto.UserOtp) error
+	Save(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error
+	UpdateOtp(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error
+	IncreaseFailedAttempts(userOtp dto.UserOtp) (int, bool, error)
+	InvalidateOtp(userOtp dto.UserOtp) (bool, error)
+	ConsumeOtp(userOtp dto.UserOtp) (bool, error)
 }
 
 type UserOtpStore struct {
@@ -25,7 +28,11 @@ func (s *UserOtpStore) GetByUserID(userID int) (dto.UserOtp, bool, error) {
 	query := `
 	SELECT u.user_otp_id,
 	u.user_id,
-	u.otp,
+	u.otp_hash,
+	u.otp_salt,
+	u.failed_attempts,
+	u.invalidated_count,
+	u.locked_until,
 	u.created_at,
 	u.updated_at
 	FROM user_otp u
@@ -43,25 +50,134 @@ func (s *UserOtpStore) GetByUserID(userID int) (dto.UserOtp, bool, error) {
 	}
 }
 
-func (s *UserOtpStore) UpdateOtp(userOtp dto.UserOtp) error {
+// UpdateOtp stores a newly generated OTP, resets the failed attempts of the previous one, counts it against
+// the quota of its country and queues the SMS carrying it in the same transaction. An OTP sent by email has
+// no SMS to queue.
+func (s *UserOtpStore) UpdateOtp(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error {
 	query := `
-	UPDATE user_otp SET otp = :otp, updated_at = :updated_at WHERE user_otp_id = :user_otp_id
+	UPDATE user_otp SET otp_hash = :otp_hash, otp_salt = :otp_salt, failed_attempts = 0, updated_at = :updated_at
+	WHERE user_otp_id = :user_otp_id
 	`
 
 	userOtpModel := &models.UserOtp{}
 	userOtpModel.FromDto(userOtp)
-	_, err := s.client.NamedExec(query, userOtpModel)
-	return err
+	return s.storeOtp(query, userOtpModel, otpCountryQuota, smsOutboxes)
 }
 
-func (s *UserOtpStore) Save(userOtp dto.UserOtp) error {
+// IncreaseFailedAttempts atomically counts a wrong OTP and returns the new number of failed attempts,
+// so concurrent guesses can not exceed the limit. It returns false when a new OTP has replaced the one
+// the attempt was checked against.
+func (s *UserOtpStore) IncreaseFailedAttempts(userOtp dto.UserOtp) (int, bool, error) {
+	tx, err := s.client.Beginx()
+	if err != nil {
+		return 0, false, err
+	}
+
+	query := `UPDATE user_otp SET failed_attempts = failed_attempts + 1 WHERE user_otp_id = ? AND otp_hash = ?`
+	result, err := tx.Exec(query, userOtp.ID, userOtp.OtpHash)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	} else if rowsAffected == 0 {
+		return 0, false, tx.Rollback()
+	}
+
+	var failedAttempts int
+	err = tx.Get(&failedAttempts, `SELECT failed_attempts FROM user_otp WHERE user_otp_id = ?`, userOtp.ID)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+
+	return failedAttempts, true, tx.Commit()
+}
+
+// InvalidateOtp clears the OTP and stores its invalidated count and lock only if it is still the stored one,
+// it returns false when a new OTP has replaced it.
+func (s *UserOtpStore) InvalidateOtp(userOtp dto.UserOtp) (bool, error) {
+	query := `
+	UPDATE user_otp SET otp_hash = '', otp_salt = '', invalidated_count = ?, locked_until = ?
+	WHERE user_otp_id = ? AND otp_hash = ?
+	`
+
+	userOtpModel := &models.UserOtp{}
+	userOtpModel.FromDto(userOtp)
+	result, err := s.client.Exec(query, userOtpModel.InvalidatedCount, userOtpModel.LockedUntil, userOtpModel.UserOtpID, userOtpModel.OtpHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ConsumeOtp clears the OTP and its attempts only if it is still the stored one, it returns false when a concurrent
+// login has already used it or a new OTP has replaced it.
+func (s *UserOtpStore) ConsumeOtp(userOtp dto.UserOtp) (bool, error) {
+	query := `
+	UPDATE user_otp SET otp_hash = '', otp_salt = '', failed_attempts = 0, invalidated_count = 0
+	WHERE user_otp_id = ? AND otp_hash = ?
+	`
+
+	result, err := s.client.Exec(query, userOtp.ID, userOtp.OtpHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (s *UserOtpStore) Save(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error {
 	query := `
-	INSERT INTO user_otp (user_id, otp, created_at, updated_at) 
-	VALUES (:user_id, :otp, :created_at, :updated_at)
+	INSERT INTO user_otp (user_id, otp_hash, otp_salt, created_at, updated_at) 
+	VALUES (:user_id, :otp_hash, :otp_salt, :created_at, :updated_at)
 	`
 
 	userOtpModel := &models.UserOtp{}
 	userOtpModel.FromDto(userOtp)
-	_, err := s.client.NamedExec(query, &userOtpModel)
-	return err
+	return s.storeOtp(query, userOtpModel, otpCountryQuota, smsOutboxes)
+}
+
+func (s *UserOtpStore) storeOtp(query string, userOtpModel *models.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes []dto.SmsOutbox) error {
+	tx, err := s.client.Beginx()
+	if err != nil {
+		return err
+	}
+
+	err = increaseOtpSent(tx, otpCountryQuota)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.NamedExec(query, userOtpModel)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for _, smsOutbox := range smsOutboxes {
+		err = saveSmsOutbox(tx, smsOutbox)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
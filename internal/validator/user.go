@@ -1,21 +1,126 @@
 package validator
 
 import (
-	"regexp"
+	"net/mail"
+	"strings"
+	"tbox_backend/config"
 )
 
+const maxEmailSize = 255
+
+// vietnamPhoneNumber keeps accepting Vietnamese mobile numbers when no country is configured.
+var vietnamPhoneNumber = config.PhoneNumber{
+	DefaultRegion: "VN",
+	Countries: []config.PhoneCountry{
+		{Code: "VN", CallingCode: "84", TrunkPrefix: "0", Lengths: []int{9}, MobilePrefixes: []string{"3", "5", "7", "8", "9"}},
+	},
+}
+
+// phoneNumberSeparators are dropped from phone numbers before they are parsed.
+var phoneNumberSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
 type IUserValidator interface {
 	IsPhoneNumberValid(phoneNumber string) bool
+	NormalizePhoneNumber(phoneNumber string) (string, string, bool)
+	IsEmailValid(email string) bool
 }
 
-type UserValidator struct{}
+type UserValidator struct {
+	cfg config.PhoneNumber
+}
+
+func NewUserValidator(cfg config.PhoneNumber) *UserValidator {
+	return &UserValidator{cfg: cfg}
+}
 
-func NewUserValidator() *UserValidator {
-	return &UserValidator{}
+func (v UserValidator) IsPhoneNumberValid(phoneNumber string) bool {
+	_, _, valid := v.NormalizePhoneNumber(phoneNumber)
+	return valid
 }
 
-func (UserValidator) IsPhoneNumberValid(phoneNumber string) bool {
-	regexPhoneNumber := `^(03|05|07|08|09)+([0-9]{8})$`
-	regex := regexp.MustCompile(regexPhoneNumber)
-	return regex.MatchString(phoneNumber)
+// NormalizePhoneNumber returns the E.164 form of a mobile number of a configured country and the code of
+// that country. International numbers start with + or 00, other numbers are national numbers of
+// the default region, dialled with its trunk prefix or its calling code.
+func (v UserValidator) NormalizePhoneNumber(phoneNumber string) (string, string, bool) {
+	cfg := v.cfg
+	if len(cfg.Countries) == 0 {
+		cfg = vietnamPhoneNumber
+	}
+
+	digits := phoneNumberSeparators.Replace(phoneNumber)
+	international := false
+	if strings.HasPrefix(digits, "+") {
+		digits, international = digits[1:], true
+	} else if strings.HasPrefix(digits, "00") {
+		digits, international = digits[2:], true
+	}
+
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", "", false
+	}
+
+	for _, country := range cfg.Countries {
+		var nationalNumbers []string
+		if international || country.Code == cfg.DefaultRegion {
+			if strings.HasPrefix(digits, country.CallingCode) {
+				nationalNumber := digits[len(country.CallingCode):]
+				// Some people keep the trunk prefix after the calling code, e.g. +84 0967...
+				nationalNumbers = append(nationalNumbers, nationalNumber, trimTrunkPrefix(country, nationalNumber))
+			}
+		}
+
+		if !international && country.Code == cfg.DefaultRegion && country.TrunkPrefix != "" &&
+			strings.HasPrefix(digits, country.TrunkPrefix) {
+			nationalNumbers = append(nationalNumbers, digits[len(country.TrunkPrefix):])
+		}
+
+		for _, nationalNumber := range nationalNumbers {
+			if isNationalNumberValid(country, nationalNumber) {
+				return "+" + country.CallingCode + nationalNumber, country.Code, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+func trimTrunkPrefix(country config.PhoneCountry, nationalNumber string) string {
+	if country.TrunkPrefix == "" {
+		return nationalNumber
+	}
+
+	return strings.TrimPrefix(nationalNumber, country.TrunkPrefix)
+}
+
+func isNationalNumberValid(country config.PhoneCountry, nationalNumber string) bool {
+	validLength := false
+	for _, length := range country.Lengths {
+		validLength = validLength || len(nationalNumber) == length
+	}
+
+	if !validLength {
+		return false
+	}
+
+	if len(country.MobilePrefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range country.MobilePrefixes {
+		if strings.HasPrefix(nationalNumber, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsEmailValid accepts a bare address, without a display name or angle brackets.
+func (UserValidator) IsEmailValid(email string) bool {
+	if len(email) > maxEmailSize {
+		return false
+	}
+
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && address.Name == ""
 }
@@ -3,10 +3,12 @@ package validator
 import (
 	"fmt"
 	"regexp"
+	"tbox_backend/internal/constants"
 )
 
 type IUserOtpValidator interface {
-	IsOtpValid(otp string, size int) bool
+	IsOtpValid(otp string, size int, alphabet string) bool
+	IsOtpChannelValid(channel string) bool
 }
 
 type UserOtpValidator struct{}
@@ -15,8 +17,18 @@ func NewUserOtpValidator() *UserOtpValidator {
 	return &UserOtpValidator{}
 }
 
-func (UserOtpValidator) IsOtpValid(otp string, size int) bool {
-	regexPhoneNumber := fmt.Sprintf("^[0-9]{%d}$", size)
-	regex := regexp.MustCompile(regexPhoneNumber)
+func (UserOtpValidator) IsOtpValid(otp string, size int, alphabet string) bool {
+	characters, exists := constants.OtpCharacters(alphabet)
+	if !exists {
+		return false
+	}
+
+	regexOtp := fmt.Sprintf("^[%s]{%d}$", regexp.QuoteMeta(characters), size)
+	regex := regexp.MustCompile(regexOtp)
 	return regex.MatchString(otp)
 }
+
+// IsOtpChannelValid accepts the empty channel, which means sms.
+func (UserOtpValidator) IsOtpChannelValid(channel string) bool {
+	return channel == "" || channel == constants.OtpSmsChannel || channel == constants.OtpVoiceChannel
+}
@@ -1,6 +1,7 @@
 package validator_test
 
 import (
+	"tbox_backend/internal/constants"
 	"tbox_backend/internal/validator"
 	"testing"
 )
@@ -9,7 +10,7 @@ func TestUserOtpValidator_IsOtpValid_Success(t *testing.T) {
 	var userOtpValidator validator.IUserOtpValidator
 	userOtpValidator = validator.UserOtpValidator{}
 
-	if !userOtpValidator.IsOtpValid("123456", 6) {
+	if !userOtpValidator.IsOtpValid("123456", 6, constants.OtpNumericAlphabet) {
 		t.Fatal("expected true")
 	}
 }
@@ -18,14 +19,41 @@ func TestUserOtpValidator_IsOtpValid_Fail(t *testing.T) {
 	var userOtpValidator validator.IUserOtpValidator
 	userOtpValidator = validator.UserOtpValidator{}
 
-	if userOtpValidator.IsOtpValid("12345", 6) {
+	if userOtpValidator.IsOtpValid("12345", 6, constants.OtpNumericAlphabet) {
 		t.Fatal("expected false")
 	}
 
-	if userOtpValidator.IsOtpValid("abc3fa", 6) {
+	if userOtpValidator.IsOtpValid("abc3fa", 6, constants.OtpNumericAlphabet) {
 		t.Fatal("expected false")
 	}
 }
 
+func TestUserOtpValidator_IsOtpValid_Alphanumeric(t *testing.T) {
+	var userOtpValidator validator.IUserOtpValidator
+	userOtpValidator = validator.UserOtpValidator{}
+
+	if !userOtpValidator.IsOtpValid("A2B3C4", 6, constants.OtpAlphanumericAlphabet) {
+		t.Fatal("expected true")
+	}
+
+	if userOtpValidator.IsOtpValid("A0B1C4", 6, constants.OtpAlphanumericAlphabet) {
+		t.Fatal("expected false")
+	}
+
+	if userOtpValidator.IsOtpValid("123456", 6, "unknown") {
+		t.Fatal("expected false")
+	}
+}
+
+func TestUserOtpValidator_IsOtpChannelValid(t *testing.T) {
+	var userOtpValidator validator.IUserOtpValidator
+	userOtpValidator = validator.UserOtpValidator{}
 
+	if !userOtpValidator.IsOtpChannelValid("") || !userOtpValidator.IsOtpChannelValid(constants.OtpSmsChannel) || !userOtpValidator.IsOtpChannelValid(constants.OtpVoiceChannel) {
+		t.Fatal("expected true")
+	}
 
+	if userOtpValidator.IsOtpChannelValid("pigeon") {
+		t.Fatal("expected false")
+	}
+}
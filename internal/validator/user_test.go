@@ -1,6 +1,7 @@
 package validator_test
 
 import (
+	"tbox_backend/config"
 	"tbox_backend/internal/validator"
 	"testing"
 )
@@ -23,7 +24,7 @@ func TestUserValidator_IsPhoneNumberValid_Fail(t *testing.T) {
 		t.Fatal("expected false")
 	}
 
-	if userValidator.IsPhoneNumberValid("+84967471759") {
+	if userValidator.IsPhoneNumberValid("+85967471759") {
 		t.Fatal("expected false")
 	}
 
@@ -31,3 +32,54 @@ func TestUserValidator_IsPhoneNumberValid_Fail(t *testing.T) {
 		t.Fatal("expected false")
 	}
 }
+
+func TestUserValidator_NormalizePhoneNumber(t *testing.T) {
+	userValidator := validator.NewUserValidator(config.PhoneNumber{
+		DefaultRegion: "VN",
+		Countries: []config.PhoneCountry{
+			{Code: "VN", CallingCode: "84", TrunkPrefix: "0", Lengths: []int{9}, MobilePrefixes: []string{"3", "5", "7", "8", "9"}},
+			{Code: "SG", CallingCode: "65", Lengths: []int{8}, MobilePrefixes: []string{"8", "9"}},
+		},
+	})
+
+	expected := []struct {
+		phoneNumber string
+		e164        string
+		countryCode string
+	}{
+		{"0967499577", "+84967499577", "VN"},
+		{"+84967499577", "+84967499577", "VN"},
+		{"84967499577", "+84967499577", "VN"},
+		{"0084 967 499 577", "+84967499577", "VN"},
+		{"+84 (0) 967-499-577", "+84967499577", "VN"},
+		{"+6591234567", "+6591234567", "SG"},
+	}
+
+	for _, test := range expected {
+		e164, countryCode, valid := userValidator.NormalizePhoneNumber(test.phoneNumber)
+		if !valid || e164 != test.e164 || countryCode != test.countryCode {
+			t.Fatalf("expected %s to be %s of %s, got %s of %s", test.phoneNumber, test.e164, test.countryCode, e164, countryCode)
+		}
+	}
+
+	for _, phoneNumber := range []string{"", "+", "0967a99577", "+84267499577", "6591234567", "91234567", "+6561234567", "+14155550100"} {
+		if _, _, valid := userValidator.NormalizePhoneNumber(phoneNumber); valid {
+			t.Fatalf("expected %s to be invalid", phoneNumber)
+		}
+	}
+}
+
+func TestUserValidator_IsEmailValid(t *testing.T) {
+	var userValidator validator.IUserValidator
+	userValidator = validator.UserValidator{}
+
+	if !userValidator.IsEmailValid("user@tbox.vn") {
+		t.Fatal("expected true")
+	}
+
+	for _, email := range []string{"", "user", "User <user@tbox.vn>", "<user@tbox.vn>", "user@tbox.vn\r\nBcc: victim@tbox.vn"} {
+		if userValidator.IsEmailValid(email) {
+			t.Fatalf("expected %q to be invalid", email)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -20,6 +22,7 @@ import (
 	"tbox_backend/internal/stores"
 	"tbox_backend/internal/validator"
 	"tbox_backend/routers"
+	"time"
 )
 
 // @title TBOX Backend API
@@ -53,30 +56,155 @@ func main() {
 
 	_ = migration.Up()
 
-	smsService := external.NewSmsService(cfg.SmsService.Url)
-	userValidator := validator.NewUserValidator()
+	var devSmsInbox *external.DevSmsInbox
+	if cfg.Base.IsDevelopment() {
+		devSmsInbox = external.NewDevSmsInbox(cfg.SmsService.DevInboxSize)
+	}
+
+	smsService, err := external.NewSmsService(cfg.SmsService, devSmsInbox)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expvar.Publish("sms_providers", expvar.Func(func() interface{} {
+		return smsService.ProviderStates()
+	}))
+
+	voiceService, err := external.NewVoiceService(cfg.VoiceService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	emailService, err := external.NewSmtpEmailService(cfg.EmailService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	userValidator := validator.NewUserValidator(cfg.PhoneNumber)
 	userOtpValidator := validator. NewUserOtpValidator()
-	userOtpHelper := helpers.NewUserOtpHelper()
-	userHelper := helpers.NewUserHelper("")
+	// Anyone who reads user_otp could brute-force the hashes of short OTPs offline if the pepper were known.
+	if cfg.Otp.Pepper == "" && cfg.Base.Environment != config.LocalEnvironment {
+		log.Fatal("The OTP pepper must be set outside Local")
+	}
+
+	userOtpHelper := helpers.NewUserOtpHelper(cfg.Otp)
+	// Anyone could forge access tokens signed with a key published in the repository.
+	if len(cfg.Token.Keys) == 0 && cfg.Token.SecretKey == "" && cfg.Base.Environment != config.LocalEnvironment {
+		log.Fatal("The token secret key or keys must be set outside Local")
+	}
+
+	tokenKeySet, err := helpers.NewTokenKeySet(cfg.Token)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	userHelper := helpers.NewUserHelper(cfg.Token, tokenKeySet)
 
 	sqlxDb := sqlx.NewDb(db, "mysql")
 	userStore := stores.NewUserStore(sqlxDb)
 	userOtpStore := stores.NewUserOtpStore(sqlxDb)
+	refreshTokenStore := stores.NewRefreshTokenStore(sqlxDb)
+	revokedTokenStore := stores.NewRevokedTokenStore(sqlxDb)
+	userDeviceStore := stores.NewUserDeviceStore(sqlxDb)
+	smsOutboxStore := stores.NewSmsOutboxStore(sqlxDb)
+	otpCountryStatStore := stores.NewOtpCountryStatStore(sqlxDb)
 
 	userService := services.NewUserService(
 		cfg,
-		smsService,
 		userValidator,
 		userOtpValidator,
 		userOtpHelper,
 		userHelper,
 		userStore,
 		userOtpStore,
+		refreshTokenStore,
+		revokedTokenStore,
+		userDeviceStore,
+		smsOutboxStore,
+		emailService,
+		otpCountryStatStore,
 	)
 
+	revokedTokenPurger := services.NewRevokedTokenPurger(revokedTokenStore, time.Duration(cfg.Token.PurgeInterval)*time.Second)
+	go revokedTokenPurger.Run(context.Background())
+
+	smsDispatcher := services.NewSmsDispatcher(cfg.SmsOutbox, time.Duration(cfg.Otp.ExpiredTime)*time.Second, smsOutboxStore, smsService, voiceService)
+	go smsDispatcher.Run(context.Background())
+
 	phoneNumberLimitConfig := cfg.PhoneNumberRateLimit
-	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(phoneNumberLimitConfig.Limit, phoneNumberLimitConfig.Burst)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	voiceLimitConfig := cfg.VoiceRateLimit
+	var phoneNumberLimiter, voiceLimiter helpers.IRateLimiter
+	var newPolicyLimiter func(name string, r float64, b int) helpers.IRateLimiter
+	if cfg.RateLimiter.Backend == config.MemoryRateLimiterBackend {
+		evictInterval := time.Duration(cfg.RateLimiter.EvictInterval) * time.Second
+		phoneNumberLimiters := helpers.NewBoundedPhoneNumberRateLimiters(phoneNumberLimitConfig.Limit, phoneNumberLimitConfig.Burst, cfg.RateLimiter.MaxKeys)
+		go phoneNumberLimiters.Run(context.Background(), evictInterval)
+		expvar.Publish("phone_number_limiters", expvar.Func(func() interface{} {
+			return phoneNumberLimiters.Stats()
+		}))
+
+		voiceLimiters := helpers.NewBoundedPhoneNumberRateLimiters(voiceLimitConfig.Limit, voiceLimitConfig.Burst, cfg.RateLimiter.MaxKeys)
+		go voiceLimiters.Run(context.Background(), evictInterval)
+		expvar.Publish("voice_limiters", expvar.Func(func() interface{} {
+			return voiceLimiters.Stats()
+		}))
+
+		phoneNumberLimiter, voiceLimiter = phoneNumberLimiters, voiceLimiters
+		newPolicyLimiter = func(name string, r float64, b int) helpers.IRateLimiter {
+			limiters := helpers.NewBoundedPhoneNumberRateLimiters(r, b, cfg.RateLimiter.MaxKeys)
+			go limiters.Run(context.Background(), evictInterval)
+			return limiters
+		}
+	} else {
+		rateLimitStore := stores.NewRateLimitStore(sqlxDb)
+		phoneNumberLimiter = helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", phoneNumberLimitConfig.Limit, phoneNumberLimitConfig.Burst)
+		voiceLimiter = helpers.NewStoreRateLimiter(rateLimitStore, "voice", voiceLimitConfig.Limit, voiceLimitConfig.Burst)
+		newPolicyLimiter = func(name string, r float64, b int) helpers.IRateLimiter {
+			return helpers.NewStoreRateLimiter(rateLimitStore, name, r, b)
+		}
+		rateLimitPurger := services.NewRateLimitPurger(rateLimitStore, time.Duration(cfg.RateLimiter.PurgeInterval)*time.Second)
+		go rateLimitPurger.Run(context.Background())
+	}
+
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(cfg.RateLimitPolicies, newPolicyLimiter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var otpChallengeService services.IOtpChallengeService
+	if cfg.OtpChallenge.Enabled {
+		challengeCfg := cfg.OtpChallenge
+		if challengeCfg.Captcha.Type == external.FakeCaptchaType && !cfg.Base.IsDevelopment() {
+			log.Fatal("The fake CAPTCHA verifier is only allowed in Local and Staging")
+		}
+
+		captchaVerifier, err := external.NewCaptchaVerifier(challengeCfg.Captcha)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Challenges signed with a known key could be forged by clients to skip them.
+		secretKey := challengeCfg.SecretKey
+		if secretKey == "" && cfg.Base.Environment != config.LocalEnvironment {
+			log.Fatal("The OTP challenge secret key must be set outside Local")
+		} else if secretKey == "" {
+			secretKey = cfg.Token.SecretKey
+		}
+
+		// A solved challenge is refused until it expires, by then the bucket of its id is full again.
+		otpChallengeService = services.NewOtpChallengeService(
+			cfg,
+			helpers.NewOtpChallengeHelper(challengeCfg, secretKey),
+			userStore,
+			otpCountryStatStore,
+			captchaVerifier,
+			newPolicyLimiter("otp_challenge:ip", challengeCfg.IPRateLimit.Limit, challengeCfg.IPRateLimit.Burst),
+			newPolicyLimiter("otp_challenge:first_time", challengeCfg.FirstTimeRateLimit.Limit, challengeCfg.FirstTimeRateLimit.Burst),
+			newPolicyLimiter("otp_challenge:solved", 1/float64(challengeCfg.ExpiredTime), 1),
+		)
+	}
+
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, voiceLimiter, smsService, devSmsInbox, rateLimitPolicies, otpChallengeService)
 	r.IndexRouter(router)
 	// setup swagger
 	url := ginSwagger.URL(cfg.Swagger.Url)
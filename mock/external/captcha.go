@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: external/captcha.go
+
+// Package mock_external is a generated GoMock package.
+package mock_external
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockICaptchaVerifier is a mock of ICaptchaVerifier interface
+type MockICaptchaVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockICaptchaVerifierMockRecorder
+}
+
+// MockICaptchaVerifierMockRecorder is the mock recorder for MockICaptchaVerifier
+type MockICaptchaVerifierMockRecorder struct {
+	mock *MockICaptchaVerifier
+}
+
+// NewMockICaptchaVerifier creates a new mock instance
+func NewMockICaptchaVerifier(ctrl *gomock.Controller) *MockICaptchaVerifier {
+	mock := &MockICaptchaVerifier{ctrl: ctrl}
+	mock.recorder = &MockICaptchaVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockICaptchaVerifier) EXPECT() *MockICaptchaVerifierMockRecorder {
+	return m.recorder
+}
+
+// Verify mocks base method
+func (m *MockICaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, token, remoteIP)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify
+func (mr *MockICaptchaVerifierMockRecorder) Verify(ctx, token, remoteIP interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockICaptchaVerifier)(nil).Verify), ctx, token, remoteIP)
+}
@@ -0,0 +1,47 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: external/email.go
+
+// Package mock_external is a generated GoMock package.
+package mock_external
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockIEmailService is a mock of IEmailService interface
+type MockIEmailService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIEmailServiceMockRecorder
+}
+
+// MockIEmailServiceMockRecorder is the mock recorder for MockIEmailService
+type MockIEmailServiceMockRecorder struct {
+	mock *MockIEmailService
+}
+
+// NewMockIEmailService creates a new mock instance
+func NewMockIEmailService(ctrl *gomock.Controller) *MockIEmailService {
+	mock := &MockIEmailService{ctrl: ctrl}
+	mock.recorder = &MockIEmailServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIEmailService) EXPECT() *MockIEmailServiceMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method
+func (m *MockIEmailService) Send(to, subject, body string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", to, subject, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send
+func (mr *MockIEmailServiceMockRecorder) Send(to, subject, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockIEmailService)(nil).Send), to, subject, body)
+}
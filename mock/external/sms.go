@@ -5,8 +5,11 @@
 package mock_external
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
+	http "net/http"
 	reflect "reflect"
+	external "tbox_backend/external"
 )
 
 // MockISmsService is a mock of ISmsService interface
@@ -32,16 +35,46 @@ func (m *MockISmsService) EXPECT() *MockISmsServiceMockRecorder {
 	return m.recorder
 }
 
-// SendOtp mocks base method
-func (m *MockISmsService) SendOtp(phoneNumber, otp string) error {
+// Send mocks base method
+func (m *MockISmsService) Send(ctx context.Context, phoneNumber, content string) (external.SmsReceipt, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendOtp", phoneNumber, otp)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "Send", ctx, phoneNumber, content)
+	ret0, _ := ret[0].(external.SmsReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Send indicates an expected call of Send
+func (mr *MockISmsServiceMockRecorder) Send(ctx, phoneNumber, content interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockISmsService)(nil).Send), ctx, phoneNumber, content)
+}
+
+// ParseDeliveryReport mocks base method
+func (m *MockISmsService) ParseDeliveryReport(provider string, req *http.Request, body []byte) (external.SmsDeliveryReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseDeliveryReport", provider, req, body)
+	ret0, _ := ret[0].(external.SmsDeliveryReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseDeliveryReport indicates an expected call of ParseDeliveryReport
+func (mr *MockISmsServiceMockRecorder) ParseDeliveryReport(provider, req, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseDeliveryReport", reflect.TypeOf((*MockISmsService)(nil).ParseDeliveryReport), provider, req, body)
+}
+
+// ProviderStates mocks base method
+func (m *MockISmsService) ProviderStates() []external.SmsProviderState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProviderStates")
+	ret0, _ := ret[0].([]external.SmsProviderState)
 	return ret0
 }
 
-// SendOtp indicates an expected call of SendOtp
-func (mr *MockISmsServiceMockRecorder) SendOtp(phoneNumber, otp interface{}) *gomock.Call {
+// ProviderStates indicates an expected call of ProviderStates
+func (mr *MockISmsServiceMockRecorder) ProviderStates() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendOtp", reflect.TypeOf((*MockISmsService)(nil).SendOtp), phoneNumber, otp)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProviderStates", reflect.TypeOf((*MockISmsService)(nil).ProviderStates))
 }
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: external/voice.go
+
+// Package mock_external is a generated GoMock package.
+package mock_external
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	external "tbox_backend/external"
+)
+
+// MockIVoiceService is a mock of IVoiceService interface
+type MockIVoiceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIVoiceServiceMockRecorder
+}
+
+// MockIVoiceServiceMockRecorder is the mock recorder for MockIVoiceService
+type MockIVoiceServiceMockRecorder struct {
+	mock *MockIVoiceService
+}
+
+// NewMockIVoiceService creates a new mock instance
+func NewMockIVoiceService(ctrl *gomock.Controller) *MockIVoiceService {
+	mock := &MockIVoiceService{ctrl: ctrl}
+	mock.recorder = &MockIVoiceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIVoiceService) EXPECT() *MockIVoiceServiceMockRecorder {
+	return m.recorder
+}
+
+// Call mocks base method
+func (m *MockIVoiceService) Call(ctx context.Context, phoneNumber, speech string) (external.VoiceReceipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Call", ctx, phoneNumber, speech)
+	ret0, _ := ret[0].(external.VoiceReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Call indicates an expected call of Call
+func (mr *MockIVoiceServiceMockRecorder) Call(ctx, phoneNumber, speech interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Call", reflect.TypeOf((*MockIVoiceService)(nil).Call), ctx, phoneNumber, speech)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/services/otp_challenge.go
+
+// Package mock_services is a generated GoMock package.
+package mock_services
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+)
+
+// MockIOtpChallengeService is a mock of IOtpChallengeService interface
+type MockIOtpChallengeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIOtpChallengeServiceMockRecorder
+}
+
+// MockIOtpChallengeServiceMockRecorder is the mock recorder for MockIOtpChallengeService
+type MockIOtpChallengeServiceMockRecorder struct {
+	mock *MockIOtpChallengeService
+}
+
+// NewMockIOtpChallengeService creates a new mock instance
+func NewMockIOtpChallengeService(ctrl *gomock.Controller) *MockIOtpChallengeService {
+	mock := &MockIOtpChallengeService{ctrl: ctrl}
+	mock.recorder = &MockIOtpChallengeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIOtpChallengeService) EXPECT() *MockIOtpChallengeServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method
+func (m *MockIOtpChallengeService) Check(ctx context.Context, phoneNumber, countryCode, clientIP string, solution dto.OtpChallengeSolution) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, phoneNumber, countryCode, clientIP, solution)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check
+func (mr *MockIOtpChallengeServiceMockRecorder) Check(ctx, phoneNumber, countryCode, clientIP, solution interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockIOtpChallengeService)(nil).Check), ctx, phoneNumber, countryCode, clientIP, solution)
+}
+
+// NewChallenge mocks base method
+func (m *MockIOtpChallengeService) NewChallenge(phoneNumber, reason string) (dto.OtpChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewChallenge", phoneNumber, reason)
+	ret0, _ := ret[0].(dto.OtpChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewChallenge indicates an expected call of NewChallenge
+func (mr *MockIOtpChallengeServiceMockRecorder) NewChallenge(phoneNumber, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewChallenge", reflect.TypeOf((*MockIOtpChallengeService)(nil).NewChallenge), phoneNumber, reason)
+}
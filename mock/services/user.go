@@ -7,6 +7,8 @@ package mock_services
 import (
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+	time "time"
 )
 
 // MockIUserService is a mock of IUserService interface
@@ -33,38 +35,40 @@ func (m *MockIUserService) EXPECT() *MockIUserServiceMockRecorder {
 }
 
 // GenerateOtp mocks base method
-func (m *MockIUserService) GenerateOtp(phoneNumber string) error {
+func (m *MockIUserService) GenerateOtp(phoneNumber, locale, channel string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateOtp", phoneNumber)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GenerateOtp", phoneNumber, locale, channel)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GenerateOtp indicates an expected call of GenerateOtp
-func (mr *MockIUserServiceMockRecorder) GenerateOtp(phoneNumber interface{}) *gomock.Call {
+func (mr *MockIUserServiceMockRecorder) GenerateOtp(phoneNumber, locale, channel interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateOtp", reflect.TypeOf((*MockIUserService)(nil).GenerateOtp), phoneNumber)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateOtp", reflect.TypeOf((*MockIUserService)(nil).GenerateOtp), phoneNumber, locale, channel)
 }
 
 // ResendOtp mocks base method
-func (m *MockIUserService) ResendOtp(phoneNumber string) error {
+func (m *MockIUserService) ResendOtp(phoneNumber, locale, channel string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ResendOtp", phoneNumber)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ResendOtp", phoneNumber, locale, channel)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ResendOtp indicates an expected call of ResendOtp
-func (mr *MockIUserServiceMockRecorder) ResendOtp(phoneNumber interface{}) *gomock.Call {
+func (mr *MockIUserServiceMockRecorder) ResendOtp(phoneNumber, locale, channel interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendOtp", reflect.TypeOf((*MockIUserService)(nil).ResendOtp), phoneNumber)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendOtp", reflect.TypeOf((*MockIUserService)(nil).ResendOtp), phoneNumber, locale, channel)
 }
 
 // Login mocks base method
-func (m *MockIUserService) Login(phoneNumber, otp string) (string, error) {
+func (m *MockIUserService) Login(phoneNumber, otp string) (dto.Token, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Login", phoneNumber, otp)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(dto.Token)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -74,3 +78,267 @@ func (mr *MockIUserServiceMockRecorder) Login(phoneNumber, otp interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockIUserService)(nil).Login), phoneNumber, otp)
 }
+
+// GenerateEmailOtp mocks base method
+func (m *MockIUserService) GenerateEmailOtp(email, locale string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateEmailOtp", email, locale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GenerateEmailOtp indicates an expected call of GenerateEmailOtp
+func (mr *MockIUserServiceMockRecorder) GenerateEmailOtp(email, locale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateEmailOtp", reflect.TypeOf((*MockIUserService)(nil).GenerateEmailOtp), email, locale)
+}
+
+// LoginWithEmail mocks base method
+func (m *MockIUserService) LoginWithEmail(email, otp string) (dto.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginWithEmail", email, otp)
+	ret0, _ := ret[0].(dto.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginWithEmail indicates an expected call of LoginWithEmail
+func (mr *MockIUserServiceMockRecorder) LoginWithEmail(email, otp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginWithEmail", reflect.TypeOf((*MockIUserService)(nil).LoginWithEmail), email, otp)
+}
+
+// SendMagicLink mocks base method
+func (m *MockIUserService) SendMagicLink(email, locale string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMagicLink", email, locale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMagicLink indicates an expected call of SendMagicLink
+func (mr *MockIUserServiceMockRecorder) SendMagicLink(email, locale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMagicLink", reflect.TypeOf((*MockIUserService)(nil).SendMagicLink), email, locale)
+}
+
+// LoginWithMagicLink mocks base method
+func (m *MockIUserService) LoginWithMagicLink(token string) (dto.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginWithMagicLink", token)
+	ret0, _ := ret[0].(dto.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginWithMagicLink indicates an expected call of LoginWithMagicLink
+func (mr *MockIUserServiceMockRecorder) LoginWithMagicLink(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginWithMagicLink", reflect.TypeOf((*MockIUserService)(nil).LoginWithMagicLink), token)
+}
+
+// LoginWithDevice mocks base method
+func (m *MockIUserService) LoginWithDevice(phoneNumber string, deviceCredential dto.DeviceCredential, ip string) (dto.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginWithDevice", phoneNumber, deviceCredential, ip)
+	ret0, _ := ret[0].(dto.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginWithDevice indicates an expected call of LoginWithDevice
+func (mr *MockIUserServiceMockRecorder) LoginWithDevice(phoneNumber, deviceCredential, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginWithDevice", reflect.TypeOf((*MockIUserService)(nil).LoginWithDevice), phoneNumber, deviceCredential, ip)
+}
+
+// RegisterDevice mocks base method
+func (m *MockIUserService) RegisterDevice(user *dto.User, tokenInfo dto.TokenInfo, name string) (dto.DeviceCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterDevice", user, tokenInfo, name)
+	ret0, _ := ret[0].(dto.DeviceCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterDevice indicates an expected call of RegisterDevice
+func (mr *MockIUserServiceMockRecorder) RegisterDevice(user, tokenInfo, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterDevice", reflect.TypeOf((*MockIUserService)(nil).RegisterDevice), user, tokenInfo, name)
+}
+
+// GetDevices mocks base method
+func (m *MockIUserService) GetDevices(user *dto.User) ([]dto.UserDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevices", user)
+	ret0, _ := ret[0].([]dto.UserDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevices indicates an expected call of GetDevices
+func (mr *MockIUserServiceMockRecorder) GetDevices(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevices", reflect.TypeOf((*MockIUserService)(nil).GetDevices), user)
+}
+
+// RevokeDevice mocks base method
+func (m *MockIUserService) RevokeDevice(user *dto.User, deviceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeDevice", user, deviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeDevice indicates an expected call of RevokeDevice
+func (mr *MockIUserServiceMockRecorder) RevokeDevice(user, deviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeDevice", reflect.TypeOf((*MockIUserService)(nil).RevokeDevice), user, deviceID)
+}
+
+// UpdateSmsDeliveryStatus mocks base method
+func (m *MockIUserService) UpdateSmsDeliveryStatus(report dto.SmsDeliveryReport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSmsDeliveryStatus", report)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSmsDeliveryStatus indicates an expected call of UpdateSmsDeliveryStatus
+func (mr *MockIUserServiceMockRecorder) UpdateSmsDeliveryStatus(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSmsDeliveryStatus", reflect.TypeOf((*MockIUserService)(nil).UpdateSmsDeliveryStatus), report)
+}
+
+// GetOtpStatus mocks base method
+func (m *MockIUserService) GetOtpStatus(phoneNumber, statusToken string) (dto.OtpStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOtpStatus", phoneNumber, statusToken)
+	ret0, _ := ret[0].(dto.OtpStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOtpStatus indicates an expected call of GetOtpStatus
+func (mr *MockIUserServiceMockRecorder) GetOtpStatus(phoneNumber, statusToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOtpStatus", reflect.TypeOf((*MockIUserService)(nil).GetOtpStatus), phoneNumber, statusToken)
+}
+
+// GetOtpCountryStats mocks base method
+func (m *MockIUserService) GetOtpCountryStats(day time.Time) ([]dto.OtpCountryStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOtpCountryStats", day)
+	ret0, _ := ret[0].([]dto.OtpCountryStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOtpCountryStats indicates an expected call of GetOtpCountryStats
+func (mr *MockIUserServiceMockRecorder) GetOtpCountryStats(day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOtpCountryStats", reflect.TypeOf((*MockIUserService)(nil).GetOtpCountryStats), day)
+}
+
+// RefreshToken mocks base method
+func (m *MockIUserService) RefreshToken(refreshToken string) (dto.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", refreshToken)
+	ret0, _ := ret[0].(dto.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken
+func (mr *MockIUserServiceMockRecorder) RefreshToken(refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockIUserService)(nil).RefreshToken), refreshToken)
+}
+
+// Authenticate mocks base method
+func (m *MockIUserService) Authenticate(accessToken string) (*dto.User, dto.TokenInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", accessToken)
+	ret0, _ := ret[0].(*dto.User)
+	ret1, _ := ret[1].(dto.TokenInfo)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Authenticate indicates an expected call of Authenticate
+func (mr *MockIUserServiceMockRecorder) Authenticate(accessToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockIUserService)(nil).Authenticate), accessToken)
+}
+
+// Logout mocks base method
+func (m *MockIUserService) Logout(tokenInfo dto.TokenInfo, refreshToken string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", tokenInfo, refreshToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout
+func (mr *MockIUserServiceMockRecorder) Logout(tokenInfo, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockIUserService)(nil).Logout), tokenInfo, refreshToken)
+}
+
+// LogoutAll mocks base method
+func (m *MockIUserService) LogoutAll(user *dto.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll
+func (mr *MockIUserServiceMockRecorder) LogoutAll(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockIUserService)(nil).LogoutAll), user)
+}
+
+// GetJwks mocks base method
+func (m *MockIUserService) GetJwks() dto.Jwks {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJwks")
+	ret0, _ := ret[0].(dto.Jwks)
+	return ret0
+}
+
+// GetJwks indicates an expected call of GetJwks
+func (mr *MockIUserServiceMockRecorder) GetJwks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJwks", reflect.TypeOf((*MockIUserService)(nil).GetJwks))
+}
+
+// AuthenticateClient mocks base method
+func (m *MockIUserService) AuthenticateClient(clientID, clientSecret string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateClient", clientID, clientSecret)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// AuthenticateClient indicates an expected call of AuthenticateClient
+func (mr *MockIUserServiceMockRecorder) AuthenticateClient(clientID, clientSecret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateClient", reflect.TypeOf((*MockIUserService)(nil).AuthenticateClient), clientID, clientSecret)
+}
+
+// IntrospectToken mocks base method
+func (m *MockIUserService) IntrospectToken(accessToken string) (dto.TokenInfo, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IntrospectToken", accessToken)
+	ret0, _ := ret[0].(dto.TokenInfo)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IntrospectToken indicates an expected call of IntrospectToken
+func (mr *MockIUserServiceMockRecorder) IntrospectToken(accessToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectToken", reflect.TypeOf((*MockIUserService)(nil).IntrospectToken), accessToken)
+}
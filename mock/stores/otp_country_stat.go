@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/otp_country_stat.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+	time "time"
+)
+
+// MockIOtpCountryStatStore is a mock of IOtpCountryStatStore interface
+type MockIOtpCountryStatStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIOtpCountryStatStoreMockRecorder
+}
+
+// MockIOtpCountryStatStoreMockRecorder is the mock recorder for MockIOtpCountryStatStore
+type MockIOtpCountryStatStoreMockRecorder struct {
+	mock *MockIOtpCountryStatStore
+}
+
+// NewMockIOtpCountryStatStore creates a new mock instance
+func NewMockIOtpCountryStatStore(ctrl *gomock.Controller) *MockIOtpCountryStatStore {
+	mock := &MockIOtpCountryStatStore{ctrl: ctrl}
+	mock.recorder = &MockIOtpCountryStatStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIOtpCountryStatStore) EXPECT() *MockIOtpCountryStatStoreMockRecorder {
+	return m.recorder
+}
+
+// IncreaseBlocked mocks base method
+func (m *MockIOtpCountryStatStore) IncreaseBlocked(countryCode string, day time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseBlocked", countryCode, day)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncreaseBlocked indicates an expected call of IncreaseBlocked
+func (mr *MockIOtpCountryStatStoreMockRecorder) IncreaseBlocked(countryCode, day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseBlocked", reflect.TypeOf((*MockIOtpCountryStatStore)(nil).IncreaseBlocked), countryCode, day)
+}
+
+// IncreaseCapped mocks base method
+func (m *MockIOtpCountryStatStore) IncreaseCapped(countryCode string, day time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseCapped", countryCode, day)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncreaseCapped indicates an expected call of IncreaseCapped
+func (mr *MockIOtpCountryStatStoreMockRecorder) IncreaseCapped(countryCode, day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseCapped", reflect.TypeOf((*MockIOtpCountryStatStore)(nil).IncreaseCapped), countryCode, day)
+}
+
+// GetByDay mocks base method
+func (m *MockIOtpCountryStatStore) GetByDay(day time.Time) ([]dto.OtpCountryStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByDay", day)
+	ret0, _ := ret[0].([]dto.OtpCountryStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByDay indicates an expected call of GetByDay
+func (mr *MockIOtpCountryStatStoreMockRecorder) GetByDay(day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByDay", reflect.TypeOf((*MockIOtpCountryStatStore)(nil).GetByDay), day)
+}
+
+// HasSentSince mocks base method
+func (m *MockIOtpCountryStatStore) HasSentSince(countryCode string, day time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasSentSince", countryCode, day)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasSentSince indicates an expected call of HasSentSince
+func (mr *MockIOtpCountryStatStoreMockRecorder) HasSentSince(countryCode, day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasSentSince", reflect.TypeOf((*MockIOtpCountryStatStore)(nil).HasSentSince), countryCode, day)
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/rate_limit.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockIRateLimitStore is a mock of IRateLimitStore interface
+type MockIRateLimitStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRateLimitStoreMockRecorder
+}
+
+// MockIRateLimitStoreMockRecorder is the mock recorder for MockIRateLimitStore
+type MockIRateLimitStoreMockRecorder struct {
+	mock *MockIRateLimitStore
+}
+
+// NewMockIRateLimitStore creates a new mock instance
+func NewMockIRateLimitStore(ctrl *gomock.Controller) *MockIRateLimitStore {
+	mock := &MockIRateLimitStore{ctrl: ctrl}
+	mock.recorder = &MockIRateLimitStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIRateLimitStore) EXPECT() *MockIRateLimitStoreMockRecorder {
+	return m.recorder
+}
+
+// Take mocks base method
+func (m *MockIRateLimitStore) Take(key string, rate float64, burst int) (bool, float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", key, rate, burst)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(float64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Take indicates an expected call of Take
+func (mr *MockIRateLimitStoreMockRecorder) Take(key, rate, burst interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockIRateLimitStore)(nil).Take), key, rate, burst)
+}
+
+// Refund mocks base method
+func (m *MockIRateLimitStore) Refund(key string, burst int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", key, burst)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refund indicates an expected call of Refund
+func (mr *MockIRateLimitStoreMockRecorder) Refund(key, burst interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockIRateLimitStore)(nil).Refund), key, burst)
+}
+
+// DeleteExpired mocks base method
+func (m *MockIRateLimitStore) DeleteExpired() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired
+func (mr *MockIRateLimitStoreMockRecorder) DeleteExpired() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockIRateLimitStore)(nil).DeleteExpired))
+}
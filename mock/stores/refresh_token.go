@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/refresh_token.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+)
+
+// MockIRefreshTokenStore is a mock of IRefreshTokenStore interface
+type MockIRefreshTokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRefreshTokenStoreMockRecorder
+}
+
+// MockIRefreshTokenStoreMockRecorder is the mock recorder for MockIRefreshTokenStore
+type MockIRefreshTokenStoreMockRecorder struct {
+	mock *MockIRefreshTokenStore
+}
+
+// NewMockIRefreshTokenStore creates a new mock instance
+func NewMockIRefreshTokenStore(ctrl *gomock.Controller) *MockIRefreshTokenStore {
+	mock := &MockIRefreshTokenStore{ctrl: ctrl}
+	mock.recorder = &MockIRefreshTokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIRefreshTokenStore) EXPECT() *MockIRefreshTokenStoreMockRecorder {
+	return m.recorder
+}
+
+// GetByTokenHash mocks base method
+func (m *MockIRefreshTokenStore) GetByTokenHash(tokenHash string) (dto.RefreshToken, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByTokenHash", tokenHash)
+	ret0, _ := ret[0].(dto.RefreshToken)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByTokenHash indicates an expected call of GetByTokenHash
+func (mr *MockIRefreshTokenStoreMockRecorder) GetByTokenHash(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByTokenHash", reflect.TypeOf((*MockIRefreshTokenStore)(nil).GetByTokenHash), tokenHash)
+}
+
+// Save mocks base method
+func (m *MockIRefreshTokenStore) Save(refreshToken dto.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", refreshToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save
+func (mr *MockIRefreshTokenStoreMockRecorder) Save(refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockIRefreshTokenStore)(nil).Save), refreshToken)
+}
+
+// Revoke mocks base method
+func (m *MockIRefreshTokenStore) Revoke(refreshToken dto.RefreshToken) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", refreshToken)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockIRefreshTokenStoreMockRecorder) Revoke(refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockIRefreshTokenStore)(nil).Revoke), refreshToken)
+}
+
+// RevokeByUserID mocks base method
+func (m *MockIRefreshTokenStore) RevokeByUserID(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeByUserID indicates an expected call of RevokeByUserID
+func (mr *MockIRefreshTokenStoreMockRecorder) RevokeByUserID(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeByUserID", reflect.TypeOf((*MockIRefreshTokenStore)(nil).RevokeByUserID), userID)
+}
@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/revoked_token.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+	time "time"
+)
+
+// MockIRevokedTokenStore is a mock of IRevokedTokenStore interface
+type MockIRevokedTokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRevokedTokenStoreMockRecorder
+}
+
+// MockIRevokedTokenStoreMockRecorder is the mock recorder for MockIRevokedTokenStore
+type MockIRevokedTokenStoreMockRecorder struct {
+	mock *MockIRevokedTokenStore
+}
+
+// NewMockIRevokedTokenStore creates a new mock instance
+func NewMockIRevokedTokenStore(ctrl *gomock.Controller) *MockIRevokedTokenStore {
+	mock := &MockIRevokedTokenStore{ctrl: ctrl}
+	mock.recorder = &MockIRevokedTokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIRevokedTokenStore) EXPECT() *MockIRevokedTokenStoreMockRecorder {
+	return m.recorder
+}
+
+// Exists mocks base method
+func (m *MockIRevokedTokenStore) Exists(tokenID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", tokenID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists
+func (mr *MockIRevokedTokenStoreMockRecorder) Exists(tokenID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockIRevokedTokenStore)(nil).Exists), tokenID)
+}
+
+// Save mocks base method
+func (m *MockIRevokedTokenStore) Save(revokedToken dto.RevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", revokedToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save
+func (mr *MockIRevokedTokenStoreMockRecorder) Save(revokedToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockIRevokedTokenStore)(nil).Save), revokedToken)
+}
+
+// Consume mocks base method
+func (m *MockIRevokedTokenStore) Consume(revokedToken dto.RevokedToken) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", revokedToken)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume
+func (mr *MockIRevokedTokenStoreMockRecorder) Consume(revokedToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockIRevokedTokenStore)(nil).Consume), revokedToken)
+}
+
+// DeleteExpired mocks base method
+func (m *MockIRevokedTokenStore) DeleteExpired(now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired
+func (mr *MockIRevokedTokenStoreMockRecorder) DeleteExpired(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockIRevokedTokenStore)(nil).DeleteExpired), now)
+}
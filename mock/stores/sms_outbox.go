@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/sms_outbox.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+	time "time"
+)
+
+// MockISmsOutboxStore is a mock of ISmsOutboxStore interface
+type MockISmsOutboxStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockISmsOutboxStoreMockRecorder
+}
+
+// MockISmsOutboxStoreMockRecorder is the mock recorder for MockISmsOutboxStore
+type MockISmsOutboxStoreMockRecorder struct {
+	mock *MockISmsOutboxStore
+}
+
+// NewMockISmsOutboxStore creates a new mock instance
+func NewMockISmsOutboxStore(ctrl *gomock.Controller) *MockISmsOutboxStore {
+	mock := &MockISmsOutboxStore{ctrl: ctrl}
+	mock.recorder = &MockISmsOutboxStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockISmsOutboxStore) EXPECT() *MockISmsOutboxStoreMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method
+func (m *MockISmsOutboxStore) Claim(now time.Time, limit int, leaseTime time.Duration) ([]dto.SmsOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", now, limit, leaseTime)
+	ret0, _ := ret[0].([]dto.SmsOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim
+func (mr *MockISmsOutboxStoreMockRecorder) Claim(now, limit, leaseTime interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockISmsOutboxStore)(nil).Claim), now, limit, leaseTime)
+}
+
+// Update mocks base method
+func (m *MockISmsOutboxStore) Update(smsOutbox dto.SmsOutbox) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", smsOutbox)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *MockISmsOutboxStoreMockRecorder) Update(smsOutbox interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockISmsOutboxStore)(nil).Update), smsOutbox)
+}
+
+// GetByProviderMessageID mocks base method
+func (m *MockISmsOutboxStore) GetByProviderMessageID(provider, messageID string) (dto.SmsOutbox, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByProviderMessageID", provider, messageID)
+	ret0, _ := ret[0].(dto.SmsOutbox)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByProviderMessageID indicates an expected call of GetByProviderMessageID
+func (mr *MockISmsOutboxStoreMockRecorder) GetByProviderMessageID(provider, messageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByProviderMessageID", reflect.TypeOf((*MockISmsOutboxStore)(nil).GetByProviderMessageID), provider, messageID)
+}
+
+// GetLatestByPhoneNumber mocks base method
+func (m *MockISmsOutboxStore) GetLatestByPhoneNumber(phoneNumber string) (dto.SmsOutbox, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestByPhoneNumber", phoneNumber)
+	ret0, _ := ret[0].(dto.SmsOutbox)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetLatestByPhoneNumber indicates an expected call of GetLatestByPhoneNumber
+func (mr *MockISmsOutboxStoreMockRecorder) GetLatestByPhoneNumber(phoneNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestByPhoneNumber", reflect.TypeOf((*MockISmsOutboxStore)(nil).GetLatestByPhoneNumber), phoneNumber)
+}
+
+// UpdateDeliveryStatus mocks base method
+func (m *MockISmsOutboxStore) UpdateDeliveryStatus(smsOutbox dto.SmsOutbox) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeliveryStatus", smsOutbox)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeliveryStatus indicates an expected call of UpdateDeliveryStatus
+func (mr *MockISmsOutboxStoreMockRecorder) UpdateDeliveryStatus(smsOutbox interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeliveryStatus", reflect.TypeOf((*MockISmsOutboxStore)(nil).UpdateDeliveryStatus), smsOutbox)
+}
@@ -49,6 +49,38 @@ func (mr *MockIUserStoreMockRecorder) GetByPhoneNumber(phoneNo interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPhoneNumber", reflect.TypeOf((*MockIUserStore)(nil).GetByPhoneNumber), phoneNo)
 }
 
+// GetByEmail mocks base method
+func (m *MockIUserStore) GetByEmail(email string) (*dto.User, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByEmail", email)
+	ret0, _ := ret[0].(*dto.User)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByEmail indicates an expected call of GetByEmail
+func (mr *MockIUserStoreMockRecorder) GetByEmail(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByEmail", reflect.TypeOf((*MockIUserStore)(nil).GetByEmail), email)
+}
+
+// GetByID mocks base method
+func (m *MockIUserStore) GetByID(userID int) (*dto.User, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", userID)
+	ret0, _ := ret[0].(*dto.User)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByID indicates an expected call of GetByID
+func (mr *MockIUserStoreMockRecorder) GetByID(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockIUserStore)(nil).GetByID), userID)
+}
+
 // Save mocks base method
 func (m *MockIUserStore) Save(user *dto.User) error {
 	m.ctrl.T.Helper()
@@ -76,3 +108,17 @@ func (mr *MockIUserStoreMockRecorder) UpdateStatus(user interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockIUserStore)(nil).UpdateStatus), user)
 }
+
+// IncreaseTokenGeneration mocks base method
+func (m *MockIUserStore) IncreaseTokenGeneration(user *dto.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseTokenGeneration", user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncreaseTokenGeneration indicates an expected call of IncreaseTokenGeneration
+func (mr *MockIUserStoreMockRecorder) IncreaseTokenGeneration(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseTokenGeneration", reflect.TypeOf((*MockIUserStore)(nil).IncreaseTokenGeneration), user)
+}
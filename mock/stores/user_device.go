@@ -0,0 +1,122 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/stores/user_device.go
+
+// Package mock_stores is a generated GoMock package.
+package mock_stores
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	dto "tbox_backend/internal/dto"
+)
+
+// MockIUserDeviceStore is a mock of IUserDeviceStore interface
+type MockIUserDeviceStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIUserDeviceStoreMockRecorder
+}
+
+// MockIUserDeviceStoreMockRecorder is the mock recorder for MockIUserDeviceStore
+type MockIUserDeviceStoreMockRecorder struct {
+	mock *MockIUserDeviceStore
+}
+
+// NewMockIUserDeviceStore creates a new mock instance
+func NewMockIUserDeviceStore(ctrl *gomock.Controller) *MockIUserDeviceStore {
+	mock := &MockIUserDeviceStore{ctrl: ctrl}
+	mock.recorder = &MockIUserDeviceStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIUserDeviceStore) EXPECT() *MockIUserDeviceStoreMockRecorder {
+	return m.recorder
+}
+
+// GetByDeviceID mocks base method
+func (m *MockIUserDeviceStore) GetByDeviceID(deviceID string) (dto.UserDevice, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByDeviceID", deviceID)
+	ret0, _ := ret[0].(dto.UserDevice)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetByDeviceID indicates an expected call of GetByDeviceID
+func (mr *MockIUserDeviceStoreMockRecorder) GetByDeviceID(deviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByDeviceID", reflect.TypeOf((*MockIUserDeviceStore)(nil).GetByDeviceID), deviceID)
+}
+
+// GetByUserID mocks base method
+func (m *MockIUserDeviceStore) GetByUserID(userID int) ([]dto.UserDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserID", userID)
+	ret0, _ := ret[0].([]dto.UserDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserID indicates an expected call of GetByUserID
+func (mr *MockIUserDeviceStoreMockRecorder) GetByUserID(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockIUserDeviceStore)(nil).GetByUserID), userID)
+}
+
+// Save mocks base method
+func (m *MockIUserDeviceStore) Save(userDevice dto.UserDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", userDevice)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save
+func (mr *MockIUserDeviceStoreMockRecorder) Save(userDevice interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockIUserDeviceStore)(nil).Save), userDevice)
+}
+
+// UpdateLastUsed mocks base method
+func (m *MockIUserDeviceStore) UpdateLastUsed(userDevice dto.UserDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsed", userDevice)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsed indicates an expected call of UpdateLastUsed
+func (mr *MockIUserDeviceStoreMockRecorder) UpdateLastUsed(userDevice interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsed", reflect.TypeOf((*MockIUserDeviceStore)(nil).UpdateLastUsed), userDevice)
+}
+
+// Revoke mocks base method
+func (m *MockIUserDeviceStore) Revoke(userID int, deviceID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", userID, deviceID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockIUserDeviceStoreMockRecorder) Revoke(userID, deviceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockIUserDeviceStore)(nil).Revoke), userID, deviceID)
+}
+
+// RevokeByUserID mocks base method
+func (m *MockIUserDeviceStore) RevokeByUserID(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeByUserID", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeByUserID indicates an expected call of RevokeByUserID
+func (mr *MockIUserDeviceStoreMockRecorder) RevokeByUserID(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeByUserID", reflect.TypeOf((*MockIUserDeviceStore)(nil).RevokeByUserID), userID)
+}
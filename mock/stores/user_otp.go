@@ -50,29 +50,85 @@ func (mr *MockIUserOtpStoreMockRecorder) GetByUserID(userID interface{}) *gomock
 }
 
 // Save mocks base method
-func (m *MockIUserOtpStore) Save(userOtp dto.UserOtp) error {
+func (m *MockIUserOtpStore) Save(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", userOtp)
+	varargs := []interface{}{userOtp, otpCountryQuota}
+	for _, a := range smsOutboxes {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Save", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save
-func (mr *MockIUserOtpStoreMockRecorder) Save(userOtp interface{}) *gomock.Call {
+func (mr *MockIUserOtpStoreMockRecorder) Save(userOtp, otpCountryQuota interface{}, smsOutboxes ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockIUserOtpStore)(nil).Save), userOtp)
+	varargs := append([]interface{}{userOtp, otpCountryQuota}, smsOutboxes...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockIUserOtpStore)(nil).Save), varargs...)
 }
 
 // UpdateOtp mocks base method
-func (m *MockIUserOtpStore) UpdateOtp(userOtp dto.UserOtp) error {
+func (m *MockIUserOtpStore) UpdateOtp(userOtp dto.UserOtp, otpCountryQuota dto.OtpCountryQuota, smsOutboxes ...dto.SmsOutbox) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateOtp", userOtp)
+	varargs := []interface{}{userOtp, otpCountryQuota}
+	for _, a := range smsOutboxes {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateOtp", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateOtp indicates an expected call of UpdateOtp
-func (mr *MockIUserOtpStoreMockRecorder) UpdateOtp(userOtp interface{}) *gomock.Call {
+func (mr *MockIUserOtpStoreMockRecorder) UpdateOtp(userOtp, otpCountryQuota interface{}, smsOutboxes ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOtp", reflect.TypeOf((*MockIUserOtpStore)(nil).UpdateOtp), userOtp)
+	varargs := append([]interface{}{userOtp, otpCountryQuota}, smsOutboxes...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOtp", reflect.TypeOf((*MockIUserOtpStore)(nil).UpdateOtp), varargs...)
+}
+
+// IncreaseFailedAttempts mocks base method
+func (m *MockIUserOtpStore) IncreaseFailedAttempts(userOtp dto.UserOtp) (int, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseFailedAttempts", userOtp)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncreaseFailedAttempts indicates an expected call of IncreaseFailedAttempts
+func (mr *MockIUserOtpStoreMockRecorder) IncreaseFailedAttempts(userOtp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseFailedAttempts", reflect.TypeOf((*MockIUserOtpStore)(nil).IncreaseFailedAttempts), userOtp)
+}
+
+// InvalidateOtp mocks base method
+func (m *MockIUserOtpStore) InvalidateOtp(userOtp dto.UserOtp) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateOtp", userOtp)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InvalidateOtp indicates an expected call of InvalidateOtp
+func (mr *MockIUserOtpStoreMockRecorder) InvalidateOtp(userOtp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateOtp", reflect.TypeOf((*MockIUserOtpStore)(nil).InvalidateOtp), userOtp)
+}
+
+// ConsumeOtp mocks base method
+func (m *MockIUserOtpStore) ConsumeOtp(userOtp dto.UserOtp) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOtp", userOtp)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOtp indicates an expected call of ConsumeOtp
+func (mr *MockIUserOtpStoreMockRecorder) ConsumeOtp(userOtp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOtp", reflect.TypeOf((*MockIUserOtpStore)(nil).ConsumeOtp), userOtp)
 }
@@ -0,0 +1,106 @@
+package routers
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+	"tbox_backend/internal/constants"
+	"tbox_backend/internal/dto"
+)
+
+type devSmsGroup struct {
+	PhoneNumber string
+	Messages    []dto.DevSmsMessage
+}
+
+var devSmsPage = template.Must(template.New("dev_sms").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dev SMS inbox</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Dev SMS inbox</h1>
+<form method="get">
+<input name="phone_number" value="{{.PhoneNumber}}" placeholder="Phone number">
+<button type="submit">Filter</button>
+<a href="?">All numbers</a>
+</form>
+{{range .Groups}}
+<h2><a href="?phone_number={{.PhoneNumber}}">{{.PhoneNumber}}</a></h2>
+<table>
+<tr><th>Sent at</th><th>Message</th></tr>
+{{range .Messages}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td><td class="content">{{.Content}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No messages.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// @Summary Dev SMS inbox
+// @Description Recent messages kept by the dev SMS provider, newest first. Only served in Local and Staging.
+// @Produce  json
+// @Param phone_number query string false "Phone number, every number when empty"
+// @Success 200 {object} dto.DevSmsInboxResponse
+// @Router /dev/sms/inbox [get]
+func (r *Router) devSmsInboxHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.NewDevSmsInboxResponse(constants.SuccessStatus, "Success", r.devSmsMessages(ctx.Query("phone_number"))))
+	return
+}
+
+// devSmsPageHandler lists the messages of the dev inbox by phone number, the number messaged last comes first.
+func (r *Router) devSmsPageHandler(ctx *gin.Context) {
+	phoneNumber := ctx.Query("phone_number")
+	var groups []*devSmsGroup
+	groupIndex := make(map[string]*devSmsGroup)
+	for _, message := range r.devSmsMessages(phoneNumber) {
+		group, exists := groupIndex[message.PhoneNumber]
+		if !exists {
+			group = &devSmsGroup{PhoneNumber: message.PhoneNumber}
+			groupIndex[message.PhoneNumber] = group
+			groups = append(groups, group)
+		}
+
+		group.Messages = append(group.Messages, message)
+	}
+
+	buf := new(bytes.Buffer)
+	err := devSmsPage.Execute(buf, map[string]interface{}{"PhoneNumber": phoneNumber, "Groups": groups})
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	return
+}
+
+func (r *Router) devSmsMessages(phoneNumber string) []dto.DevSmsMessage {
+	// Messages are sent to E.164 numbers, let the filter be written like any other phone number.
+	if normalizedPhoneNumber, _, valid := r.userValidator.NormalizePhoneNumber(phoneNumber); valid {
+		phoneNumber = normalizedPhoneNumber
+	}
+
+	messages := make([]dto.DevSmsMessage, 0)
+	for _, message := range r.devSmsInbox.Messages(phoneNumber) {
+		messages = append(messages, dto.DevSmsMessage{
+			ID:          message.ID,
+			PhoneNumber: message.PhoneNumber,
+			Content:     message.Content,
+			CreatedAt:   message.CreatedAt,
+		})
+	}
+
+	return messages
+}
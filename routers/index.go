@@ -1,80 +1,194 @@
 package routers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
 	"github.com/gin-gonic/gin"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"tbox_backend/external"
 	"tbox_backend/internal/constants"
 	"tbox_backend/internal/dto"
+	e "tbox_backend/internal/errors"
 	"tbox_backend/internal/helpers"
 	"tbox_backend/internal/services"
 	"tbox_backend/internal/validator"
+	"time"
 )
 
 const OtpRequestKey = "OtpRequest"
+const EmailOtpRequestKey = "EmailOtpRequest"
+const UserKey = "User"
+const TokenInfoKey = "TokenInfo"
+const RateLimitKey = "RateLimit"
+
+const maxWebhookBodySize = 64 << 10
+const maxPeekBodySize = 64 << 10
+
+// emailLimiterPrefix keeps the limiters of emails apart from those of phone numbers.
+const emailLimiterPrefix = "email:"
 
 type Router struct {
-	userService        services.IUserService
-	userValidator      validator.IUserValidator
-	phoneNumberLimiter *helpers.PhoneNumberRateLimiters
+	userService         services.IUserService
+	userValidator       validator.IUserValidator
+	phoneNumberLimiter  helpers.IRateLimiter
+	voiceLimiter        helpers.IRateLimiter
+	smsService          external.ISmsService
+	devSmsInbox         *external.DevSmsInbox
+	rateLimitPolicies   *helpers.RateLimitPolicies
+	otpChallengeService services.IOtpChallengeService
 }
 
-func NewRouter(userService services.IUserService, userValidator validator.IUserValidator, phoneNumberLimiter *helpers.PhoneNumberRateLimiters) *Router {
+func NewRouter(
+	userService services.IUserService,
+	userValidator validator.IUserValidator,
+	phoneNumberLimiter helpers.IRateLimiter,
+	voiceLimiter helpers.IRateLimiter,
+	smsService external.ISmsService,
+	devSmsInbox *external.DevSmsInbox,
+	rateLimitPolicies *helpers.RateLimitPolicies,
+	otpChallengeService services.IOtpChallengeService,
+) *Router {
+	// Without policies no route is limited, and clients are still told apart by their remote address alone.
+	if rateLimitPolicies == nil {
+		rateLimitPolicies = &helpers.RateLimitPolicies{}
+	}
+
 	return &Router{
-		userService:        userService,
-		userValidator:      userValidator,
-		phoneNumberLimiter: phoneNumberLimiter,
+		userService:         userService,
+		userValidator:       userValidator,
+		phoneNumberLimiter:  phoneNumberLimiter,
+		voiceLimiter:        voiceLimiter,
+		smsService:          smsService,
+		devSmsInbox:         devSmsInbox,
+		rateLimitPolicies:   rateLimitPolicies,
+		otpChallengeService: otpChallengeService,
 	}
 }
 
 func (r *Router) IndexRouter(rg *gin.Engine) {
-	gr := rg.Group("/api")
+	rg.GET("/.well-known/jwks.json", r.jwksHandler)
+
+	// The dev inbox is only created in Local and Staging.
+	if r.devSmsInbox != nil {
+		dev := rg.Group("/dev/sms")
+		{
+			dev.GET("", r.devSmsPageHandler)
+			dev.GET("/inbox", r.devSmsInboxHandler)
+		}
+	}
+
+	gr := rg.Group("/api", r.rateLimitPolicy)
 	{
-		gr.POST("/generate_otp", r.rateLimit, r.generateOtpHandler)
-		gr.POST("/resend_otp", r.rateLimit, r.resendOtpHandler)
+		gr.POST("/generate_otp", r.challengeOtp, r.rateLimit, r.generateOtpHandler)
+		gr.POST("/resend_otp", r.challengeOtp, r.rateLimit, r.resendOtpHandler)
 		gr.POST("/login", r.loginHandler)
+		gr.POST("/email/otp", r.emailRateLimit, r.emailOtpHandler)
+		gr.POST("/email/login", r.emailLoginHandler)
+		gr.POST("/email/magic_link", r.emailRateLimit, r.magicLinkHandler)
+		gr.POST("/email/magic_link/login", r.magicLinkLoginHandler)
+		gr.POST("/token/refresh", r.refreshTokenHandler)
+		gr.POST("/logout", r.authenticate, r.logoutHandler)
+		gr.POST("/logout_all", r.authenticate, r.logoutAllHandler)
+		gr.POST("/oauth/introspect", r.introspectHandler)
+		gr.GET("/otp/status", r.otpStatusHandler)
+		gr.POST("/webhooks/sms/:provider", r.smsWebhookHandler)
+
+		admin := gr.Group("/admin", r.authenticateClient)
+		{
+			admin.GET("/sms/providers", r.smsProvidersHandler)
+			admin.GET("/otp/countries", r.otpCountryStatsHandler)
+			admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+		}
+
+		me := gr.Group("/me", r.authenticate)
+		{
+			me.GET("", r.meHandler)
+			me.GET("/devices", r.devicesHandler)
+			me.POST("/devices", r.registerDeviceHandler)
+			me.DELETE("/devices/:device_id", r.revokeDeviceHandler)
+		}
 	}
 }
 
 // @Summary Generate otp
-// @Description Generate otp and send otp to phone number. OTP will be printed in console log.
+// @Description Generate otp and send otp to phone number by SMS or, with the voice channel, by a call. The message is written in the locale of the request, or of the Accept-Language header. When the risk of the request is elevated, status 207 returns a challenge: send the request again with the challenge and either a proof of work nonce or a CAPTCHA token. Once sent, status_token gives access to the delivery status of the OTP.
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred locales of the SMS"
 // @Param Body body dto.GenerateOtpRequest true "Body"
-// @Success 200 {object} dto.GenerateOtpResponse
+// @Success 200 {object} dto.OtpChallengeResponse
 // @Router /generate_otp [post]
 func (r *Router) generateOtpHandler(ctx *gin.Context) {
-	generateOtpRequest := ctx.MustGet(OtpRequestKey)
-	err := r.userService.GenerateOtp(generateOtpRequest.(dto.GenerateOtpRequest).PhoneNumber)
+	generateOtpRequest := ctx.MustGet(OtpRequestKey).(dto.GenerateOtpRequest)
+	statusToken, err := r.userService.GenerateOtp(generateOtpRequest.PhoneNumber, otpLocale(ctx, generateOtpRequest), generateOtpRequest.Channel)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, err.Error()))
+		setOtpRetryAfter(ctx, err)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(otpErrorStatus(err), err.Error()))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SuccessStatus, "Success"))
+	ctx.JSON(http.StatusOK, dto.NewOtpSentResponse(constants.SuccessStatus, "Success", statusToken))
 	return
 }
 
+// otpLocale prefers the locale of the request over the Accept-Language header.
+func otpLocale(ctx *gin.Context, generateOtpRequest dto.GenerateOtpRequest) string {
+	if generateOtpRequest.Locale != "" {
+		return generateOtpRequest.Locale
+	}
+
+	return ctx.GetHeader("Accept-Language")
+}
+
+// otpErrorStatus lets clients tell a locked phone number or an OTP invalidated by too many attempts
+// apart from other failures.
+func otpErrorStatus(err error) int {
+	switch err.(type) {
+	case e.InvalidOtpChannelError, e.InvalidEmailError:
+		return constants.InvalidRequestStatus
+	case e.TooManyOtpAttemptsError:
+		return constants.OtpAttemptsExceededStatus
+	case e.LockedPhoneNumberError, e.LockedEmailError:
+		return constants.PhoneNumberLockedStatus
+	case e.BlockedPhoneNumberError:
+		return constants.PhoneNumberBlockedStatus
+	default:
+		return constants.SomethingWentWrongStatus
+	}
+}
+
 // @Summary Resend otp
-// @Description Generate new otp and send otp to phone number. OTP will be printed in console log.
+// @Description Generate new otp and send otp to phone number by SMS or, with the voice channel, by a call. The message is written in the locale of the request, or of the Accept-Language header. Like generate_otp, status 207 returns a challenge when the risk of the request is elevated.
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred locales of the SMS"
 // @Param Body body dto.GenerateOtpRequest true "Body"
-// @Success 200 {object} dto.GenerateOtpResponse
+// @Success 200 {object} dto.OtpSentResponse
 // @Router /resend_otp [post]
 func (r *Router) resendOtpHandler(ctx *gin.Context) {
-	generateOtpRequest := ctx.MustGet(OtpRequestKey)
-	err := r.userService.ResendOtp(generateOtpRequest.(dto.GenerateOtpRequest).PhoneNumber)
+	generateOtpRequest := ctx.MustGet(OtpRequestKey).(dto.GenerateOtpRequest)
+	statusToken, err := r.userService.ResendOtp(generateOtpRequest.PhoneNumber, otpLocale(ctx, generateOtpRequest), generateOtpRequest.Channel)
 	if err != nil {
-		ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, err.Error()))
+		setOtpRetryAfter(ctx, err)
+		ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(otpErrorStatus(err), err.Error()))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SuccessStatus, "Success"))
+	ctx.JSON(http.StatusOK, dto.NewOtpSentResponse(constants.SuccessStatus, "Success", statusToken))
 	return
 }
 
 // @Summary Login
-// @Description Verify otp and return access_token. OTP is not necessary after the fist successful login
+// @Description Verify otp and return access_token. OTP is required on every login unless a trusted device_id and device_secret are given instead.
 // @Accept  json
 // @Produce  json
 // @Param Body body dto.LoginRequest true "Body"
@@ -83,12 +197,122 @@ func (r *Router) resendOtpHandler(ctx *gin.Context) {
 func (r *Router) loginHandler(ctx *gin.Context) {
 	var loginRequest dto.LoginRequest
 	if err := ctx.ShouldBindJSON(&loginRequest); err != nil {
-		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.InvalidRequestStatus, err.Error(), ""))
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.InvalidRequestStatus, err.Error(), dto.Token{}))
+		return
+	}
+
+	var token dto.Token
+	var err error
+	if loginRequest.DeviceID != "" {
+		deviceCredential := dto.DeviceCredential{DeviceID: loginRequest.DeviceID, DeviceSecret: loginRequest.DeviceSecret}
+		token, err = r.userService.LoginWithDevice(loginRequest.PhoneNumber, deviceCredential, r.rateLimitPolicies.ClientIP(ctx.Request))
+	} else {
+		token, err = r.userService.Login(loginRequest.PhoneNumber, loginRequest.Otp)
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(otpErrorStatus(err), err.Error(), token))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.SuccessStatus, "Success", token))
+	return
+}
+
+// @Summary Generate email otp
+// @Description Generate otp and send otp to the email. The user is created on first use. The email is written in the locale of the request, or of the Accept-Language header.
+// @Accept json
+// @Produce json
+// @Param Accept-Language header string false "Preferred locales of the email"
+// @Param Body body dto.EmailOtpRequest true "Body"
+// @Success 200 {object} dto.GenerateOtpResponse
+// @Router /email/otp [post]
+func (r *Router) emailOtpHandler(ctx *gin.Context) {
+	emailOtpRequest := ctx.MustGet(EmailOtpRequestKey).(dto.EmailOtpRequest)
+	err := r.userService.GenerateEmailOtp(emailOtpRequest.Email, emailLocale(ctx, emailOtpRequest))
+	if err != nil {
+		setOtpRetryAfter(ctx, err)
+		ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(otpErrorStatus(err), err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// emailLocale prefers the locale of the request over the Accept-Language header.
+func emailLocale(ctx *gin.Context, emailOtpRequest dto.EmailOtpRequest) string {
+	if emailOtpRequest.Locale != "" {
+		return emailOtpRequest.Locale
+	}
+
+	return ctx.GetHeader("Accept-Language")
+}
+
+// @Summary Login with email
+// @Description Verify the otp sent to the email and return access_token.
+// @Accept  json
+// @Produce  json
+// @Param Body body dto.EmailLoginRequest true "Body"
+// @Success 200 {object} dto.LoginResponse
+// @Router /email/login [post]
+func (r *Router) emailLoginHandler(ctx *gin.Context) {
+	var emailLoginRequest dto.EmailLoginRequest
+	if err := ctx.ShouldBindJSON(&emailLoginRequest); err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.InvalidRequestStatus, err.Error(), dto.Token{}))
 		return
 	}
 
-	token, err := r.userService.Login(loginRequest.PhoneNumber, loginRequest.Otp)
+	token, err := r.userService.LoginWithEmail(emailLoginRequest.Email, emailLoginRequest.Otp)
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(otpErrorStatus(err), err.Error(), token))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.SuccessStatus, "Success", token))
+	return
+}
+
+// @Summary Send magic link
+// @Description Send a signed link to the email which logs in once. The user is created on first use.
+// @Accept json
+// @Produce json
+// @Param Accept-Language header string false "Preferred locales of the email"
+// @Param Body body dto.EmailOtpRequest true "Body"
+// @Success 200 {object} dto.GenerateOtpResponse
+// @Router /email/magic_link [post]
+func (r *Router) magicLinkHandler(ctx *gin.Context) {
+	emailOtpRequest := ctx.MustGet(EmailOtpRequestKey).(dto.EmailOtpRequest)
+	err := r.userService.SendMagicLink(emailOtpRequest.Email, emailLocale(ctx, emailOtpRequest))
 	if err != nil {
+		setOtpRetryAfter(ctx, err)
+		ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(otpErrorStatus(err), err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// @Summary Login with magic link
+// @Description Exchange the token of a magic link for an access_token. The web page of the link posts the token, a GET link would be used up by email scanners opening it.
+// @Accept  json
+// @Produce  json
+// @Param Body body dto.MagicLinkLoginRequest true "Body"
+// @Success 200 {object} dto.LoginResponse
+// @Router /email/magic_link/login [post]
+func (r *Router) magicLinkLoginHandler(ctx *gin.Context) {
+	var magicLinkLoginRequest dto.MagicLinkLoginRequest
+	if err := ctx.ShouldBindJSON(&magicLinkLoginRequest); err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.InvalidRequestStatus, err.Error(), dto.Token{}))
+		return
+	}
+
+	token, err := r.userService.LoginWithMagicLink(magicLinkLoginRequest.Token)
+	if _, ok := err.(e.InvalidMagicLinkError); ok {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.UnauthorizedStatus, err.Error(), token))
+		return
+	} else if err != nil {
 		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.SomethingWentWrongStatus, err.Error(), token))
 		return
 	}
@@ -97,6 +321,358 @@ func (r *Router) loginHandler(ctx *gin.Context) {
 	return
 }
 
+// @Summary Refresh token
+// @Description Exchange a refresh token for a new access_token. The refresh token is rotated and can only be used once.
+// @Accept  json
+// @Produce  json
+// @Param Body body dto.RefreshTokenRequest true "Body"
+// @Success 200 {object} dto.LoginResponse
+// @Router /token/refresh [post]
+func (r *Router) refreshTokenHandler(ctx *gin.Context) {
+	var refreshTokenRequest dto.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&refreshTokenRequest); err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.InvalidRequestStatus, err.Error(), dto.Token{}))
+		return
+	}
+
+	token, err := r.userService.RefreshToken(refreshTokenRequest.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.SomethingWentWrongStatus, err.Error(), token))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewLoginResponse(constants.SuccessStatus, "Success", token))
+	return
+}
+
+// @Summary Current user
+// @Description Return the user the access_token was issued to.
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Success 200 {object} dto.UserResponse
+// @Router /me [get]
+func (r *Router) meHandler(ctx *gin.Context) {
+	user := ctx.MustGet(UserKey).(*dto.User)
+	ctx.JSON(http.StatusOK, dto.NewUserResponse(constants.SuccessStatus, "Success", user))
+	return
+}
+
+// @Summary List devices
+// @Description List the trusted devices of the current user.
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Success 200 {object} dto.DevicesResponse
+// @Router /me/devices [get]
+func (r *Router) devicesHandler(ctx *gin.Context) {
+	user := ctx.MustGet(UserKey).(*dto.User)
+	devices, err := r.userService.GetDevices(user)
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewDevicesResponse(constants.SomethingWentWrongStatus, err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewDevicesResponse(constants.SuccessStatus, "Success", devices))
+	return
+}
+
+// @Summary Register device
+// @Description Register the current device as trusted, with the access token of an OTP login of the last few minutes. The device_secret is only returned once and lets the device log in without an OTP.
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Param Body body dto.RegisterDeviceRequest false "Body"
+// @Success 200 {object} dto.DeviceResponse
+// @Router /me/devices [post]
+func (r *Router) registerDeviceHandler(ctx *gin.Context) {
+	var registerDeviceRequest dto.RegisterDeviceRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&registerDeviceRequest); err != nil {
+			ctx.JSON(http.StatusOK, dto.NewDeviceResponse(constants.InvalidRequestStatus, err.Error(), nil))
+			return
+		}
+	}
+
+	user := ctx.MustGet(UserKey).(*dto.User)
+	tokenInfo := ctx.MustGet(TokenInfoKey).(dto.TokenInfo)
+	deviceCredential, err := r.userService.RegisterDevice(user, tokenInfo, registerDeviceRequest.Name)
+	if _, ok := err.(e.OtpLoginRequiredError); ok {
+		ctx.JSON(http.StatusOK, dto.NewDeviceResponse(constants.UnauthorizedStatus, err.Error(), nil))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewDeviceResponse(constants.SomethingWentWrongStatus, err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewDeviceResponse(constants.SuccessStatus, "Success", &deviceCredential))
+	return
+}
+
+// @Summary Revoke device
+// @Description Revoke a trusted device of the current user.
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Param device_id path string true "Device ID"
+// @Success 200 {object} dto.Response
+// @Router /me/devices/{device_id} [delete]
+func (r *Router) revokeDeviceHandler(ctx *gin.Context) {
+	user := ctx.MustGet(UserKey).(*dto.User)
+	err := r.userService.RevokeDevice(user, ctx.Param("device_id"))
+	if _, ok := err.(e.NotExistsDeviceError); ok {
+		ctx.JSON(http.StatusOK, dto.NewResponse(constants.InvalidRequestStatus, err.Error()))
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewResponse(constants.SomethingWentWrongStatus, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// @Summary Logout
+// @Description Revoke the access_token and, when given, the refresh_token of the current session.
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Param Body body dto.LogoutRequest false "Body"
+// @Success 200 {object} dto.Response
+// @Router /logout [post]
+func (r *Router) logoutHandler(ctx *gin.Context) {
+	var logoutRequest dto.LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&logoutRequest); err != nil {
+			ctx.JSON(http.StatusOK, dto.NewResponse(constants.InvalidRequestStatus, err.Error()))
+			return
+		}
+	}
+
+	tokenInfo := ctx.MustGet(TokenInfoKey).(dto.TokenInfo)
+	err := r.userService.Logout(tokenInfo, logoutRequest.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewResponse(constants.SomethingWentWrongStatus, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// @Summary Logout from all sessions
+// @Description Revoke every access_token and refresh_token issued to the current user.
+// @Produce  json
+// @Param Authorization header string true "Bearer access_token"
+// @Success 200 {object} dto.Response
+// @Router /logout_all [post]
+func (r *Router) logoutAllHandler(ctx *gin.Context) {
+	user := ctx.MustGet(UserKey).(*dto.User)
+	err := r.userService.LogoutAll(user)
+	if err != nil {
+		ctx.JSON(http.StatusOK, dto.NewResponse(constants.SomethingWentWrongStatus, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// @Summary OTP delivery status
+// @Description Return the delivery status (queued, sent, delivered or failed) of the pending OTP of the phone number. status_token is returned by generate_otp and resend_otp, without it no status is returned.
+// @Produce  json
+// @Param phone_number query string true "Phone number"
+// @Param status_token query string true "Status token of the OTP"
+// @Success 200 {object} dto.OtpStatusResponse
+// @Router /otp/status [get]
+func (r *Router) otpStatusHandler(ctx *gin.Context) {
+	otpStatus, err := r.userService.GetOtpStatus(ctx.Query("phone_number"), ctx.Query("status_token"))
+	switch err.(type) {
+	case nil:
+	case e.InvalidPhoneNumberError, e.NotSentOtpError:
+		ctx.JSON(http.StatusOK, dto.NewOtpStatusResponse(constants.InvalidRequestStatus, err.Error(), nil))
+		return
+	default:
+		log.Println("Failed to get OTP status", err)
+		ctx.JSON(http.StatusOK, dto.NewOtpStatusResponse(constants.SomethingWentWrongStatus, "Something went wrong ", nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewOtpStatusResponse(constants.SuccessStatus, "Success", &otpStatus))
+	return
+}
+
+// @Summary SMS delivery report
+// @Description Webhook for delivery reports of the named SMS provider. The request must carry the signature of the provider.
+// @Accept  json
+// @Produce  json
+// @Param provider path string true "SMS provider name"
+// @Success 200 {object} dto.Response
+// @Router /webhooks/sms/{provider} [post]
+func (r *Router) smsWebhookHandler(ctx *gin.Context) {
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.NewResponse(constants.InvalidRequestStatus, err.Error()))
+		return
+	}
+
+	report, err := r.smsService.ParseDeliveryReport(ctx.Param("provider"), ctx.Request, body)
+	switch err.(type) {
+	case nil:
+	case external.UnknownSmsProviderError:
+		ctx.AbortWithStatusJSON(http.StatusNotFound, dto.NewResponse(constants.InvalidRequestStatus, err.Error()))
+		return
+	case external.InvalidSmsSignatureError:
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, dto.NewResponse(constants.UnauthorizedStatus, err.Error()))
+		return
+	default:
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.NewResponse(constants.InvalidRequestStatus, err.Error()))
+		return
+	}
+
+	// Providers retry webhooks on non-2xx responses, so only a failure on our side is reported as such.
+	err = r.userService.UpdateSmsDeliveryStatus(dto.SmsDeliveryReport{
+		Provider:  report.Provider,
+		MessageID: report.MessageID,
+		Status:    report.Status,
+	})
+
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, dto.NewResponse(constants.SomethingWentWrongStatus, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewResponse(constants.SuccessStatus, "Success"))
+	return
+}
+
+// @Summary SMS providers
+// @Description Circuit breaker state (closed, open or half_open) and counters of every SMS provider, in priority order. Clients authenticate with HTTP Basic.
+// @Produce  json
+// @Success 200 {object} dto.SmsProvidersResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /admin/sms/providers [get]
+func (r *Router) smsProvidersHandler(ctx *gin.Context) {
+	providers := make([]dto.SmsProviderState, 0)
+	for _, state := range r.smsService.ProviderStates() {
+		providers = append(providers, dto.SmsProviderState{
+			Name:                state.Name,
+			Priority:            state.Priority,
+			State:               state.State,
+			ConsecutiveFailures: state.ConsecutiveFailures,
+			OpenedAt:            state.OpenedAt,
+			Requests:            state.Requests,
+			Failures:            state.Failures,
+			Rejected:            state.Rejected,
+			Trips:               state.Trips,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewSmsProvidersResponse(constants.SuccessStatus, "Success", providers))
+	return
+}
+
+// @Summary OTP country stats
+// @Description OTPs sent to every country on a UTC day and the ones refused by the country policy. Clients authenticate with HTTP Basic.
+// @Produce  json
+// @Param day query string false "Day as YYYY-MM-DD, today when empty"
+// @Success 200 {object} dto.OtpCountryStatsResponse
+// @Failure 400 {object} dto.OtpCountryStatsResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /admin/otp/countries [get]
+func (r *Router) otpCountryStatsHandler(ctx *gin.Context) {
+	day := time.Now().UTC()
+	if ctx.Query("day") != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", ctx.Query("day"))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.NewOtpCountryStatsResponse(constants.InvalidRequestStatus, "Day invalid ", nil))
+			return
+		}
+	}
+
+	stats, err := r.userService.GetOtpCountryStats(day)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, dto.NewOtpCountryStatsResponse(constants.SomethingWentWrongStatus, err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewOtpCountryStatsResponse(constants.SuccessStatus, "Success", stats))
+	return
+}
+
+// @Summary Token introspection
+// @Description RFC 7662 token introspection. Clients authenticate with HTTP Basic or client_id/client_secret form fields.
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param token formData string true "Access token"
+// @Param token_type_hint formData string false "Token type hint"
+// @Success 200 {object} dto.IntrospectionResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /oauth/introspect [post]
+func (r *Router) introspectHandler(ctx *gin.Context) {
+	clientID, clientSecret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+
+	if !r.userService.AuthenticateClient(clientID, clientSecret) {
+		ctx.Header("WWW-Authenticate", `Basic realm="introspect"`)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, dto.OAuthErrorResponse{Error: "invalid_client"})
+		return
+	}
+
+	token := ctx.PostForm("token")
+	if token == "" {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.OAuthErrorResponse{Error: "invalid_request"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	tokenInfo, active, err := r.userService.IntrospectToken(token)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, dto.OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.NewIntrospectionResponse(active, tokenInfo))
+	return
+}
+
+// jwksHandler publishes the public keys used to sign access tokens, so other services can verify them.
+func (r *Router) jwksHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, r.userService.GetJwks())
+	return
+}
+
+func (r *Router) authenticate(ctx *gin.Context) {
+	authorization := ctx.GetHeader("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewUserResponse(constants.UnauthorizedStatus, "Missing access token ", nil))
+		return
+	}
+
+	user, tokenInfo, err := r.userService.Authenticate(strings.TrimPrefix(authorization, "Bearer "))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewUserResponse(constants.UnauthorizedStatus, err.Error(), nil))
+		return
+	}
+
+	ctx.Set(UserKey, user)
+	ctx.Set(TokenInfoKey, tokenInfo)
+	return
+}
+
+// authenticateClient lets the OAuth clients in, with HTTP Basic credentials.
+func (r *Router) authenticateClient(ctx *gin.Context) {
+	clientID, clientSecret, _ := ctx.Request.BasicAuth()
+	if !r.userService.AuthenticateClient(clientID, clientSecret) {
+		ctx.Header("WWW-Authenticate", `Basic realm="admin"`)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, dto.OAuthErrorResponse{Error: "invalid_client"})
+		return
+	}
+
+	return
+}
+
 func (r *Router) rateLimit(ctx *gin.Context) {
 	var generateOtpRequest dto.GenerateOtpRequest
 	if err := ctx.ShouldBindJSON(&generateOtpRequest); err != nil {
@@ -104,14 +680,22 @@ func (r *Router) rateLimit(ctx *gin.Context) {
 		return
 	}
 
-	if valid := r.userValidator.IsPhoneNumberValid(generateOtpRequest.PhoneNumber); !valid {
+	phoneNumber, _, valid := r.userValidator.NormalizePhoneNumber(generateOtpRequest.PhoneNumber)
+	if !valid {
 		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.InvalidRequestStatus, "Phone number invalid "))
 		return
 	}
 
-	limiter := r.phoneNumberLimiter.GetLimiter(generateOtpRequest.PhoneNumber)
-	if !limiter.Allow() {
-		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.TooManyRequestStatus, "Too many requests "))
+	// Limit the number however it was written, so 0967... and +84967... share a limiter.
+	generateOtpRequest.PhoneNumber = phoneNumber
+
+	// Voice calls cost more than SMS, they are limited separately.
+	limiter := r.phoneNumberLimiter
+	if generateOtpRequest.Channel == constants.OtpVoiceChannel {
+		limiter = r.voiceLimiter
+	}
+
+	if !r.allow(ctx, limiter, generateOtpRequest.PhoneNumber) {
 		return
 	}
 
@@ -119,3 +703,173 @@ func (r *Router) rateLimit(ctx *gin.Context) {
 	return
 }
 
+// rateLimitPolicy applies the limits declared for the route by client IP, device, phone number prefix and
+// across all clients. Those hold when phone numbers are rotated, which the limits per phone number cannot catch.
+func (r *Router) rateLimitPolicy(ctx *gin.Context) {
+	path := ctx.Request.URL.Path
+	if !r.rateLimitPolicies.HasLimits(path) {
+		return
+	}
+
+	keys := helpers.RateLimitKeys{
+		IP:       r.rateLimitPolicies.ClientIP(ctx.Request),
+		DeviceID: r.rateLimitPolicies.DeviceID(ctx.Request),
+	}
+
+	var phoneNumberRequest struct {
+		PhoneNumber string `json:"phone_number"`
+	}
+	if err := json.Unmarshal(peekBody(ctx), &phoneNumberRequest); err == nil {
+		if phoneNumber, _, valid := r.userValidator.NormalizePhoneNumber(phoneNumberRequest.PhoneNumber); valid {
+			keys.PhoneNumber = phoneNumber
+		}
+	}
+
+	rateLimit, dimension, err := r.rateLimitPolicies.Allow(path, keys)
+	if err != nil {
+		log.Println("Failed to check rate limit", err)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, "Something went wrong "))
+		return
+	}
+
+	setRateLimitHeaders(ctx, rateLimit)
+	if !rateLimit.Allowed {
+		log.Println("Rate limit exceeded", path, dimension, keys.IP)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.TooManyRequestStatus, "Too many requests "))
+		return
+	}
+
+	return
+}
+
+// challengeOtp asks OTP requests whose risk is elevated to solve a challenge before rateLimit lets them through.
+// Requests it cannot read are left to rateLimit to refuse.
+func (r *Router) challengeOtp(ctx *gin.Context) {
+	if r.otpChallengeService == nil {
+		return
+	}
+
+	var generateOtpRequest dto.GenerateOtpRequest
+	if err := json.Unmarshal(peekBody(ctx), &generateOtpRequest); err != nil {
+		return
+	}
+
+	phoneNumber, countryCode, valid := r.userValidator.NormalizePhoneNumber(generateOtpRequest.PhoneNumber)
+	if !valid {
+		return
+	}
+
+	// The client IP is the one the rate limit policies see, X-Forwarded-For is only trusted from trusted proxies.
+	clientIP := r.rateLimitPolicies.ClientIP(ctx.Request)
+	solution := dto.OtpChallengeSolution{
+		Challenge:    generateOtpRequest.Challenge,
+		Nonce:        generateOtpRequest.Nonce,
+		CaptchaToken: generateOtpRequest.CaptchaToken,
+	}
+
+	err := r.otpChallengeService.Check(ctx.Request.Context(), phoneNumber, countryCode, clientIP, solution)
+	if err == nil {
+		return
+	}
+
+	challengeRequiredErr, ok := err.(e.OtpChallengeRequiredError)
+	if !ok {
+		log.Println("Failed to check OTP challenge", err)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, "Something went wrong "))
+		return
+	}
+
+	challenge, err := r.otpChallengeService.NewChallenge(phoneNumber, challengeRequiredErr.Reason)
+	if err != nil {
+		log.Println("Failed to create OTP challenge", err)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, "Something went wrong "))
+		return
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusOK, dto.NewOtpChallengeResponse(constants.OtpChallengeRequiredStatus, challengeRequiredErr.Error(), challenge))
+	return
+}
+
+// peekBody reads the start of the body for a middleware. The body is read again by the handler, only what was
+// read here is put back in front of the rest.
+func peekBody(ctx *gin.Context) []byte {
+	body, _ := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, maxPeekBodySize))
+	ctx.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+	return body
+}
+
+// emailRateLimit limits OTP and magic link emails per email, at the rate of phone numbers.
+func (r *Router) emailRateLimit(ctx *gin.Context) {
+	var emailOtpRequest dto.EmailOtpRequest
+	if err := ctx.ShouldBindJSON(&emailOtpRequest); err != nil {
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.InvalidRequestStatus, err.Error()))
+		return
+	}
+
+	if valid := r.userValidator.IsEmailValid(emailOtpRequest.Email); !valid {
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.InvalidRequestStatus, "Email invalid "))
+		return
+	}
+
+	// Emails can be longer than the limiter_key column, they are hashed to keep their keys its size.
+	hash := sha256.Sum256([]byte(strings.ToLower(emailOtpRequest.Email)))
+	if !r.allow(ctx, r.phoneNumberLimiter, emailLimiterPrefix+hex.EncodeToString(hash[:])) {
+		return
+	}
+
+	ctx.Set(EmailOtpRequestKey, emailOtpRequest)
+	return
+}
+
+// allow takes a token of the key from the limiter, the request is aborted when there is none left. Requests are
+// refused too when the limiter cannot be reached, OTPs could not be stored anyway.
+func (r *Router) allow(ctx *gin.Context, limiter helpers.IRateLimiter, key string) bool {
+	rateLimit, err := limiter.Allow(key)
+	if err != nil {
+		log.Println("Failed to check rate limit", err)
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.SomethingWentWrongStatus, "Something went wrong "))
+		return false
+	}
+
+	setRateLimitHeaders(ctx, rateLimit)
+	if !rateLimit.Allowed {
+		ctx.AbortWithStatusJSON(http.StatusOK, dto.NewGenerateOtpResponse(constants.TooManyRequestStatus, "Too many requests "))
+		return false
+	}
+
+	return true
+}
+
+// setRateLimitHeaders reports the limit of the request with the RateLimit headers of the IETF draft, and Retry-After
+// when it is refused. A request checked by several limits reports the one with the fewest tokens left.
+func setRateLimitHeaders(ctx *gin.Context, rateLimit helpers.RateLimit) {
+	if rateLimit.Limit <= 0 {
+		return
+	}
+
+	if value, exists := ctx.Get(RateLimitKey); exists {
+		reported := value.(helpers.RateLimit)
+		if rateLimit.Allowed && rateLimit.Remaining >= reported.Remaining {
+			return
+		}
+	}
+
+	ctx.Set(RateLimitKey, rateLimit)
+	ctx.Header("RateLimit-Limit", strconv.Itoa(rateLimit.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(rateLimit.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(rateLimit.Reset)))
+	if !rateLimit.Allowed {
+		ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(rateLimit.RetryAfter)))
+	}
+}
+
+// setOtpRetryAfter tells clients when an OTP still in its cooldown can be sent again.
+func setOtpRetryAfter(ctx *gin.Context, err error) {
+	if generatedOtpError, ok := err.(e.GeneratedOtpError); ok {
+		ctx.Header("Retry-After", strconv.Itoa(generatedOtpError.RetryAfterSeconds()))
+	}
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -8,13 +8,22 @@ import (
 	"github.com/golang/mock/gomock"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"tbox_backend/config"
+	"tbox_backend/external"
 	"tbox_backend/internal/constants"
 	"tbox_backend/internal/dto"
+	e "tbox_backend/internal/errors"
 	"tbox_backend/internal/helpers"
+	"tbox_backend/internal/stores"
 	"tbox_backend/internal/validator"
+	mockExternal "tbox_backend/mock/external"
 	mockServices "tbox_backend/mock/services"
+	mockStores "tbox_backend/mock/stores"
 	"tbox_backend/routers"
 	"testing"
+	"time"
 )
 
 func performRequest(r http.Handler, method, path string, body *bytes.Reader) *httptest.ResponseRecorder {
@@ -25,16 +34,16 @@ func performRequest(r http.Handler, method, path string, body *bytes.Reader) *ht
 }
 
 func Test_GenerateOtp_Success(t *testing.T) {
-	phoneNumber := "0967288123"
+	phoneNumber := "+84967288123"
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber)).Return(nil)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Eq(""), gomock.Eq("")).Return("status_token", nil)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -49,7 +58,7 @@ func Test_GenerateOtp_Success(t *testing.T) {
 		t.Fatalf("expected status %d", http.StatusOK)
 	}
 
-	var response dto.GenerateOtpResponse
+	var response dto.OtpSentResponse
 	err := json.Unmarshal([]byte(w.Body.String()), &response)
 	if err != nil {
 		t.Fatal(err)
@@ -59,8 +68,8 @@ func Test_GenerateOtp_Success(t *testing.T) {
 		t.Fatalf("Expected success")
 	}
 
-	if response.Status != constants.SuccessStatus {
-		t.Fatalf("Expected SuccessStatus")
+	if response.Status != constants.SuccessStatus || response.StatusToken != "status_token" {
+		t.Fatalf("Expected SuccessStatus with the status token")
 	}
 }
 
@@ -72,7 +81,7 @@ func Test_GenerateOtp_InvalidRequest(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader([]byte("random_text")))
@@ -100,7 +109,7 @@ func Test_GenerateOtp_InvalidPhoneNumber(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -135,7 +144,7 @@ func Test_GenerateOtp_RateLimit(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 0)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -162,16 +171,16 @@ func Test_GenerateOtp_RateLimit(t *testing.T) {
 }
 
 func Test_GenerateOtp_Error(t *testing.T) {
-	phoneNumber := "0967288123"
+	phoneNumber := "+84967288123"
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber)).Return(errors.New("Something went wrong "))
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Eq(""), gomock.Eq("")).Return("", errors.New("Something went wrong "))
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -196,18 +205,46 @@ func Test_GenerateOtp_Error(t *testing.T) {
 		t.Fatalf("Expected SuccessStatus")
 	}
 }
+func Test_GenerateOtp_Blocked(t *testing.T) {
+	phoneNumber := "+84967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Eq(""), gomock.Eq("")).
+		Return("", e.BlockedPhoneNumberError{PhoneNumber: phoneNumber, Reason: constants.OtpDailyLimitReason})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": phoneNumber})
+	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.PhoneNumberBlockedStatus {
+		t.Fatalf("expected PhoneNumberBlockedStatus, got %d", response.Status)
+	}
+}
 
 func Test_ResendOtp_Success(t *testing.T) {
-	phoneNumber := "0967288123"
+	phoneNumber := "+84967288123"
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().ResendOtp(gomock.Eq(phoneNumber)).Return(nil)
+	userService.EXPECT().ResendOtp(gomock.Eq(phoneNumber), gomock.Eq(""), gomock.Eq("")).Return("", nil)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -245,7 +282,7 @@ func Test_ResendOtp_InvalidRequest(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 	w := performRequest(router, "POST", "/api/resend_otp", bytes.NewReader([]byte("random_text")))
@@ -274,7 +311,7 @@ func Test_ResendOtp_InvalidPhoneNumber(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -309,7 +346,7 @@ func Test_Resend_RateLimit(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 0)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -336,16 +373,16 @@ func Test_Resend_RateLimit(t *testing.T) {
 }
 
 func Test_Resend_Error(t *testing.T) {
-	phoneNumber := "0967288123"
+	phoneNumber := "+84967288123"
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().ResendOtp(gomock.Eq(phoneNumber)).Return(errors.New("Something went wrong "))
+	userService.EXPECT().ResendOtp(gomock.Eq(phoneNumber), gomock.Eq(""), gomock.Eq("")).Return("", errors.New("Something went wrong "))
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -378,10 +415,10 @@ func Test_Login_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return("tokentest", nil)
+	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return(dto.Token{AccessToken: "tokentest", RefreshToken: "refreshtest", ExpiresIn: 900}, nil)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0, 0)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 	body := map[string]interface{}{
@@ -412,6 +449,10 @@ func Test_Login_Success(t *testing.T) {
 	if response.Token != "tokentest" {
 		t.Fatalf("Expected success")
 	}
+
+	if response.RefreshToken != "refreshtest" || response.ExpiresIn != 900 {
+		t.Fatalf("Expected refresh token")
+	}
 }
 
 func Test_Login_InvalidRequest(t *testing.T) {
@@ -422,7 +463,7 @@ func Test_Login_InvalidRequest(t *testing.T) {
 	userService := mockServices.NewMockIUserService(ctrl)
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0, 0)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 	w := performRequest(router, "POST", "/api/login", bytes.NewReader([]byte("random_text")))
@@ -449,10 +490,82 @@ func Test_Login_Error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	userService := mockServices.NewMockIUserService(ctrl)
-	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return("", errors.New("Something went wrong "))
+	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return(dto.Token{}, errors.New("Something went wrong "))
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	body := map[string]interface{}{
+		"phone_number": phoneNumber,
+	}
+
+	postJson, _ := json.Marshal(body)
+	w := performRequest(router, "POST", "/api/login", bytes.NewReader(postJson))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SomethingWentWrongStatus {
+		t.Fatalf("Expected SuccessStatus")
+	}
+}
+
+func Test_Login_TooManyOtpAttempts(t *testing.T) {
+	phoneNumber := "0967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return(dto.Token{}, e.TooManyOtpAttemptsError{})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	body := map[string]interface{}{
+		"phone_number": phoneNumber,
+	}
+
+	postJson, _ := json.Marshal(body)
+	w := performRequest(router, "POST", "/api/login", bytes.NewReader(postJson))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.OtpAttemptsExceededStatus {
+		t.Fatalf("Expected OtpAttemptsExceededStatus")
+	}
+}
+
+func Test_Login_PhoneNumberLocked(t *testing.T) {
+	phoneNumber := "0967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return(dto.Token{}, e.LockedPhoneNumberError{PhoneNumber: phoneNumber, LockedUntil: time.Now().Add(time.Minute)})
 	userValidator := validator.UserValidator{}
 	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
-	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
 
 	r.IndexRouter(router)
 
@@ -473,7 +586,1385 @@ func Test_Login_Error(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if response.Status != constants.PhoneNumberLockedStatus {
+		t.Fatalf("Expected PhoneNumberLockedStatus")
+	}
+}
+
+func Test_Login_Device_Success(t *testing.T) {
+	phoneNumber := "0967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	deviceCredential := dto.DeviceCredential{DeviceID: "device", DeviceSecret: "secret"}
+	// X-Forwarded-For of a client which is not a trusted proxy is not recorded as the address of the device.
+	userService.EXPECT().LoginWithDevice(gomock.Eq(phoneNumber), gomock.Eq(deviceCredential), gomock.Eq("203.0.113.7")).Return(dto.Token{AccessToken: "tokentest", RefreshToken: "refreshtest", ExpiresIn: 900}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0, 0)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	body := map[string]interface{}{
+		"phone_number":  phoneNumber,
+		"device_id":     "device",
+		"device_secret": "secret",
+	}
+
+	postJson, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/api/login", bytes.NewReader(postJson))
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || response.Token != "tokentest" {
+		t.Fatalf("Expected SuccessStatus")
+	}
+}
+
+func Test_RefreshToken_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().RefreshToken(gomock.Eq("refreshtest")).Return(dto.Token{AccessToken: "tokentest", RefreshToken: "newrefreshtest", ExpiresIn: 900}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	body := map[string]interface{}{
+		"refresh_token": "refreshtest",
+	}
+
+	postJson, _ := json.Marshal(body)
+	w := performRequest(router, "POST", "/api/token/refresh", bytes.NewReader(postJson))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus {
+		t.Fatalf("Expected SuccessStatus")
+	}
+
+	if response.Token != "tokentest" || response.RefreshToken != "newrefreshtest" {
+		t.Fatalf("Expected rotated token")
+	}
+}
+
+func Test_RefreshToken_Error(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().RefreshToken(gomock.Eq("refreshtest")).Return(dto.Token{}, errors.New("Refresh token is invalid "))
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	body := map[string]interface{}{
+		"refresh_token": "refreshtest",
+	}
+
+	postJson, _ := json.Marshal(body)
+	w := performRequest(router, "POST", "/api/token/refresh", bytes.NewReader(postJson))
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	if response.Status != constants.SomethingWentWrongStatus {
+		t.Fatalf("Expected SomethingWentWrongStatus")
+	}
+}
+
+func performAuthorizedRequest(r http.Handler, method, path string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func Test_Me_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, dto.TokenInfo{ID: "jti", UserID: 1}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performAuthorizedRequest(router, "GET", "/api/me", "tokentest")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.UserResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus {
 		t.Fatalf("Expected SuccessStatus")
 	}
+
+	if response.User == nil || response.User.ID != 1 || response.User.PhoneNumber != "0967288123" {
+		t.Fatalf("Expected user")
+	}
+}
+
+func Test_Me_MissingToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/api/me", bytes.NewReader(nil))
+
+	var response dto.UserResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.UnauthorizedStatus {
+		t.Fatalf("Expected UnauthorizedStatus")
+	}
+}
+
+func Test_Me_InvalidToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(nil, dto.TokenInfo{}, errors.New("Access token is invalid "))
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performAuthorizedRequest(router, "GET", "/api/me", "tokentest")
+
+	var response dto.UserResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.UnauthorizedStatus {
+		t.Fatalf("Expected UnauthorizedStatus")
+	}
+
+	if response.User != nil {
+		t.Fatalf("Expected no user")
+	}
+}
+
+func Test_Logout_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	tokenInfo := dto.TokenInfo{ID: "jti", UserID: 1}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, tokenInfo, nil)
+	userService.EXPECT().Logout(gomock.Eq(tokenInfo), gomock.Eq("refreshtest")).Return(nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	postJson, _ := json.Marshal(map[string]interface{}{
+		"refresh_token": "refreshtest",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/logout", bytes.NewReader(postJson))
+	req.Header.Set("Authorization", "Bearer tokentest")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.Response
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus {
+		t.Fatalf("Expected SuccessStatus")
+	}
+}
+
+func Test_Logout_Unauthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "POST", "/api/logout", bytes.NewReader(nil))
+
+	var response dto.Response
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.UnauthorizedStatus {
+		t.Fatalf("Expected UnauthorizedStatus")
+	}
+}
+
+func Test_LogoutAll_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, dto.TokenInfo{ID: "jti", UserID: 1}, nil)
+	userService.EXPECT().LogoutAll(gomock.Eq(user)).Return(nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performAuthorizedRequest(router, "POST", "/api/logout_all", "tokentest")
+
+	var response dto.Response
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus {
+		t.Fatalf("Expected SuccessStatus")
+	}
+}
+
+func Test_Jwks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GetJwks().Return(dto.Jwks{Keys: []dto.Jwk{{Kty: "RSA", Kid: "key", Use: "sig", Alg: "RS256", N: "n", E: "AQAB"}}})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/.well-known/jwks.json", bytes.NewReader(nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.Jwks
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Keys) != 1 || response.Keys[0].Kid != "key" {
+		t.Fatalf("Expected key")
+	}
+}
+
+func performIntrospectRequest(r http.Handler, form url.Values, clientID string, clientSecret string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func Test_Introspect_Active(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	tokenInfo := dto.TokenInfo{ID: "jti", UserID: 1, Scope: "user", ClientID: "tbox_app"}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq("gateway"), gomock.Eq("secret")).Return(true)
+	userService.EXPECT().IntrospectToken(gomock.Eq("tokentest")).Return(tokenInfo, true, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performIntrospectRequest(router, url.Values{"token": {"tokentest"}}, "gateway", "secret")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	var response dto.IntrospectionResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Active || response.Sub != "1" || response.Scope != "user" {
+		t.Fatalf("Expected active token")
+	}
+}
+
+func Test_Introspect_FormCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq("gateway"), gomock.Eq("secret")).Return(true)
+	userService.EXPECT().IntrospectToken(gomock.Eq("tokentest")).Return(dto.TokenInfo{}, false, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	form := url.Values{
+		"token":         {"tokentest"},
+		"client_id":     {"gateway"},
+		"client_secret": {"secret"},
+	}
+
+	w := performIntrospectRequest(router, form, "", "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d", http.StatusOK)
+	}
+
+	if strings.TrimSpace(w.Body.String()) != `{"active":false}` {
+		t.Fatalf("Expected inactive token")
+	}
+}
+
+func Test_Introspect_InvalidClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq("gateway"), gomock.Eq("wrong")).Return(false)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performIntrospectRequest(router, url.Values{"token": {"tokentest"}}, "gateway", "wrong")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d", http.StatusUnauthorized)
+	}
+
+	var response dto.OAuthErrorResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Error != "invalid_client" {
+		t.Fatalf("Expected invalid_client")
+	}
+}
+
+func Test_Devices_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, dto.TokenInfo{ID: "jti", UserID: 1}, nil)
+	userService.EXPECT().GetDevices(gomock.Eq(user)).Return([]dto.UserDevice{{DeviceID: "device", Name: "Pixel", SecretHash: "hash"}}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performAuthorizedRequest(router, "GET", "/api/me/devices", "tokentest")
+
+	if strings.Contains(w.Body.String(), "hash") {
+		t.Fatalf("Expected secret hash to be hidden")
+	}
+
+	var response dto.DevicesResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || len(response.Devices) != 1 || response.Devices[0].DeviceID != "device" {
+		t.Fatalf("Expected devices")
+	}
+}
+
+func Test_RegisterDevice_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	tokenInfo := dto.TokenInfo{ID: "jti", UserID: 1, OtpVerified: true}
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, tokenInfo, nil)
+	userService.EXPECT().RegisterDevice(gomock.Eq(user), gomock.Eq(tokenInfo), gomock.Eq("Pixel")).Return(dto.DeviceCredential{DeviceID: "device", DeviceSecret: "secret"}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	postJson, _ := json.Marshal(map[string]interface{}{
+		"name": "Pixel",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/me/devices", bytes.NewReader(postJson))
+	req.Header.Set("Authorization", "Bearer tokentest")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.DeviceResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || response.Device == nil || response.Device.DeviceSecret != "secret" {
+		t.Fatalf("Expected device credential")
+	}
+}
+
+func Test_RegisterDevice_OtpLoginRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, dto.TokenInfo{ID: "jti", UserID: 1}, nil)
+	userService.EXPECT().RegisterDevice(gomock.Eq(user), gomock.Any(), gomock.Eq("")).Return(dto.DeviceCredential{}, e.OtpLoginRequiredError{})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	req, _ := http.NewRequest("POST", "/api/me/devices", nil)
+	req.Header.Set("Authorization", "Bearer tokentest")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.DeviceResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.UnauthorizedStatus || response.Device != nil {
+		t.Fatalf("Expected UnauthorizedStatus, got %v", response.Status)
+	}
+}
+
+func Test_RevokeDevice_NotExists(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	user := &dto.User{ID: 1, PhoneNumber: "0967288123", Status: constants.UserVerifiedStatus}
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Authenticate(gomock.Eq("tokentest")).Return(user, dto.TokenInfo{ID: "jti", UserID: 1}, nil)
+	userService.EXPECT().RevokeDevice(gomock.Eq(user), gomock.Eq("device")).Return(e.NotExistsDeviceError{DeviceID: "device"})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performAuthorizedRequest(router, "DELETE", "/api/me/devices/device", "tokentest")
+
+	var response dto.Response
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.InvalidRequestStatus {
+		t.Fatalf("Expected InvalidRequestStatus")
+	}
+}
+
+func Test_SmsWebhook_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	report := external.SmsDeliveryReport{Provider: "twilio", MessageID: "SM123", Status: external.SmsStatusDelivered}
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().ParseDeliveryReport(gomock.Eq("twilio"), gomock.Any(), gomock.Eq([]byte("MessageSid=SM123"))).Return(report, nil)
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().UpdateSmsDeliveryStatus(gomock.Eq(dto.SmsDeliveryReport{Provider: "twilio", MessageID: "SM123", Status: external.SmsStatusDelivered})).Return(nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), smsService, nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "POST", "/api/webhooks/sms/twilio", bytes.NewReader([]byte("MessageSid=SM123")))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func Test_SmsWebhook_InvalidSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().ParseDeliveryReport(gomock.Any(), gomock.Any(), gomock.Any()).Return(external.SmsDeliveryReport{}, external.InvalidSmsSignatureError{Provider: "twilio"})
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), smsService, nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "POST", "/api/webhooks/sms/twilio", bytes.NewReader(nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func Test_SmsWebhook_UnknownProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().ParseDeliveryReport(gomock.Any(), gomock.Any(), gomock.Any()).Return(external.SmsDeliveryReport{}, external.UnknownSmsProviderError{Provider: "unknown"})
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), smsService, nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "POST", "/api/webhooks/sms/unknown", bytes.NewReader(nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func Test_OtpStatus_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GetOtpStatus(gomock.Eq("0961234567"), gomock.Eq("token")).Return(dto.OtpStatus{DeliveryStatus: external.SmsStatusDelivered}, nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/api/otp/status?phone_number=0961234567&status_token=token", bytes.NewReader(nil))
+
+	var response dto.OtpStatusResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || response.OtpStatus == nil || response.OtpStatus.DeliveryStatus != external.SmsStatusDelivered {
+		t.Fatalf("Expected delivered otp status")
+	}
+}
+
+func Test_OtpStatus_NotSent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GetOtpStatus(gomock.Any(), gomock.Eq("")).Return(dto.OtpStatus{}, e.NotSentOtpError{PhoneNumber: "0961234567"})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/api/otp/status?phone_number=0961234567", bytes.NewReader(nil))
+
+	var response dto.OtpStatusResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.InvalidRequestStatus {
+		t.Fatalf("Expected status %d", constants.InvalidRequestStatus)
+	}
+}
+
+func Test_OtpStatus_StoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GetOtpStatus(gomock.Any(), gomock.Any()).Return(dto.OtpStatus{}, errors.New("connection refused "))
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/api/otp/status?phone_number=0961234567&status_token=token", bytes.NewReader(nil))
+
+	var response dto.OtpStatusResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failures of the stores are not the fault of the request, and their messages are not shown.
+	if response.Status != constants.SomethingWentWrongStatus || response.Message != "Something went wrong " {
+		t.Fatalf("Expected status %d", constants.SomethingWentWrongStatus)
+	}
+}
+
+func Test_GenerateOtp_Locale(t *testing.T) {
+	phoneNumber := "+84967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Eq("vi-VN,vi;q=0.9"), gomock.Eq("")).Return("", nil)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Eq("en"), gomock.Eq("")).Return("", nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(10, 10)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": phoneNumber})
+	req, _ := http.NewRequest("POST", "/api/generate_otp", bytes.NewReader(postJson))
+	req.Header.Set("Accept-Language", "vi-VN,vi;q=0.9")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	postJson, _ = json.Marshal(map[string]interface{}{"phone_number": phoneNumber, "locale": "en"})
+	req, _ = http.NewRequest("POST", "/api/generate_otp", bytes.NewReader(postJson))
+	req.Header.Set("Accept-Language", "vi-VN,vi;q=0.9")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func Test_GenerateOtp_VoiceRateLimit(t *testing.T) {
+	phoneNumber := "+84967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Any(), gomock.Eq(constants.OtpVoiceChannel)).Return("", nil)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Any(), gomock.Eq(constants.OtpSmsChannel)).Return("", nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	voiceLimiter := helpers.NewPhoneNumberRateLimiters(0.01, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, voiceLimiter, mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	expected := []struct {
+		channel string
+		status  int
+	}{
+		{constants.OtpVoiceChannel, constants.SuccessStatus},
+		{constants.OtpVoiceChannel, constants.TooManyRequestStatus},
+		{constants.OtpSmsChannel, constants.SuccessStatus},
+	}
+
+	for _, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": phoneNumber, "channel": test.channel})
+		w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+		var response dto.GenerateOtpResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != test.status {
+			t.Fatalf("expected status %d for %s, got %d", test.status, test.channel, response.Status)
+		}
+	}
+}
+
+func Test_GenerateOtp_RateLimitNormalizedPhoneNumber(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq("+84967288123"), gomock.Any(), gomock.Any()).Return("", nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0.01, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	expected := []struct {
+		phoneNumber string
+		status      int
+	}{
+		{"0967288123", constants.SuccessStatus},
+		{"+84 967 288 123", constants.TooManyRequestStatus},
+		{"0084967288123", constants.TooManyRequestStatus},
+	}
+
+	for _, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": test.phoneNumber})
+		w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+		var response dto.GenerateOtpResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != test.status {
+			t.Fatalf("expected status %d for %s, got %d", test.status, test.phoneNumber, response.Status)
+		}
+	}
+}
+
+func Test_GenerateOtp_SharedRateLimit(t *testing.T) {
+	phoneNumber := "+84967288123"
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq(phoneNumber), gomock.Any(), gomock.Any()).Return("", nil)
+	rateLimitStore := stores.NewMemoryRateLimitStore()
+
+	// Two replicas of the API share the limits kept in the store.
+	var replicas []*gin.Engine
+	for i := 0; i < 2; i++ {
+		router := gin.Default()
+		phoneNumberLimiter := helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", 0.01, 1)
+		voiceLimiter := helpers.NewStoreRateLimiter(rateLimitStore, "voice", 0.01, 1)
+		r := routers.NewRouter(userService, validator.UserValidator{}, phoneNumberLimiter, voiceLimiter, mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+		r.IndexRouter(router)
+		replicas = append(replicas, router)
+	}
+
+	expected := []int{constants.SuccessStatus, constants.TooManyRequestStatus}
+	for i, router := range replicas {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": phoneNumber})
+		w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+		var response dto.GenerateOtpResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != expected[i] {
+			t.Fatalf("expected status %d from replica %d, got %d", expected[i], i, response.Status)
+		}
+	}
+}
+
+func Test_GenerateOtp_RateLimitStoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	rateLimitStore := mockStores.NewMockIRateLimitStore(ctrl)
+	rateLimitStore.EXPECT().Take(gomock.Eq("phone_number:+84967288123"), gomock.Any(), gomock.Any()).Return(false, 0.0, errors.New("Something went wrong "))
+	phoneNumberLimiter := helpers.NewStoreRateLimiter(rateLimitStore, "phone_number", 1, 1)
+	r := routers.NewRouter(userService, validator.UserValidator{}, phoneNumberLimiter, phoneNumberLimiter, mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Status != constants.SomethingWentWrongStatus {
+		t.Fatalf("expected SomethingWentWrongStatus, got %d", response.Status)
+	}
+}
+
+func Test_GenerateOtp_RateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(2)
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0.5, 2)
+	r := routers.NewRouter(userService, validator.UserValidator{}, phoneNumberLimiter, phoneNumberLimiter, mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	expected := []struct {
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{"1", "2", ""},
+		{"0", "4", ""},
+		{"0", "4", "2"},
+	}
+
+	for i, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+		w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+		header := w.Header()
+		if header.Get("RateLimit-Limit") != "2" || header.Get("RateLimit-Remaining") != test.remaining ||
+			header.Get("RateLimit-Reset") != test.reset || header.Get("Retry-After") != test.retryAfter {
+			t.Fatalf("unexpected headers at request %d: %v", i, header)
+		}
+	}
+}
+
+func Test_ResendOtp_RetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().ResendOtp(gomock.Any(), gomock.Any(), gomock.Any()).Return("", e.GeneratedOtpError{RetryAfter: 12500 * time.Millisecond})
+	r := routers.NewRouter(userService, validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+	w := performRequest(router, "POST", "/api/resend_otp", bytes.NewReader(postJson))
+
+	if w.Header().Get("Retry-After") != "13" {
+		t.Fatalf("expected to retry in 13 seconds, got %v", w.Header())
+	}
+
+	var response dto.GenerateOtpResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Message != "OTP has been generated, retry in 13 seconds " {
+		t.Fatalf("unexpected message %s", response.Message)
+	}
+}
+
+func Test_GenerateOtp_IPRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Any(), gomock.Any(), gomock.Any()).Return("", nil).Times(3)
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/generate_otp", Limits: []config.RateLimitPolicy{{Dimension: config.IPRateLimitDimension, Limit: 0.01, Burst: 2}}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewPhoneNumberRateLimiters(r, b)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := routers.NewRouter(userService, validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, rateLimitPolicies, nil)
+
+	r.IndexRouter(router)
+
+	// Rotating phone numbers does not get around the limit of the client address behind the proxy.
+	expected := []struct {
+		forwardedFor string
+		phoneNumber  string
+		status       int
+	}{
+		{"203.0.113.7", "0967288121", constants.SuccessStatus},
+		{"203.0.113.7", "0967288122", constants.SuccessStatus},
+		{"203.0.113.7", "0967288123", constants.TooManyRequestStatus},
+		{"198.51.100.1, 203.0.113.7", "0967288124", constants.TooManyRequestStatus},
+		{"203.0.113.8", "0967288125", constants.SuccessStatus},
+	}
+
+	for i, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": test.phoneNumber})
+		req, _ := http.NewRequest("POST", "/api/generate_otp", bytes.NewReader(postJson))
+		req.RemoteAddr = "10.0.0.2:4000"
+		req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response dto.GenerateOtpResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != test.status {
+			t.Fatalf("expected status %d at request %d, got %d", test.status, i, response.Status)
+		}
+	}
+}
+
+func Test_Login_DeviceRateLimit(t *testing.T) {
+	phoneNumber := "0967288123"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().Login(gomock.Eq(phoneNumber), gomock.Any()).Return(dto.Token{}, e.InvalidOtpError{})
+	rateLimitPolicies, err := helpers.NewRateLimitPolicies(config.RateLimitPolicies{
+		DeviceHeader: "X-Device-ID",
+		Routes: []config.RouteRateLimit{
+			{Path: "/api/login", Limits: []config.RateLimitPolicy{{Dimension: config.DeviceRateLimitDimension, Limit: 0.01, Burst: 1}}},
+		},
+	}, func(name string, r float64, b int) helpers.IRateLimiter {
+		return helpers.NewPhoneNumberRateLimiters(r, b)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := routers.NewRouter(userService, validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, rateLimitPolicies, nil)
+
+	r.IndexRouter(router)
+
+	expected := []int{constants.SomethingWentWrongStatus, constants.TooManyRequestStatus}
+	for i, status := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"phone_number": phoneNumber, "otp": "123456"})
+		req, _ := http.NewRequest("POST", "/api/login", bytes.NewReader(postJson))
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Device-ID", "device")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response dto.LoginResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != status {
+			t.Fatalf("expected status %d at request %d, got %d", status, i, response.Status)
+		}
+	}
+}
+
+func Test_GenerateOtp_ChallengeRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	otpChallengeService := mockServices.NewMockIOtpChallengeService(ctrl)
+	otpChallengeService.EXPECT().Check(gomock.Any(), gomock.Eq("+84967288123"), gomock.Eq("VN"), gomock.Any(), gomock.Eq(dto.OtpChallengeSolution{})).Return(e.OtpChallengeRequiredError{Reason: constants.OtpChallengeIPReason})
+	otpChallengeService.EXPECT().NewChallenge(gomock.Eq("+84967288123"), gomock.Eq(constants.OtpChallengeIPReason)).Return(dto.OtpChallenge{Challenge: "challenge", Reason: constants.OtpChallengeIPReason, Difficulty: 18}, nil)
+	r := routers.NewRouter(userService, validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, otpChallengeService)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+	var response dto.OtpChallengeResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Status != constants.OtpChallengeRequiredStatus || response.Challenge.Challenge != "challenge" || response.Challenge.Difficulty != 18 {
+		t.Fatalf("expected a challenge, got %+v", response)
+	}
+}
+
+func Test_ResendOtp_ChallengeRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	otpChallengeService := mockServices.NewMockIOtpChallengeService(ctrl)
+	// X-Forwarded-For of a client which is not a trusted proxy is ignored.
+	otpChallengeService.EXPECT().Check(gomock.Any(), gomock.Eq("+84967288123"), gomock.Eq("VN"), gomock.Eq("203.0.113.7"), gomock.Any()).Return(e.OtpChallengeRequiredError{Reason: constants.OtpChallengeIPReason})
+	otpChallengeService.EXPECT().NewChallenge(gomock.Eq("+84967288123"), gomock.Eq(constants.OtpChallengeIPReason)).Return(dto.OtpChallenge{Challenge: "challenge"}, nil)
+	r := routers.NewRouter(mockServices.NewMockIUserService(ctrl), validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, otpChallengeService)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+	req, _ := http.NewRequest("POST", "/api/resend_otp", bytes.NewReader(postJson))
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.OtpChallengeResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Status != constants.OtpChallengeRequiredStatus || response.Challenge.Challenge != "challenge" {
+		t.Fatalf("expected a challenge, got %+v", response)
+	}
+}
+
+func Test_GenerateOtp_ChallengeSolved(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateOtp(gomock.Eq("+84967288123"), gomock.Any(), gomock.Any()).Return("", nil)
+	otpChallengeService := mockServices.NewMockIOtpChallengeService(ctrl)
+	solution := dto.OtpChallengeSolution{Challenge: "challenge", Nonce: "42"}
+	otpChallengeService.EXPECT().Check(gomock.Any(), gomock.Eq("+84967288123"), gomock.Eq("VN"), gomock.Any(), gomock.Eq(solution)).Return(nil)
+	r := routers.NewRouter(userService, validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, otpChallengeService)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123", "challenge": "challenge", "nonce": "42"})
+	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Status != constants.SuccessStatus {
+		t.Fatalf("expected SuccessStatus, got %d", response.Status)
+	}
+}
+
+func Test_GenerateOtp_ChallengeError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	otpChallengeService := mockServices.NewMockIOtpChallengeService(ctrl)
+	otpChallengeService.EXPECT().Check(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("Something went wrong "))
+	r := routers.NewRouter(mockServices.NewMockIUserService(ctrl), validator.UserValidator{}, helpers.NewPhoneNumberRateLimiters(1, 1), helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, otpChallengeService)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"phone_number": "0967288123"})
+	w := performRequest(router, "POST", "/api/generate_otp", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	_ = json.Unmarshal([]byte(w.Body.String()), &response)
+	if response.Status != constants.SomethingWentWrongStatus {
+		t.Fatalf("expected SomethingWentWrongStatus, got %d", response.Status)
+	}
+}
+
+func Test_EmailOtp_Success(t *testing.T) {
+	email := "user@tbox.vn"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().GenerateEmailOtp(gomock.Eq(email), gomock.Eq("en")).Return(nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"email": email, "locale": "en"})
+	w := performRequest(router, "POST", "/api/email/otp", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus {
+		t.Fatalf("Expected SuccessStatus")
+	}
+}
+
+func Test_EmailOtp_InvalidEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"email": "User <user@tbox.vn>"})
+	w := performRequest(router, "POST", "/api/email/magic_link", bytes.NewReader(postJson))
+
+	var response dto.GenerateOtpResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.InvalidRequestStatus {
+		t.Fatalf("Expected InvalidRequestStatus")
+	}
+}
+
+func Test_EmailOtp_RateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().SendMagicLink(gomock.Eq("user@tbox.vn"), gomock.Any()).Return(nil)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(0.01, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	expected := []struct {
+		email  string
+		status int
+	}{
+		{"user@tbox.vn", constants.SuccessStatus},
+		{"USER@tbox.vn", constants.TooManyRequestStatus},
+	}
+
+	for _, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"email": test.email})
+		w := performRequest(router, "POST", "/api/email/magic_link", bytes.NewReader(postJson))
+
+		var response dto.GenerateOtpResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != test.status {
+			t.Fatalf("expected status %d for %s, got %d", test.status, test.email, response.Status)
+		}
+	}
+}
+
+func Test_EmailLogin_EmailLocked(t *testing.T) {
+	email := "user@tbox.vn"
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().LoginWithEmail(gomock.Eq(email), gomock.Eq("123456")).
+		Return(dto.Token{}, e.LockedEmailError{Email: email, LockedUntil: time.Now().Add(time.Hour)})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	postJson, _ := json.Marshal(map[string]interface{}{"email": email, "otp": "123456"})
+	w := performRequest(router, "POST", "/api/email/login", bytes.NewReader(postJson))
+
+	var response dto.LoginResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.PhoneNumberLockedStatus {
+		t.Fatalf("Expected PhoneNumberLockedStatus")
+	}
+}
+
+func Test_MagicLinkLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().LoginWithMagicLink(gomock.Eq("valid")).Return(dto.Token{AccessToken: "abc"}, nil)
+	userService.EXPECT().LoginWithMagicLink(gomock.Eq("used")).Return(dto.Token{}, e.InvalidMagicLinkError{})
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+
+	expected := []struct {
+		token  string
+		status int
+	}{
+		{"valid", constants.SuccessStatus},
+		{"used", constants.UnauthorizedStatus},
+	}
+
+	for _, test := range expected {
+		postJson, _ := json.Marshal(map[string]interface{}{"token": test.token})
+		w := performRequest(router, "POST", "/api/email/magic_link/login", bytes.NewReader(postJson))
+
+		var response dto.LoginResponse
+		_ = json.Unmarshal([]byte(w.Body.String()), &response)
+		if response.Status != test.status {
+			t.Fatalf("expected status %d for %s, got %d", test.status, test.token, response.Status)
+		}
+	}
+}
+
+func Test_AdminSmsProviders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq("oncall"), gomock.Eq("secret")).Return(true)
+	smsService := mockExternal.NewMockISmsService(ctrl)
+	smsService.EXPECT().ProviderStates().Return([]external.SmsProviderState{
+		{Name: "primary", Priority: 1, CircuitBreakerStats: external.CircuitBreakerStats{State: external.CircuitOpen, Trips: 1}},
+		{Name: "secondary", Priority: 2, CircuitBreakerStats: external.CircuitBreakerStats{State: external.CircuitClosed}},
+	})
+
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), smsService, nil, nil, nil)
+
+	r.IndexRouter(router)
+	req, _ := http.NewRequest("GET", "/api/admin/sms/providers", nil)
+	req.SetBasicAuth("oncall", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.SmsProvidersResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || len(response.Providers) != 2 ||
+		response.Providers[0].State != external.CircuitOpen || response.Providers[0].Trips != 1 {
+		t.Fatalf("unexpected providers %v", response.Providers)
+	}
+}
+
+func Test_AdminSmsProviders_Unauthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq(""), gomock.Eq("")).Return(false)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/api/admin/metrics", bytes.NewReader(nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d", http.StatusUnauthorized)
+	}
+}
+
+func Test_AdminOtpCountryStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	userService := mockServices.NewMockIUserService(ctrl)
+	userService.EXPECT().AuthenticateClient(gomock.Eq("oncall"), gomock.Eq("secret")).Return(true).Times(2)
+	userService.EXPECT().GetOtpCountryStats(gomock.Eq(day)).Return([]dto.OtpCountryStat{
+		{CountryCode: "SG", Day: day, SentCount: 3, BlockedCount: 40},
+		{CountryCode: "VN", Day: day, SentCount: 120},
+	}, nil)
+
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	req, _ := http.NewRequest("GET", "/api/admin/otp/countries?day=2026-10-18", nil)
+	req.SetBasicAuth("oncall", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.OtpCountryStatsResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || response.Status != constants.SuccessStatus || len(response.Stats) != 2 ||
+		response.Stats[0].CountryCode != "SG" || response.Stats[0].BlockedCount != 40 {
+		t.Fatalf("unexpected stats %v", response.Stats)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/admin/otp/countries?day=18/10/2026", nil)
+	req.SetBasicAuth("oncall", "secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d", http.StatusBadRequest)
+	}
+}
+
+func Test_DevSmsInbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	devSmsInbox := external.NewDevSmsInbox(10)
+	devSmsInbox.Add("+84961234567", "Your OTP is: 123456")
+	devSmsInbox.Add("+84967654321", "<b>Your OTP is: 654321</b>")
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), devSmsInbox, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/dev/sms/inbox?phone_number=0961234567", bytes.NewReader(nil))
+
+	var response dto.DevSmsInboxResponse
+	err := json.Unmarshal([]byte(w.Body.String()), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != constants.SuccessStatus || len(response.Messages) != 1 || response.Messages[0].Content != "Your OTP is: 123456" {
+		t.Fatalf("unexpected messages %v", response.Messages)
+	}
+
+	w = performRequest(router, "GET", "/dev/sms", bytes.NewReader(nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Your OTP is: 123456") ||
+		!strings.Contains(w.Body.String(), "&lt;b&gt;Your OTP is: 654321&lt;/b&gt;") {
+		t.Fatalf("expected both escaped messages in the page, got %s", w.Body.String())
+	}
+}
+
+func Test_DevSmsInbox_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userService := mockServices.NewMockIUserService(ctrl)
+	userValidator := validator.UserValidator{}
+	phoneNumberLimiter := helpers.NewPhoneNumberRateLimiters(1, 1)
+	r := routers.NewRouter(userService, userValidator, phoneNumberLimiter, helpers.NewPhoneNumberRateLimiters(1, 1), mockExternal.NewMockISmsService(ctrl), nil, nil, nil)
+
+	r.IndexRouter(router)
+	w := performRequest(router, "GET", "/dev/sms/inbox", bytes.NewReader(nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d", http.StatusNotFound)
+	}
 }